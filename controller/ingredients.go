@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/service"
+)
+
+// IngredientsParseCtrl is a controller to parse ingredient lists
+type IngredientsParseCtrl struct {
+	Svc service.IngredientsSvcInterface
+}
+
+// NewIngredientsParseCtrl initiates IngredientsParseCtrl
+func NewIngredientsParseCtrl() *IngredientsParseCtrl {
+	return &IngredientsParseCtrl{
+		Svc: service.NewIngredientsSvc(),
+	}
+}
+
+// Post parses the text, or the lines, of the body and writes the parsed
+// ingredients
+func (u *IngredientsParseCtrl) Post(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var body struct {
+		Text  string   `json:"text"`
+		Lines []string `json:"lines"`
+	}
+	if err := decodeBody(r, &body); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	text := body.Text
+	if len(body.Lines) > 0 {
+		text = strings.Join(append(body.Lines, text), "\n")
+	}
+
+	parsed, err := u.Svc.Parse(text)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, map[string]interface{}{
+		"ingredients": parsed,
+	})
+}
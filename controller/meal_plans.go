@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// MealPlansCtrl is a controller for meal plans
+type MealPlansCtrl struct {
+	Svc service.MealPlansSvcInterface
+}
+
+// NewMealPlansCtrl initiates MealPlansCtrl
+func NewMealPlansCtrl(db store.Store) *MealPlansCtrl {
+	return &MealPlansCtrl{
+		Svc: service.NewMealPlansSvc(db),
+	}
+}
+
+// GetOne parses http request, calls service and writes the meal plan
+func (u *MealPlansCtrl) GetOne(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	planID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	plan, err := u.Svc.GetOne(planID)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, plan)
+}
+
+// Get parses the page asked for, calls service and writes the meal plans
+func (u *MealPlansCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	query := r.URL.Query()
+	limit := service.DefaultPageLimit
+	if s := query.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil {
+			respondErr(w, r, http.StatusBadRequest, "limit: ", err)
+			return
+		}
+	}
+
+	page, err := u.Svc.List(query.Get("cursor"), limit)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	var next *string
+	if page.Next != "" {
+		link := nextPageURL(r.URL, page.Next, limit)
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", link))
+		next = &link
+	}
+	respond(w, r, http.StatusOK, map[string]interface{}{
+		"meal_plans": page.Plans,
+		"next":       next,
+	})
+}
+
+// Post parses the plan asked for, calls service and writes the created meal
+// plan
+func (u *MealPlansCtrl) Post(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req service.MealPlanRequest
+	if err := decodeBody(r, &req); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := u.Svc.Create(&req)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/meal-plans/%d", created.ID))
+	respond(w, r, http.StatusCreated, created)
+}
+
+// Delete parses http request, calls service and writes http response
+func (u *MealPlansCtrl) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	planID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := u.Svc.Delete(planID); err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusNoContent, nil)
+}
+
+// MealsCtrl is a controller to plan recipes for meals
+type MealsCtrl struct {
+	Svc service.MealPlansSvcInterface
+}
+
+// Put parses the recipe to cook, calls service and writes the meal plan
+func (u *MealsCtrl) Put(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	planID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var a service.MealAssignment
+	if err := decodeBody(r, &a); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	plan, err := u.Svc.Assign(planID, ps.ByName("date"), ps.ByName("slot"), &a)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, plan)
+}
+
+// Delete parses http request, calls service and writes the meal plan
+func (u *MealsCtrl) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	planID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	plan, err := u.Svc.Unassign(planID, ps.ByName("date"), ps.ByName("slot"))
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, plan)
+}
+
+// MealWeekCtrl is a controller for the week view of meal plans
+type MealWeekCtrl struct {
+	Svc service.MealPlansSvcInterface
+}
+
+// Get parses the week asked for, calls service and writes its meals
+func (u *MealWeekCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	planID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	week, err := u.Svc.Week(planID, r.URL.Query().Get("date"))
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, week)
+}
+
+// MealCalendarCtrl is a controller for the iCalendar feed of meal plans
+type MealCalendarCtrl struct {
+	Svc service.MealPlansSvcInterface
+}
+
+// Get parses http request, calls service and writes the plan as iCalendar
+func (u *MealCalendarCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	planID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	cal, err := u.Svc.Calendar(planID, baseURL(r))
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	// the calendar is written out first, so that a failure is still
+	// reported with an error status
+	var buf bytes.Buffer
+	if err := cal.Write(&buf, time.Now()); err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"meal-plan-%d.ics\"", planID))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// baseURL is the scheme and host the request was made to, as the client saw
+// them behind a proxy
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// MealShoppingListCtrl is a controller to shop for the meals of plans
+type MealShoppingListCtrl struct {
+	Svc service.MealPlansSvcInterface
+}
+
+// Post parses the dates to shop for, calls service and writes the created
+// shopping list
+func (u *MealShoppingListCtrl) Post(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	planID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var body struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if r.ContentLength != 0 {
+		if err := decodeBody(r, &body); err != nil {
+			respondErr(w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	created, err := u.Svc.ShoppingList(planID, body.From, body.To)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/shopping-lists/%d", created.ID))
+	respond(w, r, http.StatusCreated, created)
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// PantryCtrl is a controller for the pantry
+type PantryCtrl struct {
+	Svc service.PantrySvcInterface
+}
+
+// NewPantryCtrl initiates PantryCtrl
+func NewPantryCtrl(db store.Store) *PantryCtrl {
+	return &PantryCtrl{
+		Svc: service.NewPantrySvc(db),
+	}
+}
+
+// Get calls service and writes the pantry items
+func (u *PantryCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	items, err := u.Svc.List()
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, map[string]interface{}{
+		"items": items,
+	})
+}
+
+// Post parses the item, calls service and writes the created pantry item
+func (u *PantryCtrl) Post(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var item resource.PantryItem
+	if err := decodeBody(r, &item); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := u.Svc.Create(&item)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/pantry/%d", created.ID))
+	respond(w, r, http.StatusCreated, created)
+}
+
+// Put parses http request, calls service and writes the pantry item
+func (u *PantryCtrl) Put(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	itemID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var item resource.PantryItem
+	if err := decodeBody(r, &item); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	replaced, err := u.Svc.Replace(itemID, &item)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, replaced)
+}
+
+// Delete parses http request, calls service and writes http response
+func (u *PantryCtrl) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	itemID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := u.Svc.Delete(itemID); err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusNoContent, nil)
+}
@@ -1,13 +1,17 @@
 package controller
 
 import (
+	"fmt"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 
-	tarantool "github.com/tarantool/go-tarantool"
-
 	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/resource"
 	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
 )
 
 // RecipesCtrl is a controller for user service
@@ -16,25 +20,223 @@ type RecipesCtrl struct {
 }
 
 // NewRecipesCtrl initiates RecipesCtrl
-func NewRecipesCtrl(client *tarantool.Connection) *RecipesCtrl {
+func NewRecipesCtrl(db store.Store, listeners ...service.RecipeListener) *RecipesCtrl {
 	return &RecipesCtrl{
-		Svc: service.NewRecipesSvc(client),
+		Svc: service.NewRecipesSvc(db, listeners...),
 	}
 }
 
-// GetOne parses http request, calls service and writes http response
+// GetOne parses http request, calls service and writes http response. With
+// the servings parameter, the recipe is scaled to serve that many, and it is
+// rendered in the unit system the client asks for.
 func (u *RecipesCtrl) GetOne(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	recipeID, err := strconv.Atoi(ps.ByName("id"))
 	if err != nil {
 		respondErr(w, r, http.StatusBadRequest, err)
 		return
 	}
+	system, convert, err := unitSystem(w, r)
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, "units: ", err)
+		return
+	}
 
-	Recipe, err := u.Svc.GetOne(recipeID)
+	var Recipe *resource.Recipe
+	if s := r.URL.Query().Get("servings"); s != "" {
+		servings, convErr := strconv.Atoi(s)
+		if convErr != nil {
+			respondErr(w, r, http.StatusBadRequest, "servings: ", convErr)
+			return
+		}
+		Recipe, err = u.Svc.Scale(recipeID, servings)
+	} else {
+		Recipe, err = u.Svc.GetOne(recipeID)
+	}
 	if err != nil {
-		respondErr(w, r, http.StatusInternalServerError, err)
+		respondSvcErr(w, r, err)
 		return
 	}
+	if convert {
+		Recipe = service.ConvertRecipe(Recipe, system)
+	}
 
 	respond(w, r, http.StatusOK, Recipe)
 }
+
+// Get parses paging parameters, calls service and writes a page of recipes
+// with the link to the next page, in the unit system the client asks for
+func (u *RecipesCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	query := r.URL.Query()
+	system, convert, err := unitSystem(w, r)
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, "units: ", err)
+		return
+	}
+	limit := service.DefaultPageLimit
+	if s := query.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil {
+			respondErr(w, r, http.StatusBadRequest, "limit: ", err)
+			return
+		}
+	}
+
+	recipeQuery, err := parseRecipeQuery(query)
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := u.Svc.List(recipeQuery, query.Get("cursor"), limit)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	if convert {
+		for i := range page.Recipes {
+			page.Recipes[i] = *service.ConvertRecipe(&page.Recipes[i], system)
+		}
+	}
+
+	var next *string
+	if page.Next != "" {
+		link := nextPageURL(r.URL, page.Next, limit)
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", link))
+		next = &link
+	}
+	respond(w, r, http.StatusOK, map[string]interface{}{
+		"recipes": page.Recipes,
+		"next":    next,
+		"facets":  page.Facets,
+	})
+}
+
+// parseRecipeQuery reads the filters and the sort order of a listing.
+// Filters taking several values are given by repeating the parameter, like
+// tag=vegan&tag=quick.
+func parseRecipeQuery(query url.Values) (*resource.RecipeQuery, error) {
+	recipeQuery := &resource.RecipeQuery{
+		Tags:       query["tag"],
+		Labels:     query["label"],
+		Cuisine:    query.Get("cuisine"),
+		Difficulty: query.Get("difficulty"),
+		Includes:   query["include"],
+		Excludes:   query["exclude"],
+		Sort:       query.Get("sort"),
+	}
+	if s := query.Get("max_total_time"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("max_total_time: %s", err)
+		}
+		recipeQuery.MaxTotalTime = n
+	}
+	if s := query.Get("has_video"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("has_video: %s", err)
+		}
+		recipeQuery.HasVideo = &b
+	}
+	return recipeQuery, nil
+}
+
+// nextPageURL keeps the query of the current request, so filters carry
+// over, and moves the cursor forward
+func nextPageURL(current *url.URL, cursor string, limit int) string {
+	query := current.Query()
+	query.Set("cursor", cursor)
+	query.Set("limit", strconv.Itoa(limit))
+	next := url.URL{Path: current.Path, RawQuery: query.Encode()}
+	return next.String()
+}
+
+// Post parses http request, calls service and writes http response
+func (u *RecipesCtrl) Post(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var recipe resource.Recipe
+	if err := decodeBody(r, &recipe); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := u.Svc.Create(&recipe)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/recipes/%d", created.ID))
+	respond(w, r, http.StatusCreated, created)
+}
+
+// Put parses http request, calls service and writes http response
+func (u *RecipesCtrl) Put(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var recipe resource.Recipe
+	if err := decodeBody(r, &recipe); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	recipe.ID = uint(recipeID)
+
+	replaced, err := u.Svc.Replace(&recipe)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, replaced)
+}
+
+// Patch parses a JSON Merge Patch request, calls service and writes http
+// response
+func (u *RecipesCtrl) Patch(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+		respondHTTPErr(w, r, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	defer r.Body.Close()
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	patched, err := u.Svc.Patch(recipeID, patch)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, patched)
+}
+
+// Delete parses http request, calls service and writes http response
+func (u *RecipesCtrl) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := u.Svc.Delete(recipeID); err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusNoContent, nil)
+}
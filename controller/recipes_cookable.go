@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipesCookableCtrl is a controller for the recipes which can be cooked
+// from the pantry
+type RecipesCookableCtrl struct {
+	Svc service.RecipesCookableSvcInterface
+}
+
+// NewRecipesCookableCtrl initiates RecipesCookableCtrl
+func NewRecipesCookableCtrl(db store.Store, index search.Index) *RecipesCookableCtrl {
+	return &RecipesCookableCtrl{
+		Svc: service.NewRecipesCookableSvc(db, index),
+	}
+}
+
+// Get parses the query, calls service and writes the ranked recipes
+func (u *RecipesCookableCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	query := r.URL.Query()
+	q := &service.CookableQuery{
+		Limit:        service.DefaultPageLimit,
+		ExpiringDays: service.DefaultExpiringDays,
+	}
+	var err error
+	if s := query.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil {
+			respondErr(w, r, http.StatusBadRequest, "limit: ", err)
+			return
+		}
+	}
+	if s := query.Get("min_coverage"); s != "" {
+		if q.MinCoverage, err = strconv.ParseFloat(s, 64); err != nil {
+			respondErr(w, r, http.StatusBadRequest, "min_coverage: ", err)
+			return
+		}
+	}
+	if s := query.Get("expiring_days"); s != "" {
+		if q.ExpiringDays, err = strconv.Atoi(s); err != nil {
+			respondErr(w, r, http.StatusBadRequest, "expiring_days: ", err)
+			return
+		}
+	}
+
+	recipes, err := u.Svc.Cookable(q)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, map[string]interface{}{
+		"recipes": recipes,
+	})
+}
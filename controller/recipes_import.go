@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipesImportCtrl is a controller to create recipes from pasted text
+type RecipesImportCtrl struct {
+	Svc service.RecipesImportSvcInterface
+}
+
+// NewRecipesImportCtrl initiates RecipesImportCtrl
+func NewRecipesImportCtrl(db store.Store, listeners ...service.RecipeListener) *RecipesImportCtrl {
+	return &RecipesImportCtrl{
+		Svc: service.NewRecipesImportSvc(db, listeners...),
+	}
+}
+
+// Post parses http request, calls service and writes the created recipe
+// with the parsed ingredient lines
+func (u *RecipesImportCtrl) Post(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var recipe service.RecipeImport
+	if err := decodeBody(r, &recipe); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	created, parsed, err := u.Svc.Import(&recipe)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/recipes/%d", created.ID))
+	respond(w, r, http.StatusCreated, map[string]interface{}{
+		"recipe":      created,
+		"ingredients": parsed,
+	})
+}
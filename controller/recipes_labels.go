@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipesLabelsCtrl is a controller for the dietary labels of recipes
+type RecipesLabelsCtrl struct {
+	Svc service.RecipesLabelsSvcInterface
+}
+
+// NewRecipesLabelsCtrl initiates RecipesLabelsCtrl
+func NewRecipesLabelsCtrl(db store.Store, listeners ...service.RecipeListener) *RecipesLabelsCtrl {
+	return &RecipesLabelsCtrl{
+		Svc: service.NewRecipesLabelsSvc(db, listeners...),
+	}
+}
+
+// Get parses the recipe ID, calls service and writes how the labels were
+// given
+func (u *RecipesLabelsCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	labels, err := u.Svc.Get(recipeID)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, labels)
+}
+
+// Put parses the override of a label, calls service and writes the labels
+func (u *RecipesLabelsCtrl) Put(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var override service.LabelOverride
+	if err := decodeBody(r, &override); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	labels, err := u.Svc.Override(recipeID, ps.ByName("label"), &override)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, labels)
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/nutrition"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipesNutritionCtrl is a controller for the nutrition facts of recipes
+type RecipesNutritionCtrl struct {
+	Svc service.RecipesNutritionSvcInterface
+}
+
+// NewRecipesNutritionCtrl initiates RecipesNutritionCtrl
+func NewRecipesNutritionCtrl(db store.Store, foods *nutrition.DB) *RecipesNutritionCtrl {
+	return &RecipesNutritionCtrl{
+		Svc: service.NewRecipesNutritionSvc(db, foods),
+	}
+}
+
+// Get parses the recipe ID, calls service and writes the nutrition facts
+func (u *RecipesNutritionCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	facts, err := u.Svc.Get(recipeID)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, facts)
+}
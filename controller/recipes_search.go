@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipesSearchCtrl is a controller for recipe search
+type RecipesSearchCtrl struct {
+	Svc service.RecipesSearchSvcInterface
+}
+
+// NewRecipesSearchCtrl initiates RecipesSearchCtrl
+func NewRecipesSearchCtrl(db store.Store, index search.Index) *RecipesSearchCtrl {
+	return &RecipesSearchCtrl{
+		Svc: service.NewRecipesSearchSvc(db, index),
+	}
+}
+
+// Get parses the query, calls service and writes the matching recipes
+func (u *RecipesSearchCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	query := r.URL.Query()
+	limit := service.DefaultPageLimit
+	if s := query.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil {
+			respondErr(w, r, http.StatusBadRequest, "limit: ", err)
+			return
+		}
+	}
+
+	hits, err := u.Svc.Search(query.Get("q"), limit)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, map[string]interface{}{
+		"hits": hits,
+	})
+}
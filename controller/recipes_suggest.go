@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipesSuggestCtrl is a controller for search-as-you-type suggestions
+type RecipesSuggestCtrl struct {
+	Svc service.RecipesSuggestSvcInterface
+}
+
+// NewRecipesSuggestCtrl initiates RecipesSuggestCtrl
+func NewRecipesSuggestCtrl(db store.Store, suggester *search.Suggester) *RecipesSuggestCtrl {
+	return &RecipesSuggestCtrl{
+		Svc: service.NewRecipesSuggestSvc(db, suggester),
+	}
+}
+
+// Get parses the prefix, calls service and writes the completions
+func (u *RecipesSuggestCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	query := r.URL.Query()
+	limit := service.DefaultSuggestLimit
+	if s := query.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil {
+			respondErr(w, r, http.StatusBadRequest, "limit: ", err)
+			return
+		}
+	}
+
+	suggestions, err := u.Svc.Suggest(query.Get("prefix"), limit)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, map[string]interface{}{
+		"suggestions": suggestions,
+	})
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/service"
+)
+
+type recipesSvcStub struct {
+	service.RecipesSvcInterface
+}
+
+func (s *recipesSvcStub) Create(recipe *resource.Recipe) (*resource.Recipe, error) {
+	if recipe.Title == "" {
+		return nil, resource.Invalid("title", "is required")
+	}
+	created := *recipe
+	created.ID = 42
+	return &created, nil
+}
+
+func (s *recipesSvcStub) GetOne(recipeID int) (*resource.Recipe, error) {
+	switch recipeID {
+	case 1:
+		return &resource.Recipe{ID: 1, Title: "Curry"}, nil
+	case 2:
+		return nil, &resource.Error{Kind: resource.ErrUnavailable, Detail: "connection closed"}
+	case 3:
+		return nil, errors.New("unexpected")
+	}
+	return nil, resource.NotFound("recipe %d doesn't exist", recipeID)
+}
+
+func (s *recipesSvcStub) Scale(recipeID int, servings int) (*resource.Recipe, error) {
+	if servings < 1 {
+		return nil, resource.Invalid("servings", "must be between 1 and 1000")
+	}
+	recipe, err := s.GetOne(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	recipe.Servings = servings
+	return recipe, nil
+}
+
+func TestRecipesGetOne(t *testing.T) {
+	type (
+		in struct {
+			id    string
+			query string
+		}
+		out struct {
+			statusCode  int
+			contentType string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"1", ""}, out{200, ""}},
+		"case-02": {in{"2", ""}, out{503, "application/problem+json"}},
+		"case-03": {in{"3", ""}, out{500, "application/problem+json"}},
+		"case-04": {in{"4", ""}, out{404, "application/problem+json"}},
+		"case-05": {in{"curry", ""}, out{400, "application/problem+json"}},
+		"case-06": {in{"1", "?servings=4"}, out{200, ""}},
+		"case-07": {in{"1", "?servings=0"}, out{400, "application/problem+json"}},
+		"case-08": {in{"4", "?servings=4"}, out{404, "application/problem+json"}},
+		"case-09": {in{"1", "?servings=four"}, out{400, "application/problem+json"}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			ctrl := &RecipesCtrl{Svc: &recipesSvcStub{}}
+
+			ps := httprouter.Params{{Key: "id", Value: in.id}}
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("GET", "/recipes/"+in.id+in.query, nil)
+			ctrl.GetOne(w, r, ps)
+
+			if statusCode := w.Code; statusCode != out.statusCode {
+				t.Errorf("actual status code %d, expected status code %d", statusCode, out.statusCode)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != out.contentType {
+				t.Errorf("actual content type %s, expected content type %s", contentType, out.contentType)
+			}
+		})
+	}
+}
+
+func TestRecipesPost(t *testing.T) {
+	type (
+		in struct {
+			body string
+		}
+		out struct {
+			statusCode int
+			location   string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {
+			in{`{"id":7,"title":"Curry","howto":["cut",{"text":"boil","duration":600}]}`},
+			out{201, "/recipes/42"},
+		},
+		"case-02": {
+			in{`{"howto":["cut"]}`},
+			out{400, ""},
+		},
+		"case-03": {
+			in{`{"title":`},
+			out{400, ""},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			ctrl := &RecipesCtrl{Svc: &recipesSvcStub{}}
+
+			ps := httprouter.Params{}
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", "/recipes", strings.NewReader(in.body))
+			ctrl.Post(w, r, ps)
+
+			if statusCode := w.Code; statusCode != out.statusCode {
+				t.Errorf("actual status code %d, expected status code %d", statusCode, out.statusCode)
+			}
+			if location := w.Header().Get("Location"); location != out.location {
+				t.Errorf("actual location %s, expected location %s", location, out.location)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipesTrashCtrl is a controller for recipes in the trash
+type RecipesTrashCtrl struct {
+	Svc service.RecipesSvcInterface
+}
+
+// NewRecipesTrashCtrl initiates RecipesTrashCtrl
+func NewRecipesTrashCtrl(db store.Store) *RecipesTrashCtrl {
+	return &RecipesTrashCtrl{
+		Svc: service.NewRecipesSvc(db),
+	}
+}
+
+// Get calls service and writes the recipes in the trash
+func (u *RecipesTrashCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	recipes, err := u.Svc.GetTrash()
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, map[string]interface{}{
+		"recipes": recipes,
+	})
+}
+
+// RecipesRestoreCtrl is a controller to restore recipes from the trash
+type RecipesRestoreCtrl struct {
+	Svc service.RecipesSvcInterface
+}
+
+// NewRecipesRestoreCtrl initiates RecipesRestoreCtrl
+func NewRecipesRestoreCtrl(db store.Store, listeners ...service.RecipeListener) *RecipesRestoreCtrl {
+	return &RecipesRestoreCtrl{
+		Svc: service.NewRecipesSvc(db, listeners...),
+	}
+}
+
+// Post parses http request, calls service and writes the restored recipe
+func (u *RecipesRestoreCtrl) Post(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	recipe, err := u.Svc.Restore(recipeID)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, recipe)
+}
@@ -2,10 +2,29 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+
+	"github.com/motomux/smart-cooking-server/resource"
 )
 
+// problem is a problem details object as defined by RFC 7807
+type problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	InvalidParams []invalidParam `json:"invalid-params,omitempty"`
+}
+
+type invalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
 func decodeBody(r *http.Request, v interface{}) error {
 	defer r.Body.Close()
 	return json.NewDecoder(r.Body).Decode(v)
@@ -22,17 +41,56 @@ func respond(w http.ResponseWriter, r *http.Request,
 		encodeBody(w, r, data)
 	}
 }
+func respondProblem(w http.ResponseWriter, r *http.Request,
+	p *problem,
+) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	p.Instance = r.URL.RequestURI()
+	w.Header().Set("Content-Type", "application/problem+json")
+	respond(w, r, p.Status, p)
+}
 func respondErr(w http.ResponseWriter, r *http.Request,
 	status int, args ...interface{},
 ) {
-	respond(w, r, status, map[string]interface{}{
-		"error": map[string]interface{}{
-			"message": fmt.Sprint(args...),
-		},
+	respondProblem(w, r, &problem{
+		Status: status,
+		Detail: fmt.Sprint(args...),
 	})
 }
 func respondHTTPErr(w http.ResponseWriter, r *http.Request,
 	status int,
 ) {
-	respondErr(w, r, status, http.StatusText(status))
+	respondProblem(w, r, &problem{Status: status})
+}
+
+// respondSvcErr maps the kind of a resource error to its status code.
+// Errors of no known kind are logged and hidden behind a 500.
+func respondSvcErr(w http.ResponseWriter, r *http.Request, err error) {
+	p := &problem{Detail: err.Error()}
+	switch {
+	case errors.Is(err, resource.ErrNotFound):
+		p.Status = http.StatusNotFound
+	case errors.Is(err, resource.ErrInvalid):
+		p.Status = http.StatusBadRequest
+		var rerr *resource.Error
+		if errors.As(err, &rerr) && rerr.Field != "" {
+			p.Detail = "The request contains invalid parameters."
+			p.InvalidParams = []invalidParam{{rerr.Field, rerr.Detail}}
+		}
+	case errors.Is(err, resource.ErrConflict):
+		p.Status = http.StatusConflict
+	case errors.Is(err, resource.ErrUnavailable):
+		p.Status = http.StatusServiceUnavailable
+		w.Header().Set("Retry-After", "1")
+	default:
+		log.Printf("%s %s: %s", r.Method, r.URL.Path, err)
+		p.Status = http.StatusInternalServerError
+		p.Detail = ""
+	}
+	respondProblem(w, r, p)
 }
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// ShoppingListsCtrl is a controller for shopping lists
+type ShoppingListsCtrl struct {
+	Svc service.ShoppingListsSvcInterface
+}
+
+// NewShoppingListsCtrl initiates ShoppingListsCtrl
+func NewShoppingListsCtrl(db store.Store) *ShoppingListsCtrl {
+	return &ShoppingListsCtrl{
+		Svc: service.NewShoppingListsSvc(db),
+	}
+}
+
+// GetOne parses http request, calls service and writes the shopping list
+func (u *ShoppingListsCtrl) GetOne(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	listID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	list, err := u.Svc.GetOne(listID)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, list)
+}
+
+// Get parses the page asked for, calls service and writes the shopping lists
+func (u *ShoppingListsCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	query := r.URL.Query()
+	limit := service.DefaultPageLimit
+	if s := query.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil {
+			respondErr(w, r, http.StatusBadRequest, "limit: ", err)
+			return
+		}
+	}
+
+	page, err := u.Svc.List(query.Get("cursor"), limit)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	var next *string
+	if page.Next != "" {
+		link := nextPageURL(r.URL, page.Next, limit)
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", link))
+		next = &link
+	}
+	respond(w, r, http.StatusOK, map[string]interface{}{
+		"shopping_lists": page.Lists,
+		"next":           next,
+	})
+}
+
+// Post parses the recipes to shop for, calls service and writes the created
+// shopping list
+func (u *ShoppingListsCtrl) Post(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var req service.ShoppingListRequest
+	if err := decodeBody(r, &req); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	created, err := u.Svc.Create(&req)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/shopping-lists/%d", created.ID))
+	respond(w, r, http.StatusCreated, created)
+}
+
+// Delete parses http request, calls service and writes http response
+func (u *ShoppingListsCtrl) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	listID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := u.Svc.Delete(listID); err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusNoContent, nil)
+}
+
+// ShoppingItemsCtrl is a controller to check items of shopping lists off
+type ShoppingItemsCtrl struct {
+	Svc service.ShoppingListsSvcInterface
+}
+
+// Patch parses whether the item is checked, calls service and writes the
+// shopping list
+func (u *ShoppingItemsCtrl) Patch(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	listID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	index, err := strconv.Atoi(ps.ByName("index"))
+	if err != nil {
+		respondErr(w, r, http.StatusBadRequest, "index: ", err)
+		return
+	}
+	var body struct {
+		Checked *bool `json:"checked"`
+	}
+	if err := decodeBody(r, &body); err != nil {
+		respondErr(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if body.Checked == nil {
+		respondErr(w, r, http.StatusBadRequest, "checked: is required")
+		return
+	}
+
+	list, err := u.Svc.Check(listID, index, *body.Checked)
+	if err != nil {
+		respondSvcErr(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, list)
+}
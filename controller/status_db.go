@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// ClusterInterface reports the state of the connections to database nodes
+type ClusterInterface interface {
+	Status() []store.NodeStatus
+}
+
+// StatusDBCtrl is a controller for the state of the database connections
+type StatusDBCtrl struct {
+	Cluster ClusterInterface
+}
+
+// NewStatusDBCtrl initializes StatusDBCtrl
+func NewStatusDBCtrl(cluster ClusterInterface) *StatusDBCtrl {
+	return &StatusDBCtrl{Cluster: cluster}
+}
+
+// Get writes the state of every node, with 503 status code while no master
+// is connected
+func (s *StatusDBCtrl) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	nodes := s.Cluster.Status()
+	status := http.StatusServiceUnavailable
+	for _, node := range nodes {
+		if node.State == store.StateConnected && node.Master {
+			status = http.StatusOK
+		}
+	}
+	respond(w, r, status, map[string]interface{}{
+		"nodes": nodes,
+	})
+}
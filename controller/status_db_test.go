@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+type fakeCluster []store.NodeStatus
+
+func (c fakeCluster) Status() []store.NodeStatus {
+	return c
+}
+
+func TestStatusDBGet(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	type (
+		in struct {
+			nodes fakeCluster
+		}
+		out struct {
+			body       string
+			statusCode int
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {
+			in{fakeCluster{
+				{Addr: "db-0:3301", Role: store.RoleMaster, State: store.StateConnected, Master: true, Since: since},
+				{Addr: "db-1:3301", Role: store.RoleReplica, State: store.StateDisconnected, Since: since, Error: "connection refused"},
+			}},
+			out{`{"nodes":[{"addr":"db-0:3301","role":"master","state":"connected","master":true,"reads":false,"since":"2024-05-01T12:00:00Z"},` +
+				`{"addr":"db-1:3301","role":"replica","state":"disconnected","master":false,"reads":false,"since":"2024-05-01T12:00:00Z","error":"connection refused"}]}` + "\n", 200},
+		},
+		// a connected replica serves reads, but writes fail without a master
+		"case-02": {
+			in{fakeCluster{{Addr: "db-1:3301", Role: store.RoleReplica, State: store.StateConnected, Reads: true, Since: since}}},
+			out{`{"nodes":[{"addr":"db-1:3301","role":"replica","state":"connected","master":false,"reads":true,"since":"2024-05-01T12:00:00Z"}]}` + "\n", 503},
+		},
+		// a read-only master takes no writes
+		"case-03": {
+			in{fakeCluster{{Addr: "db-0:3301", Role: store.RoleMaster, State: store.StateConnected, Since: since, Error: "read-only"}}},
+			out{`{"nodes":[{"addr":"db-0:3301","role":"master","state":"connected","master":false,"reads":false,"since":"2024-05-01T12:00:00Z","error":"read-only"}]}` + "\n", 503},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			ctrl := NewStatusDBCtrl(in.nodes)
+
+			ps := httprouter.Params{}
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("GET", "/_status/db", nil)
+			ctrl.Get(w, r, ps)
+
+			if body := w.Body.String(); body != out.body {
+				t.Errorf("actual body %s, expected body %s", body, out.body)
+			}
+			if statusCode := w.Code; statusCode != out.statusCode {
+				t.Errorf("actual status code %d, expected status code %d", statusCode, out.statusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/units"
+)
+
+// usRegions are the regions of language tags which cook with US customary
+// units
+var usRegions = map[string]bool{"US": true, "LR": true, "MM": true}
+
+// unitSystem returns the unit system recipes are rendered in, chosen by the
+// units parameter or else by the Accept-Language header. It reports false
+// when the request asks for neither, and recipes are rendered as written.
+func unitSystem(w http.ResponseWriter, r *http.Request) (units.System, bool, error) {
+	if s := r.URL.Query().Get("units"); s != "" {
+		system, err := units.ParseSystem(s)
+		return system, err == nil, err
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+	tag, ok := preferredLanguage(r.Header.Get("Accept-Language"))
+	if !ok {
+		return 0, false, nil
+	}
+	parts := strings.FieldsFunc(tag, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) > 1 && usRegions[strings.ToUpper(parts[1])] {
+		return units.USCustomary, true, nil
+	}
+	return units.Metric, true, nil
+}
+
+// preferredLanguage returns the language tag of an Accept-Language header
+// with the highest quality
+func preferredLanguage(header string) (string, bool) {
+	type language struct {
+		tag     string
+		quality float64
+	}
+	var languages []language
+	for _, item := range strings.Split(header, ",") {
+		params := strings.Split(item, ";")
+		tag := strings.TrimSpace(params[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			languages = append(languages, language{tag, quality})
+		}
+	}
+	if len(languages) == 0 {
+		return "", false
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+	return languages[0].tag, true
+}
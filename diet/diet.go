@@ -0,0 +1,159 @@
+// Package diet detects the allergens in the ingredients of recipes and the
+// dietary labels, like vegan or gluten-free, recipes can be given.
+package diet
+
+import "github.com/motomux/smart-cooking-server/search"
+
+// Allergen is a kind of food some diets exclude
+type Allergen string
+
+// Allergens detected in ingredients
+const (
+	Meat      Allergen = "meat"
+	Fish      Allergen = "fish"
+	Shellfish Allergen = "shellfish"
+	Dairy     Allergen = "dairy"
+	Egg       Allergen = "egg"
+	Honey     Allergen = "honey"
+	Gluten    Allergen = "gluten"
+	// Nuts are tree nuts and peanuts
+	Nuts Allergen = "nuts"
+)
+
+// Dietary labels of recipes
+const (
+	Vegan      = "vegan"
+	Vegetarian = "vegetarian"
+	GlutenFree = "gluten-free"
+	NutFree    = "nut-free"
+	DairyFree  = "dairy-free"
+)
+
+// Labels are the dietary labels, in the order they are listed
+var Labels = []string{Vegan, Vegetarian, GlutenFree, NutFree, DairyFree}
+
+// excludes are the allergens a recipe must not contain to have a label
+var excludes = map[string][]Allergen{
+	Vegan:      {Meat, Fish, Shellfish, Dairy, Egg, Honey},
+	Vegetarian: {Meat, Fish, Shellfish},
+	GlutenFree: {Gluten},
+	NutFree:    {Nuts},
+	DairyFree:  {Dairy},
+}
+
+// IsLabel reports whether label is one of Labels
+func IsLabel(label string) bool {
+	_, ok := excludes[label]
+	return ok
+}
+
+var (
+	keywords search.Keywords
+	// allergensOf maps keywords to the allergens they contain
+	allergensOf = map[string][]Allergen{}
+)
+
+func init() {
+	for _, entry := range table {
+		keywords.Add(entry.keywords...)
+		for _, text := range entry.keywords {
+			allergensOf[text] = append(allergensOf[text], entry.allergens...)
+		}
+	}
+}
+
+// Detect finds the allergens in an ingredient name. When keywords overlap,
+// like "butter" and "peanut butter", only the longest one counts.
+func Detect(name string) []Allergen {
+	allergens, _ := detect(name)
+	return allergens
+}
+
+// detect finds the allergens in an ingredient name, and reports whether
+// anything is known about it
+func detect(name string) ([]Allergen, bool) {
+	found := keywords.Find(name)
+	var allergens []Allergen
+	for _, text := range found {
+		for _, a := range allergensOf[text] {
+			if !hasAllergen(allergens, a) {
+				allergens = append(allergens, a)
+			}
+		}
+	}
+	return allergens, len(found) > 0
+}
+
+// Analysis is what was detected in the ingredients of a recipe
+type Analysis struct {
+	// Allergens lists the ingredients each allergen was found in
+	Allergens map[Allergen][]string `json:"allergens"`
+	// Unknown are the ingredients nothing is known about
+	Unknown []string `json:"unknown"`
+	// Derived are the labels the ingredients allow
+	Derived []string `json:"derived"`
+}
+
+// Analyze detects the allergens in ingredients and derives the labels they
+// allow. Ingredients nothing is known about may contain any allergen, so
+// no label is derived for ingredients with one of them; only an override
+// gives such a recipe its labels.
+func Analyze(ingredients []string) *Analysis {
+	a := &Analysis{Allergens: map[Allergen][]string{}, Unknown: []string{}, Derived: []string{}}
+	for _, name := range ingredients {
+		allergens, known := detect(name)
+		if !known {
+			a.Unknown = append(a.Unknown, name)
+		}
+		for _, allergen := range allergens {
+			a.Allergens[allergen] = append(a.Allergens[allergen], name)
+		}
+	}
+	for _, label := range Labels {
+		allowed := len(a.Unknown) == 0
+		for _, allergen := range excludes[label] {
+			if len(a.Allergens[allergen]) > 0 {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			a.Derived = append(a.Derived, label)
+		}
+	}
+	return a
+}
+
+// Apply returns derived with overrides applied, an override adding the
+// label when true and removing it when false, in the order of Labels
+func Apply(derived []string, overrides map[string]bool) []string {
+	labels := []string{}
+	for _, label := range Labels {
+		on, ok := overrides[label]
+		if !ok {
+			on = hasString(derived, label)
+		}
+		if on {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+func hasAllergen(allergens []Allergen, a Allergen) bool {
+	for _, v := range allergens {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
+
+func hasString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
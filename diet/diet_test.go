@@ -0,0 +1,118 @@
+package diet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	type (
+		in struct {
+			name string
+		}
+		out struct {
+			allergens []Allergen
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"unsalted butter"}, out{[]Allergen{Dairy}}},
+		"case-02": {in{"Eggs"}, out{[]Allergen{Egg}}},
+		"case-03": {in{"eggplant"}, out{nil}},
+		"case-04": {in{"peanut butter"}, out{[]Allergen{Nuts}}},
+		"case-05": {in{"coconut milk"}, out{nil}},
+		"case-06": {in{"cream of tartar"}, out{nil}},
+		"case-07": {in{"all-purpose flour"}, out{[]Allergen{Gluten}}},
+		"case-08": {in{"gluten-free flour"}, out{nil}},
+		"case-09": {in{"chicken stock"}, out{[]Allergen{Meat}}},
+		"case-10": {in{"鶏もも肉"}, out{[]Allergen{Meat}}},
+		"case-11": {in{"タマゴ"}, out{[]Allergen{Egg}}},
+		"case-12": {in{"ピーナッツバター"}, out{[]Allergen{Nuts}}},
+		"case-13": {in{"ココナッツミルク"}, out{nil}},
+		"case-14": {in{"醤油"}, out{[]Allergen{Gluten}}},
+		"case-15": {in{"onion"}, out{nil}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			allergens := Detect(in.name)
+			if !reflect.DeepEqual(allergens, out.allergens) {
+				t.Errorf("actual allergens %v, expected allergens %v", allergens, out.allergens)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	type (
+		in struct {
+			ingredients []string
+			overrides   map[string]bool
+		}
+		out struct {
+			unknown []string
+			derived []string
+			labels  []string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {
+			in{[]string{"rice", "tofu", "soy sauce"}, nil},
+			out{[]string{}, []string{Vegan, Vegetarian, NutFree, DairyFree}, []string{Vegan, Vegetarian, NutFree, DairyFree}},
+		},
+		"case-02": {
+			in{[]string{"flour", "butter", "sugar", "eggs"}, nil},
+			out{[]string{}, []string{Vegetarian, NutFree}, []string{Vegetarian, NutFree}},
+		},
+		"case-03": {
+			in{[]string{"chicken thigh", "almonds"}, nil},
+			out{[]string{}, []string{GlutenFree, DairyFree}, []string{GlutenFree, DairyFree}},
+		},
+		// the author knows the soy sauce is gluten-free, but not whether the
+		// tofu came from a plant which handles nuts
+		"case-04": {
+			in{[]string{"rice", "tofu", "soy sauce"}, map[string]bool{GlutenFree: true, NutFree: false}},
+			out{[]string{}, []string{Vegan, Vegetarian, NutFree, DairyFree}, []string{Vegan, Vegetarian, GlutenFree, DairyFree}},
+		},
+		"case-05": {
+			in{nil, nil},
+			out{[]string{}, Labels, Labels},
+		},
+		// nothing is known about the furikake, which may hold fish, sesame
+		// or wheat, so no label is derived until the author sets one
+		"case-06": {
+			in{[]string{"rice", "furikake"}, nil},
+			out{[]string{"furikake"}, []string{}, []string{}},
+		},
+		"case-07": {
+			in{[]string{"rice", "furikake"}, map[string]bool{DairyFree: true}},
+			out{[]string{"furikake"}, []string{}, []string{DairyFree}},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			a := Analyze(in.ingredients)
+			if !reflect.DeepEqual(a.Unknown, out.unknown) {
+				t.Errorf("actual unknown %v, expected unknown %v", a.Unknown, out.unknown)
+			}
+			if !reflect.DeepEqual(a.Derived, out.derived) {
+				t.Errorf("actual derived %v, expected derived %v", a.Derived, out.derived)
+			}
+			if labels := Apply(a.Derived, in.overrides); !reflect.DeepEqual(labels, out.labels) {
+				t.Errorf("actual labels %v, expected labels %v", labels, out.labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package diet
+
+// table maps keywords of ingredient names to the allergens they contain.
+// Longer keywords win over the ones they contain, which is how entries
+// without allergens, like "coconut milk", clear what a shorter keyword would
+// detect. English keywords match whole words after stemming, CJK keywords
+// match anywhere in the name.
+var table = []struct {
+	keywords  []string
+	allergens []Allergen
+}{
+	{[]string{
+		"beef", "pork", "chicken", "lamb", "mutton", "veal", "bacon", "ham", "sausage", "turkey",
+		"duck", "prosciutto", "salami", "pepperoni", "chorizo", "gelatin", "gelatine", "lard", "meat",
+		"牛肉", "豚肉", "鶏肉", "肉", "ベーコン", "ハム", "ソーセージ", "ゼラチン", "ラード",
+	}, []Allergen{Meat}},
+	{[]string{
+		"fish", "salmon", "tuna", "cod", "anchovy", "anchovies", "sardine", "mackerel", "trout",
+		"bonito", "katsuobushi", "dashi", "fish sauce", "worcestershire sauce",
+		"魚", "鮭", "まぐろ", "ツナ", "かつお", "鰹", "だし", "出汁", "煮干し", "しらす", "ナンプラー",
+	}, []Allergen{Fish}},
+	{[]string{
+		"shrimp", "prawn", "crab", "lobster", "clam", "mussel", "oyster", "scallop", "squid", "octopus",
+		"えび", "海老", "蟹", "あさり", "牡蠣", "ほたて", "帆立", "オイスターソース",
+	}, []Allergen{Shellfish}},
+	{[]string{
+		"milk", "butter", "cheese", "cream", "yogurt", "yoghurt", "ghee", "buttermilk", "whey",
+		"parmesan", "mozzarella", "ricotta", "mascarpone", "sour cream", "cream cheese", "ice cream",
+		"牛乳", "バター", "チーズ", "生クリーム", "ヨーグルト", "練乳", "スキムミルク",
+	}, []Allergen{Dairy}},
+	{[]string{"egg", "mayonnaise", "meringue", "卵", "たまご", "マヨネーズ"}, []Allergen{Egg}},
+	{[]string{"honey", "はちみつ", "蜂蜜"}, []Allergen{Honey}},
+	{[]string{
+		"flour", "wheat", "bread", "breadcrumbs", "panko", "pasta", "spaghetti", "macaroni", "noodle",
+		"udon", "ramen", "soba", "barley", "rye", "couscous", "semolina", "bulgur", "seitan", "beer",
+		"oat", "oats", "oat milk", "soy sauce", "tortilla",
+		"小麦", "薄力粉", "強力粉", "パン粉", "食パン", "うどん", "ラーメン", "中華麺", "パスタ",
+		"スパゲッティ", "醤油", "しょうゆ", "麩", "餃子の皮",
+	}, []Allergen{Gluten}},
+	{[]string{
+		"nut", "nuts", "almond", "walnut", "pecan", "cashew", "pistachio", "hazelnut", "macadamia",
+		"peanut", "pine nut", "praline", "marzipan",
+		"ナッツ", "アーモンド", "くるみ", "胡桃", "カシューナッツ", "ピーナッツ", "落花生",
+	}, []Allergen{Nuts}},
+
+	// compounds which aren't what their words would suggest
+	{[]string{"peanut butter", "almond milk", "almond flour", "ピーナッツバター", "アーモンドミルク"}, []Allergen{Nuts}},
+	{[]string{
+		"coconut milk", "coconut cream", "coconut flour", "soy milk", "rice milk", "cocoa butter",
+		"cream of tartar", "rice flour", "corn flour", "buckwheat flour", "gluten-free flour", "tamari",
+		"rice noodle",
+		"ココナッツ", "ココナッツミルク", "豆乳", "米粉", "カカオバター", "昆布だし",
+	}, nil},
+	{[]string{"chicken stock", "chicken broth", "beef stock", "beef broth", "鶏ガラ"}, []Allergen{Meat}},
+
+	// staples known to be free of allergens; a name matching no keyword at
+	// all is unknown
+	{[]string{
+		"water", "salt", "pepper", "sugar", "oil", "olive oil", "vinegar", "onion", "garlic", "ginger",
+		"potato", "carrot", "tomato", "lettuce", "cabbage", "spinach", "broccoli", "cucumber",
+		"zucchini", "eggplant", "mushroom", "celery", "leek", "scallion", "lemon", "lime", "apple",
+		"banana", "avocado", "parsley", "basil", "cilantro", "thyme", "rosemary", "oregano", "mint",
+		"cumin", "paprika", "cinnamon", "chili", "rice", "corn", "bean", "lentil", "chickpea", "tofu",
+		"baking soda", "baking powder", "yeast", "vanilla", "cornstarch", "cocoa",
+		"水", "塩", "砂糖", "こしょう", "胡椒", "油", "酢", "玉ねぎ", "たまねぎ", "にんにく", "生姜",
+		"しょうが", "じゃがいも", "にんじん", "人参", "トマト", "キャベツ", "ねぎ", "ほうれん草",
+		"きゅうり", "なす", "きのこ", "しいたけ", "大根", "レモン", "米", "ご飯", "豆腐", "片栗粉", "みりん",
+	}, nil},
+}
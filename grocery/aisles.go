@@ -0,0 +1,108 @@
+// Package grocery sorts ingredients into the aisles of a grocery store.
+package grocery
+
+import "github.com/motomux/smart-cooking-server/search"
+
+// Aisles of a store, in the order a shopping list walks them
+const (
+	Produce   = "produce"
+	Meat      = "meat & seafood"
+	Dairy     = "dairy & eggs"
+	Bakery    = "bakery"
+	Baking    = "baking"
+	Pantry    = "pantry"
+	Spices    = "spices & seasonings"
+	Frozen    = "frozen"
+	Beverages = "beverages"
+	// Other is the aisle of ingredients nothing is known about
+	Other = "other"
+)
+
+// Aisles are the aisles in the order a shopping list walks them
+var Aisles = []string{Produce, Meat, Dairy, Bakery, Baking, Pantry, Spices, Frozen, Beverages, Other}
+
+var table = []struct {
+	aisle    string
+	keywords []string
+}{
+	{Produce, []string{
+		"onion", "garlic", "ginger", "potato", "carrot", "tomato", "lettuce", "cabbage", "spinach",
+		"broccoli", "cucumber", "pepper", "bell pepper", "zucchini", "eggplant", "mushroom", "celery",
+		"leek", "scallion", "green onion", "lemon", "lime", "apple", "banana", "orange", "avocado",
+		"herb", "basil", "parsley", "cilantro", "mint", "rosemary", "thyme", "berries", "strawberry",
+		"玉ねぎ", "たまねぎ", "にんにく", "しょうが", "生姜", "じゃがいも", "にんじん", "人参", "トマト",
+		"キャベツ", "ほうれん草", "ねぎ", "長ねぎ", "きゅうり", "なす", "しめじ", "しいたけ", "えのき",
+		"大根", "白菜", "もやし", "ピーマン", "レモン", "りんご", "大葉",
+	}},
+	{Meat, []string{
+		"beef", "pork", "chicken", "lamb", "turkey", "bacon", "ham", "sausage", "ground meat",
+		"fish", "salmon", "tuna", "cod", "shrimp", "prawn", "crab", "clam", "mussel", "squid",
+		"肉", "鶏", "豚", "牛", "ベーコン", "ハム", "ソーセージ", "鮭", "魚", "えび", "あさり", "いか", "たこ",
+	}},
+	{Dairy, []string{
+		"milk", "butter", "cheese", "cream", "yogurt", "egg", "sour cream",
+		"牛乳", "バター", "チーズ", "生クリーム", "ヨーグルト", "卵", "たまご",
+	}},
+	{Bakery, []string{"bread", "baguette", "bun", "tortilla", "pita", "パン", "食パン"}},
+	{Baking, []string{
+		"flour", "sugar", "brown sugar", "baking powder", "baking soda", "yeast", "cocoa", "chocolate",
+		"vanilla", "cornstarch", "powdered sugar",
+		"薄力粉", "強力粉", "小麦粉", "砂糖", "ベーキングパウダー", "片栗粉", "ドライイースト",
+	}},
+	{Pantry, []string{
+		"rice", "pasta", "spaghetti", "noodle", "oat", "bean", "lentil", "chickpea", "canned tomato",
+		"stock", "broth", "oil", "olive oil", "vinegar", "soy sauce", "honey", "peanut butter", "nut",
+		"almond", "walnut", "tofu", "miso", "mirin", "sake", "ketchup", "mayonnaise", "mustard",
+		"米", "パスタ", "うどん", "そば", "豆腐", "味噌", "みそ", "醤油", "しょうゆ", "みりん", "酒",
+		"酢", "油", "ごま油", "だし", "ケチャップ", "マヨネーズ", "はちみつ",
+	}},
+	{Spices, []string{
+		"salt", "black pepper", "cumin", "paprika", "cinnamon", "nutmeg", "chili powder", "curry powder",
+		"oregano", "bay leaf", "spice", "seasoning", "peppercorn",
+		"塩", "こしょう", "胡椒", "カレー粉", "七味", "一味",
+	}},
+	{Frozen, []string{"frozen", "ice cream", "冷凍"}},
+	{Beverages, []string{"wine", "beer", "juice", "coffee", "tea", "ワイン", "ジュース"}},
+}
+
+var (
+	keywords search.Keywords
+	aisles   = map[string]string{}
+	order    = map[string]int{}
+)
+
+func init() {
+	for _, entry := range table {
+		keywords.Add(entry.keywords...)
+		for _, text := range entry.keywords {
+			aisles[text] = entry.aisle
+		}
+	}
+	for i, aisle := range Aisles {
+		order[aisle] = i
+	}
+}
+
+// Aisle finds the aisle an ingredient is sold in. When keywords of several
+// aisles are found, like "frozen" and "spinach", the aisle of the longest
+// one wins, and of the first listed on a tie.
+func Aisle(name string) string {
+	best := ""
+	for _, text := range keywords.Find(name) {
+		if len([]rune(text)) > len([]rune(best)) {
+			best = text
+		}
+	}
+	if best == "" {
+		return Other
+	}
+	return aisles[best]
+}
+
+// Order returns the position of aisle in Aisles, for sorting
+func Order(aisle string) int {
+	if i, ok := order[aisle]; ok {
+		return i
+	}
+	return len(Aisles)
+}
@@ -0,0 +1,42 @@
+package grocery
+
+import "testing"
+
+func TestAisle(t *testing.T) {
+	type (
+		in struct {
+			name string
+		}
+		out struct {
+			aisle string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"yellow onions"}, out{Produce}},
+		"case-02": {in{"chicken thighs"}, out{Meat}},
+		"case-03": {in{"eggs"}, out{Dairy}},
+		"case-04": {in{"eggplant"}, out{Produce}},
+		"case-05": {in{"all-purpose flour"}, out{Baking}},
+		"case-06": {in{"peanut butter"}, out{Pantry}},
+		"case-07": {in{"black pepper"}, out{Spices}},
+		"case-08": {in{"red bell pepper"}, out{Produce}},
+		"case-09": {in{"frozen peas"}, out{Frozen}},
+		"case-10": {in{"鶏もも肉"}, out{Meat}},
+		"case-11": {in{"ごま油"}, out{Pantry}},
+		"case-12": {in{"saffron threads"}, out{Other}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			if aisle := Aisle(in.name); aisle != out.aisle {
+				t.Errorf("actual aisle %s, expected aisle %s", aisle, out.aisle)
+			}
+		})
+	}
+}
@@ -5,12 +5,23 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/motomux/smart-cooking-server/controller"
-	tarantool "github.com/tarantool/go-tarantool"
+	"github.com/motomux/smart-cooking-server/nutrition"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/store"
 )
 
 // Env is env values
 type Env struct {
-	Client *tarantool.Connection
+	Store store.Store
+	// ReadStore serves reads which may lag behind writes, like listing
+	// recipes. Store serves them when it is nil.
+	ReadStore store.Store
+	// Cluster reports the state of the database connections, when the
+	// store is a cluster of nodes
+	Cluster   controller.ClusterInterface
+	Index     search.Index
+	Suggester *search.Suggester
+	Nutrition *nutrition.DB
 }
 
 // NewHandler inititializes mux and register handlers
@@ -19,11 +30,31 @@ func NewHandler(env *Env) *httprouter.Router {
 
 	registerStatusHealthz(mux)
 
+	if env.Cluster != nil {
+		registerStatusDB(mux, env)
+	}
+
 	registerRecipes(mux, env)
 
+	registerIngredients(mux)
+
+	registerShoppingLists(mux, env)
+
+	registerMealPlans(mux, env)
+
+	registerPantry(mux, env)
+
 	return mux
 }
 
+// readStore is the store of reads which may lag behind writes
+func (env *Env) readStore() store.Store {
+	if env.ReadStore != nil {
+		return env.ReadStore
+	}
+	return env.Store
+}
+
 func withGetOneCtrl(ctrl controller.GetOneCtrlInterface) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		ctrl.GetOne(w, r, ps)
@@ -41,3 +72,42 @@ func withPostCtrl(ctrl controller.PostCtrlInterface) httprouter.Handle {
 		ctrl.Post(w, r, ps)
 	}
 }
+
+func withPutCtrl(ctrl controller.PutCtrlInterface) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctrl.Put(w, r, ps)
+	}
+}
+
+func withPatchCtrl(ctrl controller.PatchCtrlInterface) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctrl.Patch(w, r, ps)
+	}
+}
+
+func withDeleteCtrl(ctrl controller.DeleteCtrlInterface) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctrl.Delete(w, r, ps)
+	}
+}
+
+// withStatic serves static paths which share their segment with the param
+// of a wildcard route, since httprouter doesn't allow registering both
+func withStatic(param string, static map[string]httprouter.Handle, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if s, ok := static[ps.ByName(param)]; ok {
+			s(w, r, httprouter.Params{})
+			return
+		}
+		h(w, r, ps)
+	}
+}
+
+// methodNotAllowed answers like httprouter does for methods no route has,
+// for wildcard routes registered only to serve static paths
+func methodNotAllowed(allow string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w.Header().Set("Allow", allow)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
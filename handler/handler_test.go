@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/nutrition"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+func TestHandlerMemoryStore(t *testing.T) {
+	db := store.NewMemory()
+	if err := resource.CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	mux := NewHandler(&Env{
+		Store:     db,
+		Index:     search.NewMemoryIndex(service.RecipeSearchFields...),
+		Suggester: search.NewSuggester(service.RecipeSuggestWeights),
+		Nutrition: nutrition.Staples,
+	})
+
+	type (
+		in struct {
+			method string
+			path   string
+			body   string
+		}
+		out struct {
+			status int
+			body   string
+		}
+	)
+
+	// the requests build on each other, so they run in order
+	tests := []struct {
+		in
+		out
+	}{
+		{
+			in{"POST", "/recipes", `{"title":"Pancakes","ingredients":[{"name":"milk","quantity":"1/2","unit":"cup"},{"name":"eggs","quantity":2}],"howto":[{"text":"Whisk"}],"servings":2}`},
+			out{http.StatusCreated, `"id":1,"title":"Pancakes"`},
+		},
+		{
+			in{"GET", "/recipes/1", ""},
+			out{http.StatusOK, `"title":"Pancakes"`},
+		},
+		{
+			in{"PATCH", "/recipes/1", `{"title":"Crepes"}`},
+			out{http.StatusOK, `"title":"Crepes"`},
+		},
+		{
+			in{"GET", "/recipes", ""},
+			out{http.StatusOK, `"title":"Crepes"`},
+		},
+		{
+			in{"POST", "/shopping-lists", `{"name":"Brunch","recipes":[{"recipe_id":1,"servings":4}]}`},
+			out{http.StatusCreated, `"name":"milk","quantity":"1","unit":"cup"`},
+		},
+		{
+			in{"DELETE", "/recipes/1", ""},
+			out{http.StatusNoContent, ""},
+		},
+		{
+			in{"GET", "/recipes/1", ""},
+			out{http.StatusNotFound, ""},
+		},
+		{
+			in{"POST", "/recipes/1/restore", ""},
+			out{http.StatusOK, `"title":"Crepes"`},
+		},
+	}
+
+	for _, test := range tests {
+		in, out := test.in, test.out
+
+		req := httptest.NewRequest(in.method, in.path, strings.NewReader(in.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != out.status {
+			t.Fatalf("%s %s: actual status %d, expected status %d: %s", in.method, in.path, w.Code, out.status, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), out.body) {
+			t.Errorf("%s %s: actual body %s, expected to contain %s", in.method, in.path, w.Body, out.body)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package handler
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/controller"
+)
+
+func registerIngredients(mux *httprouter.Router) {
+	parseCtrl := controller.NewIngredientsParseCtrl()
+
+	mux.POST("/ingredients/parse", withPostCtrl(parseCtrl))
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/controller"
+)
+
+func registerMealPlans(mux *httprouter.Router, env *Env) {
+	ctrl := controller.NewMealPlansCtrl(env.Store)
+	mealsCtrl := &controller.MealsCtrl{Svc: ctrl.Svc}
+	weekCtrl := &controller.MealWeekCtrl{Svc: ctrl.Svc}
+	calendarCtrl := &controller.MealCalendarCtrl{Svc: ctrl.Svc}
+	shoppingCtrl := &controller.MealShoppingListCtrl{Svc: ctrl.Svc}
+
+	mux.GET("/meal-plans", withGetCtrl(ctrl))
+	mux.GET("/meal-plans/:id", withGetOneCtrl(ctrl))
+	mux.POST("/meal-plans", withPostCtrl(ctrl))
+	mux.DELETE("/meal-plans/:id", withDeleteCtrl(ctrl))
+	mux.PUT("/meal-plans/:id/meals/:date/:slot", withPutCtrl(mealsCtrl))
+	mux.DELETE("/meal-plans/:id/meals/:date/:slot", withDeleteCtrl(mealsCtrl))
+	mux.GET("/meal-plans/:id/week", withGetCtrl(weekCtrl))
+	mux.GET("/meal-plans/:id/calendar.ics", withGetCtrl(calendarCtrl))
+	mux.POST("/meal-plans/:id/shopping-list", withPostCtrl(shoppingCtrl))
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/controller"
+)
+
+func registerPantry(mux *httprouter.Router, env *Env) {
+	ctrl := controller.NewPantryCtrl(env.Store)
+
+	mux.GET("/pantry", withGetCtrl(ctrl))
+	mux.POST("/pantry", withPostCtrl(ctrl))
+	mux.PUT("/pantry/:id", withPutCtrl(ctrl))
+	mux.DELETE("/pantry/:id", withDeleteCtrl(ctrl))
+}
@@ -3,9 +3,42 @@ package handler
 import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/motomux/smart-cooking-server/controller"
+	"github.com/motomux/smart-cooking-server/service"
 )
 
 func registerRecipes(mux *httprouter.Router, env *Env) {
-	ctrl := controller.NewRecipesCtrl(env.Client)
-	mux.GET("/recipes/:id", withGetOneCtrl(ctrl))
+	searchSvc := service.NewRecipesSearchSvc(env.readStore(), env.Index)
+	suggestSvc := service.NewRecipesSuggestSvc(env.Store, env.Suggester)
+	nutritionSvc := service.NewRecipesNutritionSvc(env.Store, env.Nutrition)
+	listeners := []service.RecipeListener{searchSvc, suggestSvc, nutritionSvc}
+
+	ctrl := controller.NewRecipesCtrl(env.Store, listeners...)
+	readCtrl := controller.NewRecipesCtrl(env.readStore())
+	trashCtrl := controller.NewRecipesTrashCtrl(env.Store)
+	restoreCtrl := controller.NewRecipesRestoreCtrl(env.Store, listeners...)
+	searchCtrl := &controller.RecipesSearchCtrl{Svc: searchSvc}
+	suggestCtrl := &controller.RecipesSuggestCtrl{Svc: suggestSvc}
+	nutritionCtrl := &controller.RecipesNutritionCtrl{Svc: nutritionSvc}
+	labelsCtrl := controller.NewRecipesLabelsCtrl(env.Store, listeners...)
+	importCtrl := controller.NewRecipesImportCtrl(env.Store, listeners...)
+	cookableCtrl := controller.NewRecipesCookableCtrl(env.Store, env.Index)
+
+	mux.GET("/recipes", withGetCtrl(readCtrl))
+	mux.GET("/recipes/:id", withStatic("id", map[string]httprouter.Handle{
+		"trash":    withGetCtrl(trashCtrl),
+		"search":   withGetCtrl(searchCtrl),
+		"suggest":  withGetCtrl(suggestCtrl),
+		"cookable": withGetCtrl(cookableCtrl),
+	}, withGetOneCtrl(readCtrl)))
+	mux.POST("/recipes", withPostCtrl(ctrl))
+	mux.POST("/recipes/:id", withStatic("id", map[string]httprouter.Handle{
+		"import": withPostCtrl(importCtrl),
+	}, methodNotAllowed("GET, PUT, PATCH, DELETE")))
+	mux.PUT("/recipes/:id", withPutCtrl(ctrl))
+	mux.PATCH("/recipes/:id", withPatchCtrl(ctrl))
+	mux.DELETE("/recipes/:id", withDeleteCtrl(ctrl))
+	mux.POST("/recipes/:id/restore", withPostCtrl(restoreCtrl))
+	mux.GET("/recipes/:id/nutrition", withGetCtrl(nutritionCtrl))
+	mux.GET("/recipes/:id/labels", withGetCtrl(labelsCtrl))
+	mux.PUT("/recipes/:id/labels/:label", withPutCtrl(labelsCtrl))
 }
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/motomux/smart-cooking-server/controller"
+)
+
+func registerShoppingLists(mux *httprouter.Router, env *Env) {
+	ctrl := controller.NewShoppingListsCtrl(env.Store)
+	itemsCtrl := &controller.ShoppingItemsCtrl{Svc: ctrl.Svc}
+
+	mux.GET("/shopping-lists", withGetCtrl(ctrl))
+	mux.GET("/shopping-lists/:id", withGetOneCtrl(ctrl))
+	mux.POST("/shopping-lists", withPostCtrl(ctrl))
+	mux.DELETE("/shopping-lists/:id", withDeleteCtrl(ctrl))
+	mux.PATCH("/shopping-lists/:id/items/:index", withPatchCtrl(itemsCtrl))
+}
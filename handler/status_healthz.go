@@ -10,3 +10,9 @@ func registerStatusHealthz(mux *httprouter.Router) {
 
 	mux.GET("/_status/healthz", withGetCtrl(ctrl))
 }
+
+func registerStatusDB(mux *httprouter.Router, env *Env) {
+	ctrl := controller.NewStatusDBCtrl(env.Cluster)
+
+	mux.GET("/_status/db", withGetCtrl(ctrl))
+}
@@ -0,0 +1,99 @@
+// Package ical writes iCalendar (RFC 5545) feeds.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Calendar is a feed of events
+type Calendar struct {
+	// ProdID identifies the product which wrote the feed
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event is a calendar event. Times are written in UTC.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	// Alarm is how long before Start the event reminds, none when 0
+	Alarm time.Duration
+}
+
+// timeFormat is the UTC form of DATE-TIME values
+const timeFormat = "20060102T150405Z"
+
+// Write writes the calendar as of now to w
+func (c *Calendar) Write(w io.Writer, now time.Time) error {
+	lw := &lineWriter{w: w}
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + c.ProdID)
+	lw.line("CALSCALE:GREGORIAN")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	for _, e := range c.Events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + e.UID)
+		lw.line("DTSTAMP:" + now.UTC().Format(timeFormat))
+		lw.line("DTSTART:" + e.Start.UTC().Format(timeFormat))
+		lw.line("DTEND:" + e.End.UTC().Format(timeFormat))
+		lw.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.URL != "" {
+			lw.line("URL:" + e.URL)
+		}
+		if e.Alarm > 0 {
+			lw.line("BEGIN:VALARM")
+			lw.line("ACTION:DISPLAY")
+			lw.line("DESCRIPTION:" + escape(e.Summary))
+			lw.line(fmt.Sprintf("TRIGGER:-PT%dM", int(e.Alarm/time.Minute)))
+			lw.line("END:VALARM")
+		}
+		lw.line("END:VEVENT")
+	}
+	lw.line("END:VCALENDAR")
+	return lw.err
+}
+
+// escape escapes TEXT values
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// lineWriter writes content lines ended by CRLF and folded at 75 octets,
+// never splitting a UTF-8 sequence. It keeps the first error.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		n := len(string(r))
+		if width+n > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	b.WriteString("\r\n")
+	_, lw.err = io.WriteString(lw.w, b.String())
+}
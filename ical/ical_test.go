@@ -0,0 +1,55 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	c := &Calendar{
+		ProdID: "-//test//EN",
+		Name:   "Week",
+		Events: []Event{{
+			UID:         "meal-1@test",
+			Summary:     "Dinner: Curry, rice; salad",
+			Description: "Start cooking at 18:00\nServes 4",
+			URL:         "https://example.com/recipes/1",
+			Start:       time.Date(2026, 10, 19, 19, 0, 0, 0, tokyo),
+			End:         time.Date(2026, 10, 19, 20, 0, 0, 0, tokyo),
+			Alarm:       time.Hour,
+		}},
+	}
+
+	var b bytes.Buffer
+	if err := c.Write(&b, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, line := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTAMP:20261017T000000Z\r\n",
+		"DTSTART:20261019T100000Z\r\n",
+		"SUMMARY:Dinner: Curry\\, rice\\; salad\r\n",
+		"DESCRIPTION:Start cooking at 18:00\\nServes 4\r\n",
+		"TRIGGER:-PT60M\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("missing %q in\n%s", line, out)
+		}
+	}
+
+	b.Reset()
+	c.Events[0].Summary = strings.Repeat("あ", 30)
+	if err := c.Write(&b, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(b.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+}
@@ -2,33 +2,134 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/motomux/smart-cooking-server/handler"
-	tarantool "github.com/tarantool/go-tarantool"
+	"github.com/motomux/smart-cooking-server/migrate"
+	"github.com/motomux/smart-cooking-server/nutrition"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/service"
+	"github.com/motomux/smart-cooking-server/store"
 )
 
 func main() {
 	port := flag.String("port", "80", "port of server")
-	db := flag.String("db", "smart-cooking-db:3301", "host of db server")
+	storeKind := flag.String("store", "tarantool", "where data is stored: tarantool, file, or memory, which is lost on exit")
+	db := flag.String("db", "smart-cooking-db:3301", "host of db server, or comma-separated hosts of masters, preferred in order while they take writes")
+	dbReplicas := flag.String("db-replicas", "", "comma-separated hosts of read-only db replicas, which serve reads like listing recipes and never take writes")
+	dbMaxLag := flag.Duration("db-max-lag", 10*time.Second, "how far a db replica may lag behind its master before reads stop going to it")
+	dataDir := flag.String("data-dir", "data", "directory the file store keeps data in")
+	trashRetention := flag.Duration("trash-retention", 30*24*time.Hour, "how long deleted recipes are kept in the trash")
+	trashPurgeInterval := flag.Duration("trash-purge-interval", time.Hour, "how often the trash is purged")
+	searchRefreshInterval := flag.Duration("search-refresh-interval", time.Minute, "how often the search index and suggestions are refreshed from the store, which picks up recipes written through other servers")
+	searchAnalyzers := flag.String("search-analyzers", "", "analyzers of search fields, like title=cjk,steps=english")
+	nutritionDB := flag.String("nutrition-db", "", "CSV file of the nutrients of foods per 100 g, the built-in staples are used when empty")
+	migrateHowto := flag.Bool("migrate-howto", false, "convert comma-joined howto of stored recipes into steps and exit")
+	relabel := flag.Bool("relabel", false, "derive the dietary labels of stored recipes again and exit")
+	createIndexes := flag.Bool("create-indexes", false, "same as migrate up, kept for older deploy scripts; the file and memory stores create their spaces and indexes on start")
 	flag.Parse()
+	if *trashPurgeInterval <= 0 {
+		log.Fatalf("Invalid trash purge interval %s, it must be positive", *trashPurgeInterval)
+	}
+	if *searchRefreshInterval <= 0 {
+		log.Fatalf("Invalid search refresh interval %s, it must be positive", *searchRefreshInterval)
+	}
+
+	client, err := openStore(*storeKind, *db, *dbReplicas, *dbMaxLag, *dataDir)
+	if err != nil {
+		log.Fatalf("Failed to open %s store: %s", *storeKind, err.Error())
+	}
+
+	if flag.Arg(0) == "migrate" {
+		runMigrate(client, flag.Args()[1:])
+		return
+	}
+
+	if *migrateHowto {
+		n, err := resource.NewRecipesRsc(client).MigrateHowto()
+		if err != nil {
+			log.Fatalf("Failed to migrate howto after %d recipes: %s", n, err.Error())
+		}
+		log.Printf("Migrated howto of %d recipes", n)
+		return
+	}
+
+	if *relabel {
+		n, err := service.NewRecipesLabelsSvc(client).Relabel()
+		if err != nil {
+			log.Fatalf("Failed to relabel after %d recipes: %s", n, err.Error())
+		}
+		log.Printf("Relabeled %d recipes", n)
+		return
+	}
+
+	if *createIndexes {
+		if _, ok := client.(migrate.Evaler); ok {
+			runMigrate(client, []string{"up"})
+		}
+		return
+	}
 
-	opts := tarantool.Opts{
-		Timeout:       500 * time.Millisecond,
-		Reconnect:     1 * time.Second,
-		MaxReconnects: 3,
+	if ev, ok := client.(migrate.Evaler); ok {
+		pending, err := migrate.NewMigrator(ev).Pending()
+		if err != nil {
+			log.Fatalf("Failed to read the schema version: %s", err.Error())
+		}
+		if len(pending) > 0 {
+			log.Fatalf("Schema is behind by %d migrations, run migrate first", len(pending))
+		}
+	}
+	if v, ok := client.(store.Verifier); ok {
+		if err := v.Verify(resource.Spaces()); err != nil {
+			log.Fatalf("Failed to verify the schema: %s", err.Error())
+		}
 	}
 
-	client, err := tarantool.Connect(*db, opts)
+	fields, err := search.WithAnalyzers(service.RecipeSearchFields, *searchAnalyzers)
+	if err != nil {
+		log.Fatalf("Invalid search analyzers: %s", err.Error())
+	}
+	index := search.NewMemoryIndex(fields...)
+	searchSvc := service.NewRecipesSearchSvc(client, index)
+	n, err := searchSvc.Reindex()
 	if err != nil {
-		log.Fatalf("Failed to connect: %s, %s", err.Error(), *db)
+		log.Fatalf("Failed to build search index: %s", err.Error())
 	}
-	log.Println("Connected to tarantool")
+	log.Printf("Indexed %d recipes", n)
+	go searchSvc.ReindexEvery(*searchRefreshInterval)
+
+	suggester := search.NewSuggester(service.RecipeSuggestWeights)
+	suggestSvc := service.NewRecipesSuggestSvc(client, suggester)
+	if _, err := suggestSvc.Rebuild(); err != nil {
+		log.Fatalf("Failed to build suggestions: %s", err.Error())
+	}
+	go suggestSvc.RebuildEvery(*searchRefreshInterval)
+
+	foods := nutrition.Staples
+	if *nutritionDB != "" {
+		foods, err = importNutrition(*nutritionDB)
+		if err != nil {
+			log.Fatalf("Failed to import nutrition database: %s", err.Error())
+		}
+	}
+
+	go service.NewRecipesSvc(client).PurgeTrashEvery(*trashPurgeInterval, *trashRetention)
 
 	env := &handler.Env{
-		Client: client,
+		Store:     client,
+		Index:     index,
+		Suggester: suggester,
+		Nutrition: foods,
+	}
+	if cluster, ok := client.(*store.Cluster); ok {
+		env.ReadStore = cluster.Replicas()
+		env.Cluster = cluster
 	}
 	// Handler
 	mux := handler.NewHandler(env)
@@ -37,3 +138,63 @@ func main() {
 	log.Println("Starting web server on", port)
 	log.Fatalln(http.ListenAndServe(":"+*port, mux))
 }
+
+// openStore opens the store of kind. Tarantool masters are at addrs and
+// replicas at replicas, both comma-separated, and replicas lagging more
+// than maxLag serve no reads. The file store keeps data in dir, and both
+// embedded stores start with every space created.
+func openStore(kind, addrs, replicas string, maxLag time.Duration, dir string) (store.Store, error) {
+	switch kind {
+	case "tarantool":
+		opts := store.ClusterOpts{
+			Timeout:       500 * time.Millisecond,
+			MinBackoff:    100 * time.Millisecond,
+			MaxBackoff:    30 * time.Second,
+			CheckInterval: time.Second,
+			MaxLag:        maxLag,
+		}
+		cluster := store.NewCluster(splitHosts(addrs), splitHosts(replicas), opts)
+		log.Println("Waiting for a tarantool master")
+		if err := cluster.WaitMaster(); err != nil {
+			return nil, err
+		}
+		return cluster, nil
+	case "memory":
+		db := store.NewMemory()
+		if err := resource.CreateSpaces(db); err != nil {
+			return nil, err
+		}
+		log.Println("Storing data in memory")
+		return db, nil
+	case "file":
+		db, err := store.OpenFile(dir)
+		if err != nil {
+			return nil, err
+		}
+		if err := resource.CreateSpaces(db); err != nil {
+			return nil, err
+		}
+		log.Println("Storing data in", dir)
+		return db, nil
+	}
+	return nil, fmt.Errorf("unknown store %q", kind)
+}
+
+func splitHosts(hosts string) []string {
+	var split []string
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			split = append(split, host)
+		}
+	}
+	return split
+}
+
+func importNutrition(path string) (*nutrition.DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return nutrition.ImportCSV(f)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/motomux/smart-cooking-server/migrate"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// runMigrate runs the migrate subcommand:
+//
+//	migrate [-dry-run] [up [version] | down [version] | status]
+//
+// up applies every pending migration, or those up to version. down reverts
+// the last migration applied, or every one above version.
+func runMigrate(db store.Store, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print the migrations which would run and their scripts without running them")
+	flags.Parse(args)
+
+	ev, ok := db.(migrate.Evaler)
+	if !ok {
+		log.Fatalln("Migrations only apply to tarantool, the other stores create their spaces on start")
+	}
+	m := migrate.NewMigrator(ev)
+	applied, err := m.Applied()
+	if err != nil {
+		log.Fatalf("Failed to read the schema version: %s", err.Error())
+	}
+
+	command := flags.Arg(0)
+	to, err := migrateTarget(command, flags.Arg(1), applied, m.Latest())
+	if err != nil {
+		log.Fatalf("Invalid version: %s", err.Error())
+	}
+
+	var plan []migrate.Migration
+	switch command {
+	case "", "up":
+		plan, err = m.Up(to, *dryRun)
+	case "down":
+		plan, err = m.Down(to, *dryRun)
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			log.Fatalf("Failed to read the schema version: %s", err.Error())
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%4d  %-7s  %s\n", status.Version, state, status.Name)
+		}
+		return
+	default:
+		log.Fatalf("Unknown migrate command %q, expected up, down or status", command)
+	}
+
+	for _, migration := range plan {
+		script, verb := migration.Up, "Applied"
+		if command == "down" {
+			script, verb = migration.Down, "Reverted"
+		}
+		if *dryRun {
+			fmt.Printf("-- %d %s\n%s\n", migration.Version, migration.Name, script)
+			continue
+		}
+		log.Printf("%s migration %d %s", verb, migration.Version, migration.Name)
+	}
+	if err != nil {
+		log.Fatalf("Failed to migrate: %s", err.Error())
+	}
+	if len(plan) == 0 {
+		log.Println("Schema is up to date")
+	}
+}
+
+// migrateTarget is the version command migrates to: arg when given, else
+// the latest version for up and the version before the last one applied
+// for down
+func migrateTarget(command, arg string, applied []int, latest int) (int, error) {
+	if arg != "" {
+		return strconv.Atoi(arg)
+	}
+	if command != "down" {
+		return latest, nil
+	}
+	if len(applied) < 2 {
+		return 0, nil
+	}
+	return applied[len(applied)-2], nil
+}
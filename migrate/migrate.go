@@ -0,0 +1,191 @@
+// Package migrate applies versioned Lua migrations to the tarantool schema,
+// and records the versions applied in the _migrations space.
+package migrate
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Migration changes the schema from the previous version to Version with
+// Up, and back with Down. Both are Lua chunks.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Evaler evaluates Lua on a tarantool server, reading the values it returns
+// into result, like store.Tarantool does
+type Evaler interface {
+	Eval(expr string, args []interface{}, result interface{}) error
+}
+
+const (
+	// luaApplied returns the versions applied, none when the _migrations
+	// space doesn't exist yet
+	luaApplied = `
+local space = box.space._migrations
+if space == nil then return end
+local versions = {}
+for _, t in space:pairs() do table.insert(versions, t[1]) end
+return unpack(versions)
+`
+	luaCreateSpace = `
+box.schema.space.create('_migrations', {if_not_exists = true})
+box.space._migrations:create_index('primary', {parts = {1, 'unsigned'}, if_not_exists = true})
+`
+	// a migration runs in a block of its own, so that its locals don't
+	// shadow version and name, followed by the write recording it
+	luaUpHead   = "local version, name = ...\ndo\n"
+	luaUpTail   = "\nend\nbox.space._migrations:replace({version, name, os.time()})\n"
+	luaDownHead = "local version = ...\ndo\n"
+	luaDownTail = "\nend\nbox.space._migrations:delete({version})\n"
+)
+
+// Migrator applies migrations through Ev
+type Migrator struct {
+	Ev         Evaler
+	Migrations []Migration
+}
+
+// NewMigrator initiates Migrator with Migrations
+func NewMigrator(ev Evaler) *Migrator {
+	return &Migrator{Ev: ev, Migrations: Migrations}
+}
+
+// Latest is the version of the last migration
+func (m *Migrator) Latest() int {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Applied returns the versions applied, in ascending order
+func (m *Migrator) Applied() ([]int, error) {
+	var versions []int
+	if err := m.Ev.Eval(luaApplied, []interface{}{}, &versions); err != nil {
+		return nil, err
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// Status is a migration and whether it is applied
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Status returns every migration, in order, with whether it is applied
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(m.Migrations))
+	for i, migration := range m.Migrations {
+		statuses[i] = Status{migration, hasVersion(applied, migration.Version)}
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations not applied yet
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+	return planUp(m.Migrations, applied, m.Latest()), nil
+}
+
+// Up applies the migrations not applied yet up to version to, in order, and
+// returns them. With dryRun it only returns them.
+func (m *Migrator) Up(to int, dryRun bool) ([]Migration, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+	plan := planUp(m.Migrations, applied, to)
+	if dryRun || len(plan) == 0 {
+		return plan, nil
+	}
+
+	if err := m.Ev.Eval(luaCreateSpace, []interface{}{}, nil); err != nil {
+		return nil, err
+	}
+	for i, migration := range plan {
+		err := m.Ev.Eval(luaUpHead+migration.Up+luaUpTail, []interface{}{migration.Version, migration.Name}, nil)
+		if err != nil {
+			return plan[:i], fmt.Errorf("migration %d %s: %s", migration.Version, migration.Name, err)
+		}
+	}
+	return plan, nil
+}
+
+// Down reverts the migrations applied above version to, latest first, and
+// returns them. With dryRun it only returns them.
+func (m *Migrator) Down(to int, dryRun bool) ([]Migration, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planDown(m.Migrations, applied, to)
+	if err != nil || dryRun {
+		return plan, err
+	}
+
+	for i, migration := range plan {
+		err := m.Ev.Eval(luaDownHead+migration.Down+luaDownTail, []interface{}{migration.Version}, nil)
+		if err != nil {
+			return plan[:i], fmt.Errorf("migration %d %s: %s", migration.Version, migration.Name, err)
+		}
+	}
+	return plan, nil
+}
+
+// planUp returns the migrations up to version to which are not applied
+func planUp(migrations []Migration, applied []int, to int) []Migration {
+	var plan []Migration
+	for _, migration := range migrations {
+		if migration.Version <= to && !hasVersion(applied, migration.Version) {
+			plan = append(plan, migration)
+		}
+	}
+	return plan
+}
+
+// planDown returns the migrations applied above version to, latest first.
+// A version applied by a newer release can't be reverted, since its Down is
+// unknown.
+func planDown(migrations []Migration, applied []int, to int) ([]Migration, error) {
+	var plan []Migration
+	for i := len(applied) - 1; i >= 0 && applied[i] > to; i-- {
+		migration, ok := find(migrations, applied[i])
+		if !ok {
+			return nil, fmt.Errorf("version %d was applied by a newer release", applied[i])
+		}
+		plan = append(plan, migration)
+	}
+	return plan, nil
+}
+
+func find(migrations []Migration, version int) (Migration, bool) {
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func hasVersion(versions []int, version int) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
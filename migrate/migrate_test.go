@@ -0,0 +1,139 @@
+package migrate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeEvaler runs migrations by recording their versions
+type fakeEvaler struct {
+	applied []int
+	ran     []int
+	// fail is the version whose migration fails
+	fail int
+}
+
+func (e *fakeEvaler) Eval(expr string, args []interface{}, result interface{}) error {
+	switch {
+	case expr == luaApplied:
+		*result.(*[]int) = append([]int(nil), e.applied...)
+		return nil
+	case expr == luaCreateSpace:
+		return nil
+	}
+
+	version := args[0].(int)
+	if version == e.fail {
+		return errors.New("lua error")
+	}
+	e.ran = append(e.ran, version)
+	if strings.HasPrefix(expr, luaUpHead) {
+		e.applied = append(e.applied, version)
+		return nil
+	}
+	var applied []int
+	for _, v := range e.applied {
+		if v != version {
+			applied = append(applied, v)
+		}
+	}
+	e.applied = applied
+	return nil
+}
+
+func TestMigrator(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "one", Up: "up1", Down: "down1"},
+		{Version: 2, Name: "two", Up: "up2", Down: "down2"},
+		{Version: 3, Name: "three", Up: "up3", Down: "down3"},
+	}
+
+	type (
+		in struct {
+			applied []int
+			down    bool
+			to      int
+			dryRun  bool
+			fail    int
+		}
+		out struct {
+			planned []int
+			ran     []int
+			err     bool
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{nil, false, 3, false, 0}, out{[]int{1, 2, 3}, []int{1, 2, 3}, false}},
+		"case-02": {in{[]int{1}, false, 2, false, 0}, out{[]int{2}, []int{2}, false}},
+		// a migration skipped by an earlier release is applied
+		"case-03": {in{[]int{1, 3}, false, 3, false, 0}, out{[]int{2}, []int{2}, false}},
+		"case-04": {in{nil, false, 3, true, 0}, out{[]int{1, 2, 3}, nil, false}},
+		// migrations after a failed one are not run
+		"case-05": {in{nil, false, 3, false, 2}, out{[]int{1}, []int{1}, true}},
+		"case-06": {in{[]int{1, 2, 3}, true, 1, false, 0}, out{[]int{3, 2}, []int{3, 2}, false}},
+		"case-07": {in{[]int{1, 2, 3}, true, 0, true, 0}, out{[]int{3, 2, 1}, nil, false}},
+		// a version applied by a newer release can't be reverted
+		"case-08": {in{[]int{1, 4}, true, 0, false, 0}, out{nil, nil, true}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			ev := &fakeEvaler{applied: in.applied, fail: in.fail}
+			m := &Migrator{Ev: ev, Migrations: migrations}
+			var plan []Migration
+			var err error
+			if in.down {
+				plan, err = m.Down(in.to, in.dryRun)
+			} else {
+				plan, err = m.Up(in.to, in.dryRun)
+			}
+
+			if (err != nil) != out.err {
+				t.Errorf("actual err %v, expected err %v", err, out.err)
+			}
+			var planned []int
+			for _, migration := range plan {
+				planned = append(planned, migration.Version)
+			}
+			if !reflect.DeepEqual(planned, out.planned) {
+				t.Errorf("actual planned %v, expected planned %v", planned, out.planned)
+			}
+			if !reflect.DeepEqual(ev.ran, out.ran) {
+				t.Errorf("actual ran %v, expected ran %v", ev.ran, out.ran)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	migrations := []Migration{{Version: 1, Name: "one"}, {Version: 2, Name: "two"}, {Version: 3, Name: "three"}}
+	m := &Migrator{Ev: &fakeEvaler{applied: []int{1, 3}}, Migrations: migrations}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Status{{migrations[0], true}, {migrations[1], false}, {migrations[2], true}}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("actual statuses %v, expected statuses %v", statuses, expected)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	for i, migration := range Migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %q has version %d, expected %d", migration.Name, migration.Version, i+1)
+		}
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %d lacks a script", migration.Version)
+		}
+	}
+}
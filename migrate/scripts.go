@@ -0,0 +1,119 @@
+package migrate
+
+// Migrations are the migrations of the tarantool schema, in order of
+// version. A released migration is never changed, the schema only changes
+// by appending migrations. Up scripts create what may already exist with
+// if_not_exists, since servers set up before migrations were recorded have
+// part of the schema already.
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create recipes",
+		Up: `
+box.schema.sequence.create('recipes_id', {if_not_exists = true})
+box.schema.space.create('recipes', {if_not_exists = true})
+box.space.recipes:create_index('primary', {parts = {1, 'unsigned'}, if_not_exists = true})
+`,
+		Down: `
+if box.space.recipes then box.space.recipes:drop() end
+if box.sequence.recipes_id then box.sequence.recipes_id:drop() end
+`,
+	},
+	{
+		Version: 2,
+		Name:    "index recipes by cuisine, difficulty, rating and cook time",
+		// recipes written before version 2 of the tuple format lack the
+		// indexed fields, and get them with their zero values; the fields
+		// in between are nil, which reads as a zero value too. The field
+		// numbers repeat those of the recipe tuple in package resource,
+		// counted from 1; servers verify the indexes against the
+		// definitions of resource.Spaces on start, so a mismatch stops
+		// them rather than going unnoticed.
+		Up: `
+local recipes = box.space.recipes
+local zero = {[10] = '', [11] = '', [13] = 0, [14] = 0}
+for _, t in recipes:pairs() do
+    if t:len() < 14 then
+        local fields = t:totable()
+        for i = t:len() + 1, 14 do
+            if zero[i] ~= nil then fields[i] = zero[i] else fields[i] = box.NULL end
+        end
+        recipes:replace(fields)
+    end
+end
+recipes:create_index('cuisine', {parts = {10, 'string', 1, 'unsigned'}, if_not_exists = true})
+recipes:create_index('difficulty', {parts = {11, 'string', 1, 'unsigned'}, if_not_exists = true})
+recipes:create_index('rating', {parts = {14, 'number', 1, 'unsigned'}, if_not_exists = true})
+recipes:create_index('cook_time', {parts = {13, 'integer', 1, 'unsigned'}, if_not_exists = true})
+`,
+		Down: `
+for _, name in ipairs({'cook_time', 'rating', 'difficulty', 'cuisine'}) do
+    if box.space.recipes.index[name] then box.space.recipes.index[name]:drop() end
+end
+`,
+	},
+	{
+		Version: 3,
+		Name:    "create recipe nutrition",
+		Up: `
+box.schema.space.create('recipe_nutrition', {if_not_exists = true})
+box.space.recipe_nutrition:create_index('primary', {parts = {1, 'unsigned'}, if_not_exists = true})
+`,
+		Down: `
+if box.space.recipe_nutrition then box.space.recipe_nutrition:drop() end
+`,
+	},
+	{
+		Version: 4,
+		Name:    "create recipe label audit",
+		Up: `
+box.schema.sequence.create('recipe_label_audit_id', {if_not_exists = true})
+box.schema.space.create('recipe_label_audit', {if_not_exists = true})
+box.space.recipe_label_audit:create_index('primary', {parts = {1, 'unsigned'}, if_not_exists = true})
+box.space.recipe_label_audit:create_index('recipe', {parts = {2, 'unsigned', 1, 'unsigned'}, if_not_exists = true})
+`,
+		Down: `
+if box.space.recipe_label_audit then box.space.recipe_label_audit:drop() end
+if box.sequence.recipe_label_audit_id then box.sequence.recipe_label_audit_id:drop() end
+`,
+	},
+	{
+		Version: 5,
+		Name:    "create shopping lists",
+		Up: `
+box.schema.sequence.create('shopping_lists_id', {if_not_exists = true})
+box.schema.space.create('shopping_lists', {if_not_exists = true})
+box.space.shopping_lists:create_index('primary', {parts = {1, 'unsigned'}, if_not_exists = true})
+`,
+		Down: `
+if box.space.shopping_lists then box.space.shopping_lists:drop() end
+if box.sequence.shopping_lists_id then box.sequence.shopping_lists_id:drop() end
+`,
+	},
+	{
+		Version: 6,
+		Name:    "create meal plans",
+		Up: `
+box.schema.sequence.create('meal_plans_id', {if_not_exists = true})
+box.schema.space.create('meal_plans', {if_not_exists = true})
+box.space.meal_plans:create_index('primary', {parts = {1, 'unsigned'}, if_not_exists = true})
+`,
+		Down: `
+if box.space.meal_plans then box.space.meal_plans:drop() end
+if box.sequence.meal_plans_id then box.sequence.meal_plans_id:drop() end
+`,
+	},
+	{
+		Version: 7,
+		Name:    "create pantry",
+		Up: `
+box.schema.sequence.create('pantry_id', {if_not_exists = true})
+box.schema.space.create('pantry', {if_not_exists = true})
+box.space.pantry:create_index('primary', {parts = {1, 'unsigned'}, if_not_exists = true})
+`,
+		Down: `
+if box.space.pantry then box.space.pantry:drop() end
+if box.sequence.pantry_id then box.sequence.pantry_id:drop() end
+`,
+	},
+}
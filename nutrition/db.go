@@ -0,0 +1,199 @@
+package nutrition
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/search"
+)
+
+// minMatchScore is the least similarity, by the Dice coefficient of their
+// terms, an ingredient name needs to a food name to match it
+const minMatchScore = 0.4
+
+// Food is an entry of the nutrient database
+type Food struct {
+	// Names are the name of the food and its other names, like the
+	// Japanese one
+	Names   []string
+	Per100g Nutrients
+	// PortionGrams is the weight of one piece, like an egg, 0 when the
+	// food isn't counted by pieces
+	PortionGrams float64
+}
+
+// DB is a database of the nutrients of foods
+type DB struct {
+	foods []Food
+	// terms are the analyzed terms of each name of each food
+	terms [][]map[string]bool
+	// Source identifies the data the database was built from, so that
+	// facts computed with other data can be told apart
+	Source string
+}
+
+var analyzer = search.Analyzers["cjk"]
+
+// NewDB initiates DB with foods. source identifies the data.
+func NewDB(source string, foods ...Food) *DB {
+	db := &DB{Source: source}
+	for _, food := range foods {
+		db.add(food)
+	}
+	return db
+}
+
+func (db *DB) add(food Food) {
+	names := make([]map[string]bool, len(food.Names))
+	for i, name := range food.Names {
+		names[i] = terms(name)
+	}
+	db.foods = append(db.foods, food)
+	db.terms = append(db.terms, names)
+}
+
+func terms(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, token := range analyzer.Analyze(text) {
+		set[token.Term] = true
+	}
+	return set
+}
+
+// Match finds the food whose name is the most similar to name
+func (db *DB) Match(name string) (*Food, bool) {
+	query := terms(name)
+	if len(query) == 0 {
+		return nil, false
+	}
+
+	best, bestScore, bestCommon := -1, 0.0, 0
+	for i, names := range db.terms {
+		for _, food := range names {
+			common := 0
+			for term := range query {
+				if food[term] {
+					common++
+				}
+			}
+			score := 2 * float64(common) / float64(len(query)+len(food))
+			if score > bestScore || score == bestScore && common > bestCommon {
+				best, bestScore, bestCommon = i, score, common
+			}
+		}
+	}
+	if best < 0 || bestScore < minMatchScore {
+		return nil, false
+	}
+	return &db.foods[best], true
+}
+
+// csvColumns maps the header names of nutrient database CSV files, as
+// exported from USDA FoodData Central and similar, to what they hold
+var csvColumns = map[string]string{
+	"name": "name", "description": "name", "food": "name",
+	"aliases":     "aliases",
+	"energy_kcal": "calories", "calories": "calories", "energy (kcal)": "calories",
+	"protein_g": "protein", "protein": "protein", "protein (g)": "protein",
+	"fat_g": "fat", "fat": "fat", "total lipid (fat) (g)": "fat", "total_lipid_g": "fat",
+	"carbohydrate_g": "carbohydrate", "carbohydrate": "carbohydrate", "carbohydrate, by difference (g)": "carbohydrate",
+	"fiber_g": "fiber", "fiber": "fiber", "fiber, total dietary (g)": "fiber",
+	"sodium_mg": "sodium", "sodium": "sodium", "sodium, na (mg)": "sodium",
+	"portion_g": "portion", "gram_weight": "portion", "portion": "portion",
+}
+
+// ImportCSV builds a database from CSV with a header row. Nutrient columns
+// hold amounts per 100 g, and aliases are other names separated by "|".
+// The source of the database is the hash of the data.
+func ImportCSV(r io.Reader) (*DB, error) {
+	hash := sha256.New()
+	reader := csv.NewReader(io.TeeReader(r, hash))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("nutrient csv header: %s", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		if column, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[column] = i
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("nutrient csv has no name column")
+	}
+
+	var foods []Food
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		food, err := parseFood(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("nutrient csv line %d: %s", line, err)
+		}
+		foods = append(foods, food)
+	}
+
+	return NewDB(hex.EncodeToString(hash.Sum(nil))[:16], foods...), nil
+}
+
+func parseFood(record []string, columns map[string]int) (Food, error) {
+	field := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	number := func(column string) (float64, error) {
+		s := field(column)
+		if s == "" {
+			return 0, nil
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %s", column, err)
+		}
+		return v, nil
+	}
+
+	food := Food{Names: []string{field("name")}}
+	if food.Names[0] == "" {
+		return food, fmt.Errorf("name is empty")
+	}
+	for _, alias := range strings.Split(field("aliases"), "|") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			food.Names = append(food.Names, alias)
+		}
+	}
+
+	var err error
+	for _, f := range []struct {
+		column string
+		v      *float64
+	}{
+		{"calories", &food.Per100g.Calories},
+		{"protein", &food.Per100g.Protein},
+		{"fat", &food.Per100g.Fat},
+		{"carbohydrate", &food.Per100g.Carbohydrate},
+		{"fiber", &food.Per100g.Fiber},
+		{"sodium", &food.Per100g.Sodium},
+		{"portion", &food.PortionGrams},
+	} {
+		if *f.v, err = number(f.column); err != nil {
+			return food, err
+		}
+	}
+	return food, nil
+}
@@ -0,0 +1,118 @@
+// Package nutrition computes the nutrition facts of recipes from their
+// ingredients, with a local database of the nutrients of foods.
+package nutrition
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/units"
+)
+
+// Nutrients are the energy and nutrients of an amount of food
+type Nutrients struct {
+	// Calories are in kcal
+	Calories float64 `json:"calories"`
+	// Protein, Fat, Carbohydrate and Fiber are in grams
+	Protein      float64 `json:"protein"`
+	Fat          float64 `json:"fat"`
+	Carbohydrate float64 `json:"carbohydrate"`
+	Fiber        float64 `json:"fiber"`
+	// Sodium is in milligrams
+	Sodium float64 `json:"sodium"`
+}
+
+// Add returns n+m
+func (n Nutrients) Add(m Nutrients) Nutrients {
+	return Nutrients{
+		Calories:     n.Calories + m.Calories,
+		Protein:      n.Protein + m.Protein,
+		Fat:          n.Fat + m.Fat,
+		Carbohydrate: n.Carbohydrate + m.Carbohydrate,
+		Fiber:        n.Fiber + m.Fiber,
+		Sodium:       n.Sodium + m.Sodium,
+	}
+}
+
+// Scale returns n multiplied by f
+func (n Nutrients) Scale(f float64) Nutrients {
+	return Nutrients{
+		Calories:     n.Calories * f,
+		Protein:      n.Protein * f,
+		Fat:          n.Fat * f,
+		Carbohydrate: n.Carbohydrate * f,
+		Fiber:        n.Fiber * f,
+		Sodium:       n.Sodium * f,
+	}
+}
+
+// Item is an ingredient to compute the nutrients of
+type Item struct {
+	Name string
+	// Amount is nil when the recipe doesn't say how much, as in "salt to
+	// taste"
+	Amount *big.Rat
+	Unit   string
+}
+
+// countUnits are units counting pieces of a food, which weigh the portion
+// of the food each
+var countUnits = map[string]bool{
+	"": true, "piece": true, "pieces": true, "whole": true, "large": true, "medium": true,
+	"clove": true, "cloves": true, "個": true, "本": true, "枚": true, "片": true, "かけ": true,
+}
+
+// Compute adds up the nutrients of items. Items which aren't in the
+// database, or whose amount can't be weighed, are left out and returned.
+func (db *DB) Compute(items []Item) (Nutrients, []string) {
+	var total Nutrients
+	var unmatched []string
+	for _, item := range items {
+		food, ok := db.Match(item.Name)
+		if !ok || item.Amount == nil {
+			unmatched = append(unmatched, item.Name)
+			continue
+		}
+		g, ok := grams(food, item)
+		if !ok {
+			unmatched = append(unmatched, item.Name)
+			continue
+		}
+		total = total.Add(food.Per100g.Scale(g / 100))
+	}
+	return total, unmatched
+}
+
+// grams weighs the amount of item. Volumes are weighed with the density of
+// the ingredient, or that of water when it isn't known.
+func grams(food *Food, item Item) (float64, bool) {
+	unit, ok := units.Lookup(item.Unit)
+	if !ok {
+		if !countUnits[strings.ToLower(strings.TrimSpace(item.Unit))] || food.PortionGrams == 0 {
+			return 0, false
+		}
+		n, _ := item.Amount.Float64()
+		return n * food.PortionGrams, true
+	}
+
+	switch unit.Dimension {
+	case units.Mass:
+		g, err := units.Convert(item.Amount, unit, units.Gram)
+		if err != nil {
+			return 0, false
+		}
+		f, _ := g.Float64()
+		return f, true
+	case units.Volume:
+		ml, err := units.Convert(item.Amount, unit, units.Millilitre)
+		if err != nil {
+			return 0, false
+		}
+		if d, ok := units.DensityOf(item.Name); ok {
+			ml.Mul(ml, d.GramsPerMillilitre)
+		}
+		f, _ := ml.Float64()
+		return f, true
+	}
+	return 0, false
+}
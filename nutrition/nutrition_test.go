@@ -0,0 +1,148 @@
+package nutrition
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	type (
+		in struct {
+			name string
+		}
+		out struct {
+			food string
+			ok   bool
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"flour"}, out{"all-purpose flour", true}},
+		"case-02": {in{"All-Purpose Flour"}, out{"all-purpose flour", true}},
+		"case-03": {in{"eggs"}, out{"egg", true}},
+		"case-04": {in{"chicken thighs"}, out{"chicken thigh", true}},
+		"case-05": {in{"unsalted butter"}, out{"butter", true}},
+		"case-06": {in{"鶏もも肉"}, out{"chicken thigh", true}},
+		"case-07": {in{"タマネギ"}, out{"onion", true}},
+		"case-08": {in{"saffron"}, out{"", false}},
+		"case-09": {in{""}, out{"", false}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			food, ok := Staples.Match(in.name)
+			if ok != out.ok {
+				t.Fatalf("actual ok %v, expected ok %v", ok, out.ok)
+			}
+			if ok && food.Names[0] != out.food {
+				t.Errorf("actual food %s, expected food %s", food.Names[0], out.food)
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	db := NewDB("test",
+		Food{[]string{"flour"}, Nutrients{Calories: 364, Protein: 10}, 0},
+		Food{[]string{"sugar"}, Nutrients{Calories: 400}, 0},
+		Food{[]string{"egg"}, Nutrients{Calories: 140, Sodium: 140}, 50},
+		Food{[]string{"stock"}, Nutrients{Calories: 10}, 0},
+	)
+
+	type (
+		in struct {
+			items []Item
+		}
+		out struct {
+			calories  float64
+			unmatched []string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{[]Item{{"flour", big.NewRat(200, 1), "g"}}}, out{728, nil}},
+		"case-02": {in{[]Item{{"flour", big.NewRat(1, 2), "kg"}, {"sugar", big.NewRat(50, 1), "g"}}}, out{2020, nil}},
+		// a cup of flour weighs 125 g
+		"case-03": {in{[]Item{{"flour", big.NewRat(1, 1), "cup"}}}, out{455, nil}},
+		// stock weighs as water
+		"case-04": {in{[]Item{{"stock", big.NewRat(200, 1), "ml"}}}, out{20, nil}},
+		"case-05": {in{[]Item{{"eggs", big.NewRat(2, 1), ""}}}, out{140, nil}},
+		"case-06": {in{[]Item{{"sugar", nil, ""}, {"saffron", big.NewRat(1, 1), "g"}}}, out{0, []string{"sugar", "saffron"}}},
+		"case-07": {in{[]Item{{"flour", big.NewRat(2, 1), "cm"}}}, out{0, []string{"flour"}}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			total, unmatched := db.Compute(in.items)
+			if math.Abs(total.Calories-out.calories) > 0.5 {
+				t.Errorf("actual calories %v, expected calories %v", total.Calories, out.calories)
+			}
+			if !reflect.DeepEqual(unmatched, out.unmatched) {
+				t.Errorf("actual unmatched %v, expected unmatched %v", unmatched, out.unmatched)
+			}
+		})
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	type (
+		in struct {
+			csv string
+		}
+		out struct {
+			foods []Food
+			isErr bool
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {
+			in{`Description,Energy (kcal),Protein (g),Total lipid (fat) (g),"Carbohydrate, by difference (g)","Fiber, total dietary (g)","Sodium, Na (mg)",aliases,portion_g` + "\n" +
+				"\"Eggs, whole, raw\",143,12.6,9.5,0.7,0,142,卵|たまご,50\n"},
+			out{[]Food{{[]string{"Eggs, whole, raw", "卵", "たまご"}, Nutrients{143, 12.6, 9.5, 0.7, 0, 142}, 50}}, false},
+		},
+		"case-02": {
+			in{"name,calories\nsugar,387\nwater,\n"},
+			out{[]Food{{[]string{"sugar"}, Nutrients{Calories: 387}, 0}, {[]string{"water"}, Nutrients{}, 0}}, false},
+		},
+		"case-03": {in{"calories,protein_g\n100,1\n"}, out{nil, true}},
+		"case-04": {in{"name,calories\nsugar,lots\n"}, out{nil, true}},
+		"case-05": {in{"name,calories\n,100\n"}, out{nil, true}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			db, err := ImportCSV(strings.NewReader(in.csv))
+			if (err != nil) != out.isErr {
+				t.Fatalf("actual error %v, expected error %v", err, out.isErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(db.foods, out.foods) {
+				t.Errorf("actual foods %v, expected foods %v", db.foods, out.foods)
+			}
+			if db.Source == "" {
+				t.Error("source is empty")
+			}
+		})
+	}
+}
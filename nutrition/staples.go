@@ -0,0 +1,41 @@
+package nutrition
+
+// Staples is a database of common ingredients, used until a full database
+// is imported. Values are per 100 g, after USDA SR Legacy and the Standard
+// Tables of Food Composition in Japan.
+var Staples = NewDB("staples-1",
+	Food{[]string{"all-purpose flour", "flour", "薄力粉", "小麦粉"}, Nutrients{364, 10.3, 1, 76.3, 2.7, 2}, 0},
+	Food{[]string{"bread flour", "強力粉"}, Nutrients{361, 12, 1.7, 72.5, 2.4, 2}, 0},
+	Food{[]string{"sugar", "砂糖", "グラニュー糖"}, Nutrients{387, 0, 0, 100, 0, 1}, 0},
+	Food{[]string{"brown sugar", "三温糖"}, Nutrients{380, 0.1, 0, 98.1, 0, 28}, 0},
+	Food{[]string{"honey", "はちみつ"}, Nutrients{304, 0.3, 0, 82.4, 0.2, 4}, 0},
+	Food{[]string{"butter", "バター"}, Nutrients{717, 0.9, 81.1, 0.1, 0, 11}, 0},
+	Food{[]string{"egg", "卵", "たまご"}, Nutrients{143, 12.6, 9.5, 0.7, 0, 142}, 50},
+	Food{[]string{"milk", "牛乳"}, Nutrients{61, 3.2, 3.3, 4.8, 0, 43}, 0},
+	Food{[]string{"heavy cream", "生クリーム"}, Nutrients{340, 2.8, 36.1, 2.7, 0, 27}, 0},
+	Food{[]string{"cheddar cheese", "cheese", "チーズ"}, Nutrients{403, 24.9, 33.1, 1.3, 0, 621}, 0},
+	Food{[]string{"water", "水"}, Nutrients{}, 0},
+	Food{[]string{"salt", "塩"}, Nutrients{0, 0, 0, 0, 0, 38758}, 0},
+	Food{[]string{"black pepper", "pepper", "こしょう"}, Nutrients{251, 10.4, 3.3, 64, 25.3, 20}, 0},
+	Food{[]string{"soy sauce", "醤油", "しょうゆ"}, Nutrients{53, 8.1, 0.6, 4.9, 0.8, 5493}, 0},
+	Food{[]string{"miso", "味噌", "みそ"}, Nutrients{199, 11.7, 6, 26.5, 5.4, 3728}, 0},
+	Food{[]string{"mirin", "みりん"}, Nutrients{241, 0.3, 0, 43.2, 0, 3}, 0},
+	Food{[]string{"sake", "酒", "料理酒"}, Nutrients{109, 0.4, 0, 4.9, 0, 2}, 0},
+	Food{[]string{"olive oil", "オリーブオイル"}, Nutrients{884, 0, 100, 0, 0, 2}, 0},
+	Food{[]string{"vegetable oil", "oil", "サラダ油", "油"}, Nutrients{884, 0, 100, 0, 0, 0}, 0},
+	Food{[]string{"white rice", "rice", "米"}, Nutrients{365, 7.1, 0.7, 80, 1.3, 5}, 0},
+	Food{[]string{"rolled oats", "oats"}, Nutrients{389, 16.9, 6.9, 66.3, 10.6, 2}, 0},
+	Food{[]string{"cocoa powder", "ココア"}, Nutrients{228, 19.6, 13.7, 57.9, 37, 21}, 0},
+	Food{[]string{"baking powder", "ベーキングパウダー"}, Nutrients{53, 0, 0, 27.7, 0.2, 10600}, 0},
+	Food{[]string{"chicken thigh", "鶏もも肉"}, Nutrients{121, 19.7, 4.1, 0, 0, 95}, 0},
+	Food{[]string{"chicken breast", "鶏むね肉"}, Nutrients{120, 22.5, 2.6, 0, 0, 45}, 0},
+	Food{[]string{"ground beef", "牛ひき肉"}, Nutrients{254, 17.2, 20, 0, 0, 66}, 0},
+	Food{[]string{"pork belly", "豚バラ肉"}, Nutrients{518, 9.3, 53, 0, 0, 32}, 0},
+	Food{[]string{"tofu", "豆腐"}, Nutrients{76, 8.1, 4.8, 1.9, 0.3, 7}, 0},
+	Food{[]string{"onion", "玉ねぎ", "たまねぎ"}, Nutrients{40, 1.1, 0.1, 9.3, 1.7, 4}, 110},
+	Food{[]string{"garlic", "にんにく"}, Nutrients{149, 6.4, 0.5, 33.1, 2.1, 17}, 3},
+	Food{[]string{"potato", "じゃがいも"}, Nutrients{77, 2, 0.1, 17.5, 2.2, 6}, 170},
+	Food{[]string{"carrot", "にんじん"}, Nutrients{41, 0.9, 0.2, 9.6, 2.8, 69}, 61},
+	Food{[]string{"tomato", "トマト"}, Nutrients{18, 0.9, 0.2, 3.9, 1.2, 5}, 123},
+	Food{[]string{"lemon juice"}, Nutrients{22, 0.4, 0.2, 6.9, 0.3, 1}, 0},
+)
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/units"
+)
+
+// numberWords are the numbers written out, "a" and "an" only count before a
+// unit, as in "a pinch"
+var numberWords = map[string]string{
+	"one": "1", "two": "2", "three": "3", "four": "4", "five": "5", "six": "6",
+	"seven": "7", "eight": "8", "nine": "9", "ten": "10", "eleven": "11", "twelve": "12",
+	"dozen": "12", "half": "1/2",
+}
+
+// countUnits are units which aren't measures, written as they are
+var countUnits = map[string]bool{
+	"pinch": true, "pinches": true, "dash": true, "dashes": true, "drop": true, "drops": true,
+	"clove": true, "cloves": true, "slice": true, "slices": true, "piece": true, "pieces": true,
+	"stick": true, "sticks": true, "sprig": true, "sprigs": true, "stalk": true, "stalks": true,
+	"head": true, "heads": true, "bunch": true, "bunches": true, "handful": true, "handfuls": true,
+	"can": true, "cans": true, "jar": true, "jars": true, "package": true, "packages": true,
+	"pkg": true, "bag": true, "bags": true, "box": true, "boxes": true, "sheet": true, "sheets": true,
+}
+
+// englishUnits are abbreviations which mean another unit in ingredient
+// lines than in units, where C is Celsius: a "1 c flour" is a cup
+var englishUnits = map[string]string{
+	"c": units.Cup.Name,
+}
+
+var (
+	numberToken   = regexp.MustCompile(`^\d+(?:\.\d+)?(?:/\d+)?$`)
+	rangeToken    = regexp.MustCompile(`^(\d+(?:\.\d+)?(?:/\d+)?)[-–](\d+(?:\.\d+)?(?:/\d+)?)$`)
+	attachedToken = regexp.MustCompile(`^(\d+(?:\.\d+)?(?:/\d+)?)([a-zA-Z]+\.?)$`)
+)
+
+// parseEnglish parses lines like "2 1/2 cups all-purpose flour": a
+// quantity, which may be a mixed number, a range or a word, then an
+// optional unit and "of", then the name
+func parseEnglish(text string) parsed {
+	var p parsed
+	tokens := strings.Fields(text)
+	if len(tokens) > 0 {
+		if m := attachedToken.FindStringSubmatch(tokens[0]); m != nil && isUnit(m[2]) {
+			tokens = append([]string{m[1], m[2]}, tokens[1:]...)
+		}
+	}
+
+	i := 0
+	if i < len(tokens) {
+		if m := rangeToken.FindStringSubmatch(tokens[i]); m != nil {
+			p.quantity, _ = resource.ParseQuantity(m[1])
+			p.ranged = !p.quantity.IsZero()
+			p.notes = append(p.notes, tokens[i])
+			i++
+		} else if q, ok := englishNumber(tokens, i); ok {
+			p.quantity = q
+			i++
+			if i < len(tokens) && strings.Contains(tokens[i], "/") && numberToken.MatchString(tokens[i]) && q.Den == 1 {
+				if frac, err := resource.ParseQuantity(tokens[i]); err == nil {
+					if sum, err := q.Add(frac); err == nil {
+						p.quantity = sum
+						i++
+					}
+				}
+			}
+			if i+1 < len(tokens) && (tokens[i] == "-" || tokens[i] == "–" || tokens[i] == "to" || tokens[i] == "or") {
+				if _, ok := englishNumber(tokens, i+1); ok {
+					p.ranged = true
+					p.notes = append(p.notes, strings.Join(tokens[:i+2], " "))
+					i += 2
+				}
+			}
+		}
+	}
+
+	if i+1 < len(tokens) {
+		if u, ok := units.Lookup(tokens[i] + " " + tokens[i+1]); ok {
+			p.unit = u.Name
+			i += 2
+		}
+	}
+	if p.unit == "" && i < len(tokens) && (!p.quantity.IsZero() || i > 0) && isUnit(tokens[i]) {
+		p.unit = englishUnit(tokens[i])
+		i++
+	}
+	if p.unit != "" && i < len(tokens) && tokens[i] == "of" {
+		i++
+	}
+
+	p.name = strings.Join(tokens[i:], " ")
+	return p
+}
+
+// englishNumber reads the number at tokens[i], "a" and "an" only when a unit
+// follows
+func englishNumber(tokens []string, i int) (resource.Quantity, bool) {
+	token := strings.ToLower(tokens[i])
+	if token == "a" || token == "an" {
+		if i+1 < len(tokens) && isUnit(tokens[i+1]) {
+			return resource.NewQuantity(1, 1), true
+		}
+		return resource.Quantity{}, false
+	}
+	if word, ok := numberWords[token]; ok {
+		token = word
+	}
+	if !numberToken.MatchString(token) {
+		return resource.Quantity{}, false
+	}
+	q, err := resource.ParseQuantity(token)
+	return q, err == nil && !q.IsZero()
+}
+
+func isUnit(s string) bool {
+	s = strings.ToLower(strings.TrimSuffix(s, "."))
+	if _, ok := englishUnits[s]; ok {
+		return true
+	}
+	if _, ok := units.Lookup(s); ok {
+		return true
+	}
+	return countUnits[s]
+}
+
+func englishUnit(s string) string {
+	s = strings.ToLower(strings.TrimSuffix(s, "."))
+	if name, ok := englishUnits[s]; ok {
+		return name
+	}
+	return unitName(s)
+}
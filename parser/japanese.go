@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/resource"
+)
+
+const (
+	jpNumber = `\d+(?:\.\d+)?(?:/\d+)?(?:と\d+/\d+)?`
+	jpAmount = jpNumber + `(?:\s*[~〜\-]\s*` + jpNumber + `)?`
+	// jpSpoons are the measures written before the amount, as in 大さじ2
+	jpSpoons = `大さじ|小さじ|大匙|小匙|カップ`
+	// jpUnits are the units written after the amount, as in 2個
+	jpUnits = `kg|g|ml|cc|l|カップ|個|本|枚|片|かけ|束|袋|缶|株|合|切れ|房|玉|尾|杯|パック|つまみ`
+)
+
+var (
+	jpSpoonFirst  = regexp.MustCompile(`(` + jpSpoons + `)\s*(` + jpAmount + `)?`)
+	jpAmountFirst = regexp.MustCompile(`(` + jpAmount + `)\s*(` + jpUnits + `)?`)
+	jpRangeSep    = regexp.MustCompile(`\s*[~〜\-]\s*`)
+)
+
+// parseJapanese parses lines like "醤油 大さじ2" or "卵 2個", where the
+// amount comes before or after the name, with or without a space
+func parseJapanese(text string) parsed {
+	var p parsed
+	text = strings.Replace(text, "ひとつまみ", "1つまみ", 1)
+
+	var loc []int
+	var amount string
+	if m := jpAmountFirst.FindAllStringSubmatchIndex(text, -1); m != nil {
+		for _, sub := range m {
+			if sub[4] >= 0 {
+				loc = sub
+				amount, p.unit = text[sub[2]:sub[3]], text[sub[4]:sub[5]]
+				break
+			}
+		}
+	}
+	if loc == nil {
+		if sub := jpSpoonFirst.FindStringSubmatchIndex(text); sub != nil {
+			loc = sub
+			p.unit = text[sub[2]:sub[3]]
+			if sub[4] >= 0 {
+				amount = text[sub[4]:sub[5]]
+			}
+		}
+	}
+	if loc == nil {
+		if sub := jpAmountFirst.FindStringSubmatchIndex(text); sub != nil {
+			loc = sub
+			amount = text[sub[2]:sub[3]]
+		}
+	}
+
+	if loc != nil {
+		text = text[:loc[0]] + " " + text[loc[1]:]
+		p.unit = unitName(p.unit)
+		if parts := jpRangeSep.Split(amount, 2); len(parts) == 2 {
+			p.ranged = true
+			p.notes = append(p.notes, amount)
+			amount = parts[0]
+		}
+		p.quantity, _ = resource.ParseQuantity(strings.Replace(amount, "と", " ", 1))
+	}
+
+	p.name = strings.Trim(strings.Join(strings.Fields(text), " "), " ・…:：.")
+	return p
+}
@@ -0,0 +1,212 @@
+// Package parser turns ingredient lists written as free text, in English or
+// Japanese, into structured ingredients.
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/units"
+)
+
+// LowConfidence is the confidence below which a parsed line is worth a
+// second look by the author
+const LowConfidence = 0.6
+
+// Parsed is an ingredient parsed from a line of text
+type Parsed struct {
+	Line       string              `json:"line"`
+	Ingredient resource.Ingredient `json:"ingredient"`
+	// Confidence is from 0 to 1 how likely the line was understood right
+	Confidence float64 `json:"confidence"`
+}
+
+// ParseList parses the lines of text. Blank lines are skipped, and header
+// lines like "For the sauce:" or 【タレ】 set the group of the ingredients
+// which follow them.
+func ParseList(text string) []Parsed {
+	var parsed []Parsed
+	var group string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if header, ok := parseHeader(line); ok {
+			group = header
+			continue
+		}
+		p := Parse(line)
+		p.Ingredient.Group = group
+		parsed = append(parsed, p)
+	}
+	return parsed
+}
+
+var headerPattern = regexp.MustCompile(`^(?:[【<\[■◆●]\s*([^】>\]\d]+?)\s*[】>\]]?|([^\d:]+):)$`)
+
+func parseHeader(line string) (string, bool) {
+	m := headerPattern.FindStringSubmatch(normalize(line))
+	if m == nil {
+		return "", false
+	}
+	return strings.TrimSpace(m[1] + m[2]), true
+}
+
+// Parse parses one line into the quantity, unit, name and note of an
+// ingredient
+func Parse(line string) Parsed {
+	text, notes := splitNotes(normalize(line))
+
+	var p parsed
+	if isJapanese(text) {
+		p = parseJapanese(text)
+	} else {
+		p = parseEnglish(text)
+	}
+	p.notes = append(p.notes, notes...)
+	p.name, p.notes, p.marked = cutMarker(p.name, p.notes)
+
+	return Parsed{
+		Line: line,
+		Ingredient: resource.Ingredient{
+			Name:     p.name,
+			Quantity: p.quantity,
+			Unit:     p.unit,
+			Note:     strings.Join(p.notes, ", "),
+		},
+		Confidence: p.confidence(),
+	}
+}
+
+// parsed is a line taken apart
+type parsed struct {
+	quantity resource.Quantity
+	unit     string
+	name     string
+	notes    []string
+	// ranged is set when the amount was a range, like 2-3, of which the
+	// lower bound is kept
+	ranged bool
+	// marked is set when the line says the amount is up to the cook, like
+	// "to taste"
+	marked bool
+}
+
+func (p *parsed) confidence() float64 {
+	if p.name == "" {
+		return 0
+	}
+	c := 1.0
+	if p.quantity.IsZero() && !p.marked {
+		c -= 0.5
+	}
+	if p.ranged {
+		c -= 0.1
+	}
+	if strings.IndexFunc(p.name, unicode.IsDigit) >= 0 {
+		// numbers left in the name were not understood
+		c -= 0.3
+	}
+	if words := len(strings.Fields(p.name)); words > 5 {
+		c -= 0.1 * float64(words-5)
+	}
+	if c < 0 {
+		return 0
+	}
+	return c
+}
+
+// vulgarFractions are the fraction characters cooks write
+var vulgarFractions = map[rune]string{
+	'½': "1/2", '⅓': "1/3", '⅔': "2/3", '¼': "1/4", '¾': "3/4", '⅕': "1/5", '⅖': "2/5",
+	'⅗': "3/5", '⅘': "4/5", '⅙': "1/6", '⅚': "5/6", '⅛': "1/8", '⅜': "3/8", '⅝': "5/8", '⅞': "7/8",
+}
+
+// normalize writes full-width ASCII at its usual width and fraction
+// characters as n/d, and drops list bullets
+func normalize(line string) string {
+	var b strings.Builder
+	for _, r := range line {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E:
+			b.WriteRune(r - 0xFEE0)
+		case r == 0x3000:
+			b.WriteRune(' ')
+		case r == '⁄':
+			b.WriteRune('/')
+		case vulgarFractions[r] != "":
+			b.WriteString(" " + vulgarFractions[r])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimLeft(strings.Join(strings.Fields(b.String()), " "), "-*•・ ")
+}
+
+var parenPattern = regexp.MustCompile(`\(([^()]*)\)`)
+
+// splitNotes takes the parenthesized parts and what follows the first comma
+// off text as notes
+func splitNotes(text string) (string, []string) {
+	var notes []string
+	text = parenPattern.ReplaceAllStringFunc(text, func(s string) string {
+		if note := strings.TrimSpace(s[1 : len(s)-1]); note != "" {
+			notes = append(notes, note)
+		}
+		return " "
+	})
+	if i := strings.Index(text, ","); i >= 0 {
+		if note := strings.TrimSpace(text[i+1:]); note != "" {
+			notes = append(notes, note)
+		}
+		text = text[:i]
+	}
+	return strings.Join(strings.Fields(text), " "), notes
+}
+
+// markers say the amount is up to the cook
+var markers = []string{
+	"to taste", "as needed", "as required", "for garnish", "for serving", "optional",
+	"少々", "適量", "適宜", "お好みで", "少量",
+}
+
+// cutMarker moves a marker in name to the notes, and reports whether the
+// name or the notes have one
+func cutMarker(name string, notes []string) (string, []string, bool) {
+	lower := strings.ToLower(name)
+	for _, marker := range markers {
+		if i := strings.Index(lower, marker); i >= 0 {
+			name = strings.Join(strings.Fields(name[:i]+" "+name[i+len(marker):]), " ")
+			return strings.Trim(name, " ,"), append([]string{marker}, notes...), true
+		}
+	}
+	for _, note := range notes {
+		for _, marker := range markers {
+			if strings.Contains(strings.ToLower(note), marker) {
+				return name, notes, true
+			}
+		}
+	}
+	return name, notes, false
+}
+
+func isJapanese(text string) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+			return true
+		}
+	}
+	return false
+}
+
+// unitName returns the name units are stored by, the canonical one for units
+// the units package knows
+func unitName(s string) string {
+	if u, ok := units.Lookup(s); ok {
+		return u.Name
+	}
+	return s
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/resource"
+)
+
+func TestParse(t *testing.T) {
+	type (
+		in struct {
+			line string
+		}
+		out struct {
+			ingredient resource.Ingredient
+			confident  bool
+		}
+	)
+
+	q := resource.NewQuantity
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"2 1/2 cups all-purpose flour, sifted"}, out{resource.Ingredient{Name: "all-purpose flour", Quantity: q(5, 2), Unit: "cup", Note: "sifted"}, true}},
+		"case-02": {in{"1½ tsp. salt"}, out{resource.Ingredient{Name: "salt", Quantity: q(3, 2), Unit: "tsp"}, true}},
+		"case-03": {in{"3 eggs"}, out{resource.Ingredient{Name: "eggs", Quantity: q(3, 1)}, true}},
+		"case-04": {in{"1 (14 oz) can diced tomatoes"}, out{resource.Ingredient{Name: "diced tomatoes", Quantity: q(1, 1), Unit: "can", Note: "14 oz"}, true}},
+		"case-05": {in{"2-3 cloves garlic, minced"}, out{resource.Ingredient{Name: "garlic", Quantity: q(2, 1), Unit: "cloves", Note: "2-3, minced"}, true}},
+		"case-06": {in{"salt to taste"}, out{resource.Ingredient{Name: "salt", Note: "to taste"}, true}},
+		"case-07": {in{"a pinch of nutmeg"}, out{resource.Ingredient{Name: "nutmeg", Quantity: q(1, 1), Unit: "pinch"}, true}},
+		"case-08": {in{"200g butter"}, out{resource.Ingredient{Name: "butter", Quantity: q(200, 1), Unit: "g"}, true}},
+		"case-09": {in{"2 fl oz milk"}, out{resource.Ingredient{Name: "milk", Quantity: q(2, 1), Unit: "fl oz"}, true}},
+		"case-10": {in{"- one large onion"}, out{resource.Ingredient{Name: "large onion", Quantity: q(1, 1)}, true}},
+		"case-11": {in{"大さじ2 醤油"}, out{resource.Ingredient{Name: "醤油", Quantity: q(2, 1), Unit: "大さじ"}, true}},
+		"case-12": {in{"砂糖　小さじ１/２"}, out{resource.Ingredient{Name: "砂糖", Quantity: q(1, 2), Unit: "小さじ"}, true}},
+		"case-13": {in{"卵 2個"}, out{resource.Ingredient{Name: "卵", Quantity: q(2, 1), Unit: "個"}, true}},
+		"case-14": {in{"玉ねぎ（みじん切り）1/2個"}, out{resource.Ingredient{Name: "玉ねぎ", Quantity: q(1, 2), Unit: "個", Note: "みじん切り"}, true}},
+		"case-15": {in{"鶏もも肉300g"}, out{resource.Ingredient{Name: "鶏もも肉", Quantity: q(300, 1), Unit: "g"}, true}},
+		"case-16": {in{"塩 少々"}, out{resource.Ingredient{Name: "塩", Note: "少々"}, true}},
+		"case-17": {in{"牛乳 1/2カップ"}, out{resource.Ingredient{Name: "牛乳", Quantity: q(1, 2), Unit: "カップ"}, true}},
+		"case-18": {in{"みりん 大さじ1と1/2"}, out{resource.Ingredient{Name: "みりん", Quantity: q(3, 2), Unit: "大さじ"}, true}},
+		"case-19": {in{"じゃがいも 2〜3個"}, out{resource.Ingredient{Name: "じゃがいも", Quantity: q(2, 1), Unit: "個", Note: "2〜3"}, true}},
+		"case-20": {in{"some leftover vegetables"}, out{resource.Ingredient{Name: "some leftover vegetables"}, false}},
+		"case-21": {in{"2 cups"}, out{resource.Ingredient{Quantity: q(2, 1), Unit: "cup"}, false}},
+		"case-22": {in{"1 c flour"}, out{resource.Ingredient{Name: "flour", Quantity: q(1, 1), Unit: "cup"}, true}},
+		"case-23": {in{"1/2 C. sugar"}, out{resource.Ingredient{Name: "sugar", Quantity: q(1, 2), Unit: "cup"}, true}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			p := Parse(in.line)
+			if !reflect.DeepEqual(p.Ingredient, out.ingredient) {
+				t.Errorf("actual ingredient %+v, expected ingredient %+v", p.Ingredient, out.ingredient)
+			}
+			if confident := p.Confidence >= LowConfidence; confident != out.confident {
+				t.Errorf("actual confidence %v, expected confident %v", p.Confidence, out.confident)
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	text := "200g flour\n\nFor the glaze:\n1 cup powdered sugar\n【タレ】\n醤油 大さじ2\n"
+
+	var actual []resource.Ingredient
+	for _, p := range ParseList(text) {
+		actual = append(actual, p.Ingredient)
+	}
+	expected := []resource.Ingredient{
+		{Name: "flour", Quantity: resource.NewQuantity(200, 1), Unit: "g"},
+		{Name: "powdered sugar", Quantity: resource.NewQuantity(1, 1), Unit: "cup", Group: "For the glaze"},
+		{Name: "醤油", Quantity: resource.NewQuantity(2, 1), Unit: "大さじ", Group: "タレ"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("actual ingredients %+v, expected ingredients %+v", actual, expected)
+	}
+}
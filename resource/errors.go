@@ -0,0 +1,83 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	tarantool "github.com/tarantool/go-tarantool"
+)
+
+// Kinds of errors reported by resources. Check for them with errors.Is, the
+// returned errors are *Error values carrying the details.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrInvalid     = errors.New("invalid")
+	ErrConflict    = errors.New("conflict")
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// Error describes a failed operation on a resource
+type Error struct {
+	// Kind is one of ErrNotFound, ErrInvalid, ErrConflict or ErrUnavailable
+	Kind error
+	// Field names the offending field of an ErrInvalid error
+	Field  string
+	Detail string
+	// Err is the underlying error, if any
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Detail)
+	}
+	return e.Detail
+}
+
+// Is reports whether target is the kind of e
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFound returns an ErrNotFound error
+func NotFound(format string, args ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Invalid returns an ErrInvalid error about field
+func Invalid(field, detail string) error {
+	return &Error{Kind: ErrInvalid, Field: field, Detail: detail}
+}
+
+// Conflict returns an ErrConflict error
+func Conflict(format string, args ...interface{}) error {
+	return &Error{Kind: ErrConflict, Detail: fmt.Sprintf(format, args...)}
+}
+
+// wrapErr classifies an error returned by the tarantool client. Errors which
+// don't belong to any kind are returned as they are.
+func wrapErr(err error) error {
+	switch e := err.(type) {
+	case tarantool.Error:
+		switch e.Code {
+		case tarantool.ErrTupleFound, tarantool.ErrTransactionConflict:
+			return &Error{Kind: ErrConflict, Detail: e.Msg, Err: err}
+		case tarantool.ErrTupleNotFound:
+			return &Error{Kind: ErrNotFound, Detail: e.Msg, Err: err}
+		case tarantool.ErrNonmaster, tarantool.ErrReadonly, tarantool.ErrNoConnection,
+			tarantool.ErrTimeout, tarantool.ErrLocalServerIsNotActive:
+			return &Error{Kind: ErrUnavailable, Detail: e.Msg, Err: err}
+		}
+	case tarantool.ClientError:
+		return &Error{Kind: ErrUnavailable, Detail: e.Msg, Err: err}
+	case net.Error:
+		return &Error{Kind: ErrUnavailable, Detail: "database is unreachable", Err: err}
+	}
+	return err
+}
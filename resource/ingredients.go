@@ -0,0 +1,293 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+// Ingredient is one line of the ingredient list of a recipe
+type Ingredient struct {
+	Name     string   `json:"name"`
+	Quantity Quantity `json:"quantity"`
+	Unit     string   `json:"unit,omitempty"`
+	// Note is the preparation of the ingredient, like "finely chopped"
+	Note string `json:"note,omitempty"`
+	// Group collects ingredients used together, like "for the sauce"
+	Group string `json:"group,omitempty"`
+}
+
+// Quantity is an exact rational amount like 1 1/2. The zero value means
+// the amount isn't specified, as in "salt to taste".
+type Quantity struct {
+	Num int64
+	Den int64
+}
+
+// MaxQuantityTerm bounds the numerator and the denominator of quantities,
+// far above any amount of a recipe, so that sums and products of two
+// quantities can't overflow
+const MaxQuantityTerm = 1000000000
+
+func init() {
+	msgpack.Register(reflect.TypeOf(Ingredient{}), encodeIngredient, decodeIngredient)
+}
+
+// NewQuantity returns num/den in lowest terms
+func NewQuantity(num, den int64) Quantity {
+	if den == 0 {
+		return Quantity{}
+	}
+	if den < 0 {
+		num, den = -num, -den
+	}
+	g := gcd(abs(num), den)
+	return Quantity{num / g, den / g}
+}
+
+// ParseQuantity parses integers, fractions, mixed numbers and decimals such
+// as "2", "1/3", "1 1/2" and "0.75". The sign of a mixed number goes before
+// its whole part, and applies to all of it.
+func ParseQuantity(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Quantity{}, nil
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 2 {
+		whole, err := strconv.ParseInt(strings.TrimPrefix(fields[0], "-"), 10, 64)
+		if err != nil || whole < 0 || strings.ContainsAny(fields[1], "+-") {
+			return Quantity{}, fmt.Errorf("invalid quantity %q", s)
+		}
+		frac, err := ParseQuantity(fields[1])
+		if err != nil || frac.Num >= frac.Den {
+			return Quantity{}, fmt.Errorf("invalid quantity %q", s)
+		}
+		q, err := NewQuantity(whole, 1).Add(frac)
+		if err != nil {
+			return Quantity{}, fmt.Errorf("invalid quantity %q: %s", s, err)
+		}
+		if strings.HasPrefix(fields[0], "-") {
+			q.Num = -q.Num
+		}
+		return q, nil
+	}
+	if len(fields) != 1 {
+		return Quantity{}, fmt.Errorf("invalid quantity %q", s)
+	}
+
+	var q Quantity
+	if i := strings.Index(s, "/"); i >= 0 {
+		num, err1 := strconv.ParseInt(s[:i], 10, 64)
+		den, err2 := strconv.ParseInt(s[i+1:], 10, 64)
+		if err1 != nil || err2 != nil || den == 0 {
+			return Quantity{}, fmt.Errorf("invalid quantity %q", s)
+		}
+		q = NewQuantity(num, den)
+	} else if i := strings.Index(s, "."); i >= 0 {
+		decimals := len(s) - i - 1
+		if decimals > 6 {
+			return Quantity{}, fmt.Errorf("invalid quantity %q", s)
+		}
+		num, err := strconv.ParseInt(s[:i]+s[i+1:], 10, 64)
+		if err != nil {
+			return Quantity{}, fmt.Errorf("invalid quantity %q", s)
+		}
+		q = NewQuantity(num, int64(math.Pow10(decimals)))
+	} else {
+		num, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return Quantity{}, fmt.Errorf("invalid quantity %q", s)
+		}
+		q = NewQuantity(num, 1)
+	}
+	if !q.InRange() {
+		return Quantity{}, fmt.Errorf("invalid quantity %q: too large", s)
+	}
+	return q, nil
+}
+
+// IsZero reports whether the amount is unspecified
+func (q Quantity) IsZero() bool {
+	return q.Den == 0
+}
+
+// Float64 returns the nearest float64 of the amount
+func (q Quantity) Float64() float64 {
+	if q.IsZero() {
+		return 0
+	}
+	return float64(q.Num) / float64(q.Den)
+}
+
+// InRange reports whether the numerator and the denominator of the amount
+// are within MaxQuantityTerm
+func (q Quantity) InRange() bool {
+	return q.Num >= -MaxQuantityTerm && q.Num <= MaxQuantityTerm && q.Den >= 0 && q.Den <= MaxQuantityTerm
+}
+
+// Rat returns the amount as a big.Rat, 0 when unspecified
+func (q Quantity) Rat() *big.Rat {
+	if q.IsZero() {
+		return new(big.Rat)
+	}
+	return big.NewRat(q.Num, q.Den)
+}
+
+// QuantityOf returns r as a Quantity, failing when it isn't InRange
+func QuantityOf(r *big.Rat) (Quantity, error) {
+	if !r.Num().IsInt64() || !r.Denom().IsInt64() {
+		return Quantity{}, Invalid("quantity", fmt.Sprintf("%s is too large", r.RatString()))
+	}
+	q := NewQuantity(r.Num().Int64(), r.Denom().Int64())
+	if !q.InRange() {
+		return Quantity{}, Invalid("quantity", fmt.Sprintf("%s is too large", r.RatString()))
+	}
+	return q, nil
+}
+
+// Add returns q+r, failing when it isn't InRange
+func (q Quantity) Add(r Quantity) (Quantity, error) {
+	if q.IsZero() {
+		return r, nil
+	}
+	if r.IsZero() {
+		return q, nil
+	}
+	return QuantityOf(new(big.Rat).Add(q.Rat(), r.Rat()))
+}
+
+// Mul returns q*r, failing when it isn't InRange
+func (q Quantity) Mul(r Quantity) (Quantity, error) {
+	if q.IsZero() || r.IsZero() {
+		return Quantity{}, nil
+	}
+	return QuantityOf(new(big.Rat).Mul(q.Rat(), r.Rat()))
+}
+
+// String formats the amount as a mixed number like "1 1/2"
+func (q Quantity) String() string {
+	if q.IsZero() {
+		return ""
+	}
+	whole, rest := q.Num/q.Den, abs(q.Num%q.Den)
+	switch {
+	case rest == 0:
+		return strconv.FormatInt(whole, 10)
+	case whole == 0 && q.Num < 0:
+		return fmt.Sprintf("-%d/%d", rest, q.Den)
+	case whole == 0:
+		return fmt.Sprintf("%d/%d", rest, q.Den)
+	}
+	return fmt.Sprintf("%d %d/%d", whole, rest, q.Den)
+}
+
+// MarshalJSON writes the amount as a string, or null when unspecified
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	if q.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(q.String())
+}
+
+// UnmarshalJSON reads the amount from a string accepted by ParseQuantity,
+// a number or null
+func (q *Quantity) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var err error
+	switch v := v.(type) {
+	case nil:
+		*q = Quantity{}
+	case string:
+		*q, err = ParseQuantity(v)
+	case float64:
+		*q, err = ParseQuantity(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		err = fmt.Errorf("invalid quantity %s", b)
+	}
+	return err
+}
+
+func encodeIngredient(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(Ingredient)
+	if err := e.EncodeSliceLen(6); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Name); err != nil {
+		return err
+	}
+	if err := e.EncodeInt64(m.Quantity.Num); err != nil {
+		return err
+	}
+	if err := e.EncodeInt64(m.Quantity.Den); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Unit); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Note); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Group); err != nil {
+		return err
+	}
+	return nil
+}
+
+func decodeIngredient(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*Ingredient)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != 6 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+	if m.Name, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Quantity.Num, err = d.DecodeInt64(); err != nil {
+		return err
+	}
+	if m.Quantity.Den, err = d.DecodeInt64(); err != nil {
+		return err
+	}
+	if m.Unit, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Note, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Group, err = d.DecodeString(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a == 0 {
+		return 1
+	}
+	return a
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,148 @@
+package resource
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+func TestParseQuantity(t *testing.T) {
+	type (
+		in struct {
+			s string
+		}
+		out struct {
+			quantity Quantity
+			str      string
+			isErr    bool
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"2"}, out{Quantity{2, 1}, "2", false}},
+		"case-02": {in{"2/6"}, out{Quantity{1, 3}, "1/3", false}},
+		"case-03": {in{"1 1/2"}, out{Quantity{3, 2}, "1 1/2", false}},
+		"case-04": {in{"0.75"}, out{Quantity{3, 4}, "3/4", false}},
+		"case-05": {in{""}, out{Quantity{}, "", false}},
+		"case-06": {in{"1/0"}, out{Quantity{}, "", true}},
+		"case-07": {in{"a pinch"}, out{Quantity{}, "", true}},
+		"case-08": {in{"1 3/2"}, out{Quantity{}, "", true}},
+		// the sign of a mixed number applies to all of it
+		"case-09": {in{"-1 1/2"}, out{Quantity{-3, 2}, "-1 1/2", false}},
+		"case-10": {in{"-0 1/2"}, out{Quantity{-1, 2}, "-1/2", false}},
+		"case-11": {in{"1 -1/2"}, out{Quantity{}, "", true}},
+		"case-12": {in{"1 1/-2"}, out{Quantity{}, "", true}},
+		"case-13": {in{"-1 -1/2"}, out{Quantity{}, "", true}},
+		"case-14": {in{"--1 1/2"}, out{Quantity{}, "", true}},
+		// terms beyond MaxQuantityTerm, whose sums would overflow
+		"case-15": {in{"9223372036854775807/2"}, out{Quantity{}, "", true}},
+		"case-16": {in{"1/9223372036854775807"}, out{Quantity{}, "", true}},
+		"case-17": {in{"-9223372036854775808"}, out{Quantity{}, "", true}},
+		"case-18": {in{"9223372036854775807 1/2"}, out{Quantity{}, "", true}},
+		"case-19": {in{"1000000000/999999999"}, out{Quantity{1000000000, 999999999}, "1 1/999999999", false}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			quantity, err := ParseQuantity(in.s)
+			if (err != nil) != out.isErr {
+				t.Fatalf("actual error %v, expected error %v", err, out.isErr)
+			}
+			if quantity != out.quantity {
+				t.Errorf("actual quantity %v, expected quantity %v", quantity, out.quantity)
+			}
+			if str := quantity.String(); str != out.str {
+				t.Errorf("actual string %s, expected string %s", str, out.str)
+			}
+		})
+	}
+}
+
+func TestQuantityArithmetic(t *testing.T) {
+	type (
+		in struct {
+			q, r Quantity
+		}
+		out struct {
+			// errors are expected when the results don't fit a Quantity
+			sum        Quantity
+			sumErr     bool
+			product    Quantity
+			productErr bool
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{NewQuantity(1, 2), NewQuantity(1, 3)}, out{Quantity{5, 6}, false, Quantity{1, 6}, false}},
+		"case-02": {in{NewQuantity(2, 3), Quantity{}}, out{Quantity{2, 3}, false, Quantity{}, false}},
+		// terms which would overflow int64 rather than wrap around
+		"case-03": {in{NewQuantity(999999999, 1000000000), NewQuantity(999999998, 999999999)}, out{Quantity{}, true, Quantity{499999999, 500000000}, false}},
+		"case-04": {in{NewQuantity(MaxQuantityTerm, 1), NewQuantity(MaxQuantityTerm, 1)}, out{Quantity{}, true, Quantity{}, true}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			sum, err := in.q.Add(in.r)
+			if (err != nil) != out.sumErr || sum != out.sum {
+				t.Errorf("actual sum %v, %v, expected sum %v", sum, err, out.sum)
+			}
+			product, err := in.q.Mul(in.r)
+			if (err != nil) != out.productErr || product != out.product {
+				t.Errorf("actual product %v, %v, expected product %v", product, err, out.product)
+			}
+		})
+	}
+}
+
+func TestIngredientJSON(t *testing.T) {
+	var ingredient Ingredient
+	err := json.Unmarshal([]byte(`{"name":"flour","quantity":1.5,"unit":"cup","note":"sifted"}`), &ingredient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Ingredient{Name: "flour", Quantity: Quantity{3, 2}, Unit: "cup", Note: "sifted"}
+	if ingredient != expected {
+		t.Errorf("actual ingredient %v, expected ingredient %v", ingredient, expected)
+	}
+
+	b, _ := json.Marshal(Ingredient{Name: "salt"})
+	if s := string(b); s != `{"name":"salt","quantity":null}` {
+		t.Errorf("actual json %s", s)
+	}
+}
+
+func TestRecipeMsgpackIngredients(t *testing.T) {
+	recipe := Recipe{
+		ID:    1,
+		Title: "Pancakes",
+		Ingredients: []Ingredient{
+			{Name: "flour", Quantity: Quantity{3, 2}, Unit: "cup"},
+			{Name: "maple syrup", Group: "for serving"},
+		},
+		Howto: []Step{{Text: "mix"}, {Text: "fry", Duration: 120, Ingredients: []int{0}}},
+	}
+
+	b, err := msgpack.Marshal(recipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Recipe
+	if err := msgpack.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, recipe) {
+		t.Errorf("actual recipe %v, expected recipe %v", decoded, recipe)
+	}
+}
@@ -0,0 +1,183 @@
+package resource
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/motomux/smart-cooking-server/store"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+	"gopkg.in/vmihailenco/msgpack.v2/codes"
+)
+
+// LabelAuditRscInterface is an interface to test LabelAuditRsc
+type LabelAuditRscInterface interface {
+	Insert(entry *LabelAudit) (*LabelAudit, error)
+	GetByRecipe(recipeID int) ([]LabelAudit, error)
+}
+
+// LabelAuditRsc provides api to manipulate the audit trail of label
+// overrides in the store
+type LabelAuditRsc struct {
+	db           store.Store
+	spaceName    string
+	sequenceName string
+}
+
+// LabelAudit records a change of the label overrides of a recipe
+type LabelAudit struct {
+	ID       uint   `json:"id"`
+	RecipeID uint   `json:"recipe_id"`
+	Label    string `json:"label"`
+	// Value is the override set, nil when the override was cleared
+	Value  *bool  `json:"value"`
+	Author string `json:"author"`
+	Reason string `json:"reason"`
+	// At is the unix time of the change
+	At int64 `json:"at"`
+}
+
+// NewLabelAuditRsc initiates LabelAuditRsc
+func NewLabelAuditRsc(db store.Store) *LabelAuditRsc {
+	return &LabelAuditRsc{
+		db:           db,
+		spaceName:    "recipe_label_audit",
+		sequenceName: "recipe_label_audit_id",
+	}
+}
+
+// Insert appends entry to the trail. The ID is allocated from the sequence.
+func (rsc *LabelAuditRsc) Insert(entry *LabelAudit) (*LabelAudit, error) {
+	ID, err := nextID(rsc.db, rsc.sequenceName)
+	if err != nil {
+		return nil, err
+	}
+
+	tuple := *entry
+	tuple.ID = ID
+	var entries []LabelAudit
+	err = rsc.db.Insert(rsc.spaceName, tuple, &entries)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("insert into %s returned no tuple", rsc.spaceName)
+	}
+
+	return &entries[0], nil
+}
+
+// GetByRecipe finds the trail of the recipe with recipeID, oldest first
+func (rsc *LabelAuditRsc) GetByRecipe(recipeID int) ([]LabelAudit, error) {
+	var trail []LabelAudit
+	for offset := uint32(0); ; offset += scanBatchSize {
+		var entries []LabelAudit
+		err := rsc.db.Select(rsc.spaceName, "recipe", offset, scanBatchSize, store.IterEq, []interface{}{recipeID}, &entries)
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		trail = append(trail, entries...)
+		if len(entries) < scanBatchSize {
+			return trail, nil
+		}
+	}
+}
+
+// CreateSpace creates the space of the trail, its sequence and its index by
+// recipe. It is safe to run more than once.
+func (rsc *LabelAuditRsc) CreateSpace() error {
+	return wrapErr(rsc.db.CreateSpace(rsc.space()))
+}
+
+// space defines the space of the trail
+func (rsc *LabelAuditRsc) space() *store.Space {
+	return &store.Space{
+		Name:     rsc.spaceName,
+		Sequence: rsc.sequenceName,
+		Indexes: []store.Index{
+			idIndex,
+			{Name: "recipe", Parts: []store.Part{{Field: 1, Type: "unsigned"}, {Field: 0, Type: "unsigned"}}},
+		},
+	}
+}
+
+func init() {
+	msgpack.Register(reflect.TypeOf(LabelAudit{}), encodeLabelAudit, decodeLabelAudit)
+}
+
+// labelAuditLen is the number of fields of a tuple
+// [id, recipe_id, label, value, author, reason, at]
+const labelAuditLen = 7
+
+func encodeLabelAudit(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(LabelAudit)
+	if err := e.EncodeSliceLen(labelAuditLen); err != nil {
+		return err
+	}
+	if err := e.EncodeUint(m.ID); err != nil {
+		return err
+	}
+	if err := e.EncodeUint(m.RecipeID); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Label); err != nil {
+		return err
+	}
+	if m.Value == nil {
+		if err := e.EncodeNil(); err != nil {
+			return err
+		}
+	} else if err := e.EncodeBool(*m.Value); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Author); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Reason); err != nil {
+		return err
+	}
+	return e.EncodeInt64(m.At)
+}
+
+func decodeLabelAudit(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*LabelAudit)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != labelAuditLen {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+
+	*m = LabelAudit{}
+	if m.ID, err = d.DecodeUint(); err != nil {
+		return err
+	}
+	if m.RecipeID, err = d.DecodeUint(); err != nil {
+		return err
+	}
+	if m.Label, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if c, err := d.PeekCode(); err != nil {
+		return err
+	} else if c == codes.Nil {
+		if err := d.DecodeNil(); err != nil {
+			return err
+		}
+	} else {
+		value, err := d.DecodeBool()
+		if err != nil {
+			return err
+		}
+		m.Value = &value
+	}
+	if m.Author, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Reason, err = d.DecodeString(); err != nil {
+		return err
+	}
+	m.At, err = d.DecodeInt64()
+	return err
+}
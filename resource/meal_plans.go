@@ -0,0 +1,176 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// MealPlansRscInterface is an interface to test MealPlansRsc
+type MealPlansRscInterface interface {
+	GetOne(ID int) (*MealPlan, error)
+	GetPage(after uint, limit int) ([]MealPlan, bool, error)
+	Insert(plan *MealPlan) (*MealPlan, error)
+	Replace(plan *MealPlan) (*MealPlan, error)
+	UpdateMeals(ID int, meals, previous []Meal) (*MealPlan, error)
+	Delete(ID int) error
+}
+
+// MealPlansRsc provides api to manipulate meal plans in the store
+type MealPlansRsc struct {
+	db           store.Store
+	spaceName    string
+	sequenceName string
+}
+
+// MealPlan assigns recipes to the meals of days
+type MealPlan struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	// TimeZone is the IANA name of the time zone meals are eaten in
+	TimeZone string `json:"time_zone"`
+	// Meals are sorted by date and slot
+	Meals []Meal `json:"meals"`
+	// CreatedAt is the unix time the plan was made
+	CreatedAt int64 `json:"created_at"`
+}
+
+// Meal is a recipe planned for a meal slot of a day
+type Meal struct {
+	// Date is formatted as 2006-01-02
+	Date string `json:"date"`
+	// Slot is one of MealSlots
+	Slot     string `json:"slot"`
+	RecipeID uint   `json:"recipe_id"`
+	// Servings is the servings to cook, 0 for the servings of the recipe
+	Servings int `json:"servings"`
+}
+
+// MealSlots are the meals of a day, in the order they are eaten
+var MealSlots = []string{"breakfast", "lunch", "dinner"}
+
+// NewMealPlansRsc initiates MealPlansRsc
+func NewMealPlansRsc(db store.Store) *MealPlansRsc {
+	return &MealPlansRsc{
+		db:           db,
+		spaceName:    "meal_plans",
+		sequenceName: "meal_plans_id",
+	}
+}
+
+// GetOne finds the meal plan with ID
+func (rsc *MealPlansRsc) GetOne(ID int) (*MealPlan, error) {
+	var plans []MealPlan
+	err := rsc.db.Select(rsc.spaceName, "primary", 0, 1, store.IterEq, []interface{}{ID}, &plans)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(plans) == 0 {
+		return nil, NotFound("meal plan %d doesn't exist", ID)
+	}
+
+	return &plans[0], nil
+}
+
+// GetPage finds up to limit meal plans whose ID is greater than after, in
+// ID order. It also reports whether more plans follow.
+func (rsc *MealPlansRsc) GetPage(after uint, limit int) ([]MealPlan, bool, error) {
+	var plans []MealPlan
+	err := rsc.db.Select(rsc.spaceName, "primary", 0, uint32(limit+1), store.IterGt, []interface{}{after}, &plans)
+	if err != nil {
+		return nil, false, wrapErr(err)
+	}
+	if len(plans) > limit {
+		return plans[:limit], true, nil
+	}
+
+	return plans, false, nil
+}
+
+// Insert stores plan as a new document with an ID from the sequence
+func (rsc *MealPlansRsc) Insert(plan *MealPlan) (*MealPlan, error) {
+	ID, err := nextID(rsc.db, rsc.sequenceName)
+	if err != nil {
+		return nil, err
+	}
+
+	tuple := *plan
+	tuple.ID = ID
+	var plans []MealPlan
+	err = rsc.db.Insert(rsc.spaceName, tuple, &plans)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("insert into %s returned no tuple", rsc.spaceName)
+	}
+
+	return &plans[0], nil
+}
+
+// Replace overwrites the existing plan with the ID of plan
+func (rsc *MealPlansRsc) Replace(plan *MealPlan) (*MealPlan, error) {
+	if _, err := rsc.GetOne(int(plan.ID)); err != nil {
+		return nil, err
+	}
+
+	var plans []MealPlan
+	err := rsc.db.Replace(rsc.spaceName, *plan, &plans)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("replace on %s returned no tuple", rsc.spaceName)
+	}
+
+	return &plans[0], nil
+}
+
+// UpdateMeals writes meals to the meal plan with ID, only while its meals
+// are still previous, so that a meal planned in between isn't lost. It
+// returns an ErrConflict error when the meals changed.
+func (rsc *MealPlansRsc) UpdateMeals(ID int, meals, previous []Meal) (*MealPlan, error) {
+	var plans []MealPlan
+	ops := []interface{}{[]interface{}{"=", mealPlanFieldMeals, meals}}
+	err := store.UpdateIf(rsc.db, rsc.spaceName, "primary", []interface{}{ID}, mealPlanFieldMeals, previous, ops, &plans)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(plans) == 0 {
+		if _, err := rsc.GetOne(ID); err != nil {
+			return nil, err
+		}
+		return nil, Conflict("meals of meal plan %d changed", ID)
+	}
+
+	return &plans[0], nil
+}
+
+// Delete deletes the meal plan with ID
+func (rsc *MealPlansRsc) Delete(ID int) error {
+	var plans []MealPlan
+	err := rsc.db.Delete(rsc.spaceName, "primary", []interface{}{ID}, &plans)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if len(plans) == 0 {
+		return NotFound("meal plan %d doesn't exist", ID)
+	}
+
+	return nil
+}
+
+// CreateSpace creates the space of meal plans and its sequence. It is safe
+// to run more than once.
+func (rsc *MealPlansRsc) CreateSpace() error {
+	return wrapErr(rsc.db.CreateSpace(rsc.space()))
+}
+
+// space defines the space of meal plans
+func (rsc *MealPlansRsc) space() *store.Space {
+	return &store.Space{
+		Name:     rsc.spaceName,
+		Sequence: rsc.sequenceName,
+		Indexes:  []store.Index{idIndex},
+	}
+}
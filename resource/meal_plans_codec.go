@@ -0,0 +1,106 @@
+package resource
+
+import (
+	"fmt"
+	"reflect"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+// Meal plans are stored as [id, name, time_zone, [meal...], created_at]
+// with meals as [date, slot, recipe_id, servings]
+
+// mealPlanFieldMeals is the field number of the meals
+const mealPlanFieldMeals = 3
+
+func init() {
+	msgpack.Register(reflect.TypeOf(MealPlan{}), encodeMealPlan, decodeMealPlan)
+	msgpack.Register(reflect.TypeOf(Meal{}), encodeMeal, decodeMeal)
+}
+
+func encodeMealPlan(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(MealPlan)
+	if err := e.EncodeSliceLen(5); err != nil {
+		return err
+	}
+	if err := e.EncodeUint(m.ID); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Name); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.TimeZone); err != nil {
+		return err
+	}
+	if err := e.Encode(m.Meals); err != nil {
+		return err
+	}
+	return e.EncodeInt64(m.CreatedAt)
+}
+
+func decodeMealPlan(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*MealPlan)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != 5 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+	*m = MealPlan{}
+	if m.ID, err = d.DecodeUint(); err != nil {
+		return err
+	}
+	if m.Name, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.TimeZone, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if err = d.Decode(&m.Meals); err != nil {
+		return err
+	}
+	m.CreatedAt, err = d.DecodeInt64()
+	return err
+}
+
+func encodeMeal(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(Meal)
+	if err := e.EncodeSliceLen(4); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Date); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Slot); err != nil {
+		return err
+	}
+	if err := e.EncodeUint(m.RecipeID); err != nil {
+		return err
+	}
+	return e.EncodeInt(m.Servings)
+}
+
+func decodeMeal(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*Meal)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != 4 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+	if m.Date, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Slot, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.RecipeID, err = d.DecodeUint(); err != nil {
+		return err
+	}
+	m.Servings, err = d.DecodeInt()
+	return err
+}
@@ -0,0 +1,168 @@
+package resource
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/motomux/smart-cooking-server/nutrition"
+	"github.com/motomux/smart-cooking-server/store"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+// NutritionRscInterface is an interface to test NutritionRsc
+type NutritionRscInterface interface {
+	Get(recipeID int) (*NutritionFacts, error)
+	Put(facts *NutritionFacts) (*NutritionFacts, error)
+	Delete(recipeID int) error
+}
+
+// NutritionRsc provides api to manipulate the nutrition facts cached for
+// recipes in the store
+type NutritionRsc struct {
+	db        store.Store
+	spaceName string
+}
+
+// NutritionFacts are the nutrients of a whole recipe, as computed from its
+// ingredients
+type NutritionFacts struct {
+	RecipeID uint
+	// Source identifies the nutrient database the facts were computed with
+	Source   string
+	Servings int
+	Total    nutrition.Nutrients
+	// Unmatched are the names of the ingredients left out of Total
+	Unmatched []string
+}
+
+// NewNutritionRsc initiates NutritionRsc
+func NewNutritionRsc(db store.Store) *NutritionRsc {
+	return &NutritionRsc{
+		db:        db,
+		spaceName: "recipe_nutrition",
+	}
+}
+
+// Get finds the facts cached for the recipe with recipeID
+func (rsc *NutritionRsc) Get(recipeID int) (*NutritionFacts, error) {
+	var facts []NutritionFacts
+	err := rsc.db.Select(rsc.spaceName, "primary", 0, 1, store.IterEq, []interface{}{recipeID}, &facts)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(facts) == 0 {
+		return nil, NotFound("nutrition of recipe %d isn't cached", recipeID)
+	}
+
+	return &facts[0], nil
+}
+
+// Put caches facts, replacing those cached for the same recipe
+func (rsc *NutritionRsc) Put(facts *NutritionFacts) (*NutritionFacts, error) {
+	var stored []NutritionFacts
+	err := rsc.db.Replace(rsc.spaceName, *facts, &stored)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(stored) == 0 {
+		return nil, fmt.Errorf("replace on %s returned no tuple", rsc.spaceName)
+	}
+
+	return &stored[0], nil
+}
+
+// Delete drops the facts cached for the recipe with recipeID. Deleting facts
+// which aren't cached is a no-op.
+func (rsc *NutritionRsc) Delete(recipeID int) error {
+	var facts []NutritionFacts
+	err := rsc.db.Delete(rsc.spaceName, "primary", []interface{}{recipeID}, &facts)
+	return wrapErr(err)
+}
+
+// CreateSpace creates the space facts are cached in. It is safe to run more
+// than once.
+func (rsc *NutritionRsc) CreateSpace() error {
+	return wrapErr(rsc.db.CreateSpace(rsc.space()))
+}
+
+// space defines the space facts are cached in
+func (rsc *NutritionRsc) space() *store.Space {
+	return &store.Space{
+		Name:    rsc.spaceName,
+		Indexes: []store.Index{idIndex},
+	}
+}
+
+func init() {
+	msgpack.Register(reflect.TypeOf(NutritionFacts{}), encodeNutritionFacts, decodeNutritionFacts)
+}
+
+// nutrientsLen is the number of nutrients, stored as an array in the order
+// of the fields of nutrition.Nutrients
+const nutrientsLen = 6
+
+func nutrientFields(n *nutrition.Nutrients) []*float64 {
+	return []*float64{&n.Calories, &n.Protein, &n.Fat, &n.Carbohydrate, &n.Fiber, &n.Sodium}
+}
+
+// encodeNutritionFacts writes the tuple
+// [recipe_id, source, servings, [nutrients...], [unmatched...]]
+func encodeNutritionFacts(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(NutritionFacts)
+	if err := e.EncodeSliceLen(5); err != nil {
+		return err
+	}
+	if err := e.EncodeUint(m.RecipeID); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Source); err != nil {
+		return err
+	}
+	if err := e.EncodeInt(m.Servings); err != nil {
+		return err
+	}
+	if err := e.EncodeSliceLen(nutrientsLen); err != nil {
+		return err
+	}
+	for _, f := range nutrientFields(&m.Total) {
+		if err := e.EncodeFloat64(*f); err != nil {
+			return err
+		}
+	}
+	return e.Encode(m.Unmatched)
+}
+
+func decodeNutritionFacts(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*NutritionFacts)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != 5 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+
+	*m = NutritionFacts{}
+	if m.RecipeID, err = d.DecodeUint(); err != nil {
+		return err
+	}
+	if m.Source, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Servings, err = d.DecodeInt(); err != nil {
+		return err
+	}
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != nutrientsLen {
+		return fmt.Errorf("nutrients len doesn't match: %d", l)
+	}
+	for _, f := range nutrientFields(&m.Total) {
+		if *f, err = d.DecodeFloat64(); err != nil {
+			return err
+		}
+	}
+	return d.Decode(&m.Unmatched)
+}
@@ -0,0 +1,39 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/nutrition"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+func TestNutritionFactsCodec(t *testing.T) {
+	facts := NutritionFacts{
+		RecipeID:  3,
+		Source:    "staples-1",
+		Servings:  4,
+		Total:     nutrition.Nutrients{Calories: 1200, Protein: 40.5, Fat: 30, Carbohydrate: 150, Fiber: 8, Sodium: 2300},
+		Unmatched: []string{"saffron"},
+	}
+
+	b, err := msgpack.Marshal(facts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tuple []interface{}
+	if err := msgpack.Unmarshal(b, &tuple); err != nil {
+		t.Fatal(err)
+	}
+	if len(tuple) != 5 {
+		t.Errorf("actual tuple len %d, expected tuple len 5", len(tuple))
+	}
+
+	var decoded NutritionFacts
+	if err := msgpack.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, facts) {
+		t.Errorf("actual facts %+v, expected facts %+v", decoded, facts)
+	}
+}
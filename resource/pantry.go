@@ -0,0 +1,146 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// PantryRscInterface is an interface to test PantryRsc
+type PantryRscInterface interface {
+	GetOne(ID int) (*PantryItem, error)
+	GetAll() ([]PantryItem, error)
+	Insert(item *PantryItem) (*PantryItem, error)
+	Replace(item *PantryItem) (*PantryItem, error)
+	Delete(ID int) error
+}
+
+// PantryRsc provides api to manipulate the pantry in the store
+type PantryRsc struct {
+	db           store.Store
+	spaceName    string
+	sequenceName string
+}
+
+// PantryItem is an ingredient kept at home
+type PantryItem struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	// Quantity is zero when the amount isn't tracked
+	Quantity Quantity `json:"quantity"`
+	Unit     string   `json:"unit,omitempty"`
+	// ExpiresOn is the date the item goes bad, formatted as 2006-01-02, or
+	// empty when it keeps
+	ExpiresOn string `json:"expires_on,omitempty"`
+	// AddedAt is the unix time the item was put in the pantry
+	AddedAt int64 `json:"added_at"`
+}
+
+// NewPantryRsc initiates PantryRsc
+func NewPantryRsc(db store.Store) *PantryRsc {
+	return &PantryRsc{
+		db:           db,
+		spaceName:    "pantry",
+		sequenceName: "pantry_id",
+	}
+}
+
+// GetOne finds the pantry item with ID
+func (rsc *PantryRsc) GetOne(ID int) (*PantryItem, error) {
+	var items []PantryItem
+	err := rsc.db.Select(rsc.spaceName, "primary", 0, 1, store.IterEq, []interface{}{ID}, &items)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(items) == 0 {
+		return nil, NotFound("pantry item %d doesn't exist", ID)
+	}
+
+	return &items[0], nil
+}
+
+// GetAll finds every pantry item, in ID order
+func (rsc *PantryRsc) GetAll() ([]PantryItem, error) {
+	all := []PantryItem{}
+	var after uint
+	for {
+		var items []PantryItem
+		err := rsc.db.Select(rsc.spaceName, "primary", 0, scanBatchSize, store.IterGt, []interface{}{after}, &items)
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		all = append(all, items...)
+		if len(items) < scanBatchSize {
+			return all, nil
+		}
+		after = items[len(items)-1].ID
+	}
+}
+
+// Insert stores item as a new document with an ID from the sequence
+func (rsc *PantryRsc) Insert(item *PantryItem) (*PantryItem, error) {
+	ID, err := nextID(rsc.db, rsc.sequenceName)
+	if err != nil {
+		return nil, err
+	}
+
+	tuple := *item
+	tuple.ID = ID
+	var items []PantryItem
+	err = rsc.db.Insert(rsc.spaceName, tuple, &items)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("insert into %s returned no tuple", rsc.spaceName)
+	}
+
+	return &items[0], nil
+}
+
+// Replace overwrites the existing item with the ID of item
+func (rsc *PantryRsc) Replace(item *PantryItem) (*PantryItem, error) {
+	if _, err := rsc.GetOne(int(item.ID)); err != nil {
+		return nil, err
+	}
+
+	var items []PantryItem
+	err := rsc.db.Replace(rsc.spaceName, *item, &items)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("replace on %s returned no tuple", rsc.spaceName)
+	}
+
+	return &items[0], nil
+}
+
+// Delete deletes the pantry item with ID
+func (rsc *PantryRsc) Delete(ID int) error {
+	var items []PantryItem
+	err := rsc.db.Delete(rsc.spaceName, "primary", []interface{}{ID}, &items)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if len(items) == 0 {
+		return NotFound("pantry item %d doesn't exist", ID)
+	}
+
+	return nil
+}
+
+// CreateSpace creates the space of the pantry and its sequence. It is safe
+// to run more than once.
+func (rsc *PantryRsc) CreateSpace() error {
+	return wrapErr(rsc.db.CreateSpace(rsc.space()))
+}
+
+// space defines the space of the pantry
+func (rsc *PantryRsc) space() *store.Space {
+	return &store.Space{
+		Name:     rsc.spaceName,
+		Sequence: rsc.sequenceName,
+		Indexes:  []store.Index{idIndex},
+	}
+}
@@ -0,0 +1,72 @@
+package resource
+
+import (
+	"fmt"
+	"reflect"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+// Pantry items are stored as [id, name, num, den, unit, expires_on, added_at]
+
+func init() {
+	msgpack.Register(reflect.TypeOf(PantryItem{}), encodePantryItem, decodePantryItem)
+}
+
+func encodePantryItem(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(PantryItem)
+	if err := e.EncodeSliceLen(7); err != nil {
+		return err
+	}
+	if err := e.EncodeUint(m.ID); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Name); err != nil {
+		return err
+	}
+	if err := e.EncodeInt64(m.Quantity.Num); err != nil {
+		return err
+	}
+	if err := e.EncodeInt64(m.Quantity.Den); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Unit); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.ExpiresOn); err != nil {
+		return err
+	}
+	return e.EncodeInt64(m.AddedAt)
+}
+
+func decodePantryItem(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*PantryItem)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != 7 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+	if m.ID, err = d.DecodeUint(); err != nil {
+		return err
+	}
+	if m.Name, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Quantity.Num, err = d.DecodeInt64(); err != nil {
+		return err
+	}
+	if m.Quantity.Den, err = d.DecodeInt64(); err != nil {
+		return err
+	}
+	if m.Unit, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.ExpiresOn, err = d.DecodeString(); err != nil {
+		return err
+	}
+	m.AddedAt, err = d.DecodeInt64()
+	return err
+}
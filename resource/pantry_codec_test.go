@@ -0,0 +1,27 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+func TestPantryItemCodec(t *testing.T) {
+	items := []PantryItem{
+		{ID: 3, Name: "milk", Quantity: NewQuantity(1, 2), Unit: "l", ExpiresOn: "2026-10-20", AddedAt: 1700000000},
+		{ID: 4, Name: "salt", AddedAt: 1700000000},
+	}
+
+	b, err := msgpack.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []PantryItem
+	if err := msgpack.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, items) {
+		t.Errorf("actual items %+v, expected items %+v", decoded, items)
+	}
+}
@@ -2,106 +2,427 @@ package resource
 
 import (
 	"fmt"
-	"reflect"
-	"strings"
-
-	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+	"time"
 
+	"github.com/motomux/smart-cooking-server/store"
 	tarantool "github.com/tarantool/go-tarantool"
 )
 
 // RecipesRscInterface is an interface to test RecipesRsc
 type RecipesRscInterface interface {
 	GetOne(ID int) (*Recipe, error)
+	Insert(recipe *Recipe) (*Recipe, error)
+	Replace(recipe *Recipe, previous map[string]bool) (*Recipe, error)
+	Update(ID int, recipe *Recipe, fields []string, previous map[string]bool) (*Recipe, error)
+	OverrideLabels(ID int, recipe *Recipe, previous map[string]bool) (*Recipe, error)
+	SoftDelete(ID int, at time.Time) (*Recipe, error)
+	Restore(ID int) (*Recipe, error)
+	GetPage(after uint, limit int) ([]Recipe, bool, error)
+	Find(query *RecipeQuery, after *Recipe, limit int) ([]Recipe, bool, error)
+	Facets(query *RecipeQuery) (*RecipeFacets, error)
+	GetDeleted() ([]Recipe, error)
+	Purge(before time.Time) ([]uint, error)
 }
 
-// RecipesRsc provides api to manipulate resouce on tarantool
+// RecipesRsc provides api to manipulate resouce in the store
 type RecipesRsc struct {
-	client    *tarantool.Connection
-	spaceName string
+	db           store.Store
+	spaceName    string
+	sequenceName string
 }
 
 // Recipe represents a document on Recipess collection in taratool
 type Recipe struct {
-	ID    uint     `json:"id"`
-	Title string   `json:"title"`
-	Photo string   `json:"photo"`
-	Howto []string `json:"howto"`
-	Video string   `json:"video"`
+	ID          uint         `json:"id"`
+	Title       string       `json:"title"`
+	Photo       string       `json:"photo"`
+	Ingredients []Ingredient `json:"ingredients"`
+	Howto       []Step       `json:"howto"`
+	Video       string       `json:"video"`
+	Tags        []string     `json:"tags"`
+	Cuisine     string       `json:"cuisine"`
+	// Difficulty is one of "easy", "medium" and "hard", or empty
+	Difficulty string `json:"difficulty"`
+	// PrepTime and CookTime are in seconds, 0 when unknown
+	PrepTime int `json:"prep_time"`
+	CookTime int `json:"cook_time"`
+	// Rating is the average rating from 0 to 5
+	Rating float64 `json:"rating"`
+	// Servings is how many people the quantities of the ingredients serve, 0
+	// when unknown
+	Servings int `json:"servings"`
+	// Labels are the dietary labels, like "vegan", derived from the
+	// ingredients with LabelOverrides applied
+	Labels []string `json:"labels"`
+	// LabelOverrides are the labels the author set on or off, whatever the
+	// ingredients say. They are changed through the label audit trail only.
+	LabelOverrides map[string]bool `json:"label_overrides,omitempty"`
+	// DeletedAt is the unix time the recipe was moved to the trash, 0 while
+	// the recipe is alive
+	DeletedAt int64 `json:"deleted_at,omitempty"`
 }
 
-func init() {
-	msgpack.Register(reflect.TypeOf(Recipe{}), encodeRecipe, decodeRecipe)
-}
+const scanBatchSize = 100
 
 // NewRecipesRsc initiates RecipesRsc
-func NewRecipesRsc(client *tarantool.Connection) *RecipesRsc {
+func NewRecipesRsc(db store.Store) *RecipesRsc {
 	return &RecipesRsc{
-		client:    client,
-		spaceName: "recipes",
+		db:           db,
+		spaceName:    "recipes",
+		sequenceName: "recipes_id",
 	}
 }
 
-// GetOne finds one document on MongoDB with RecipeID
+// GetOne finds one document on MongoDB with RecipeID. Recipes in the trash
+// are reported as not found.
 func (rsc *RecipesRsc) GetOne(ID int) (*Recipe, error) {
+	recipe, err := rsc.getOne(ID)
+	if err != nil {
+		return nil, err
+	}
+	if recipe.DeletedAt != 0 {
+		return nil, NotFound("recipe %d is in the trash", ID)
+	}
+
+	return recipe, nil
+}
+
+func (rsc *RecipesRsc) getOne(ID int) (*Recipe, error) {
 	var recipes []Recipe
-	err := rsc.client.SelectTyped(rsc.spaceName, "primary", 0, 1, tarantool.IterEq, []interface{}{ID}, &recipes)
+	err := rsc.db.Select(rsc.spaceName, "primary", 0, 1, store.IterEq, []interface{}{ID}, &recipes)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(recipes) == 0 {
+		return nil, NotFound("recipe %d doesn't exist", ID)
+	}
+
+	return &recipes[0], nil
+}
+
+// GetPage finds up to limit documents whose ID is greater than after, in ID
+// order, skipping the trash. It also reports whether more documents follow.
+func (rsc *RecipesRsc) GetPage(after uint, limit int) ([]Recipe, bool, error) {
+	return rsc.Find(&RecipeQuery{}, &Recipe{ID: after}, limit)
+}
+
+// Insert stores recipe as a new document. The ID is always allocated from
+// the recipes sequence, whatever the caller put into recipe.ID.
+func (rsc *RecipesRsc) Insert(recipe *Recipe) (*Recipe, error) {
+	ID, err := nextID(rsc.db, rsc.sequenceName)
 	if err != nil {
 		return nil, err
 	}
 
+	tuple := *recipe
+	tuple.ID = ID
+	var recipes []Recipe
+	err = rsc.db.Insert(rsc.spaceName, tuple, &recipes)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(recipes) == 0 {
+		return nil, fmt.Errorf("insert into %s returned no tuple", rsc.spaceName)
+	}
+
 	return &recipes[0], nil
 }
 
-func encodeRecipe(e *msgpack.Encoder, v reflect.Value) error {
-	m := v.Interface().(Recipe)
-	if err := e.EncodeSliceLen(5); err != nil {
-		return err
+// Replace overwrites the whole document with recipe, only while its label
+// overrides are still previous, so that an override made in between isn't
+// lost. The document must already exist, IDs are never created by replace.
+// It returns an ErrConflict error when the overrides changed.
+func (rsc *RecipesRsc) Replace(recipe *Recipe, previous map[string]bool) (*Recipe, error) {
+	current, err := rsc.GetOne(int(recipe.ID))
+	if err != nil {
+		return nil, err
 	}
-	if err := e.EncodeUint(m.ID); err != nil {
-		return err
+
+	tuple := *recipe
+	tuple.DeletedAt = current.DeletedAt
+	var recipes []Recipe
+	err = store.ReplaceIf(rsc.db, rsc.spaceName, "primary", []interface{}{recipe.ID}, fieldLabelOverrides, previous, tuple, &recipes)
+	if err != nil {
+		return nil, wrapErr(err)
 	}
-	if err := e.EncodeString(m.Title); err != nil {
-		return err
+	if len(recipes) == 0 {
+		return nil, rsc.overridesChanged(int(recipe.ID))
 	}
-	if err := e.EncodeString(m.Photo); err != nil {
-		return err
+
+	return &recipes[0], nil
+}
+
+func (rsc *RecipesRsc) replace(recipe *Recipe) (*Recipe, error) {
+	var recipes []Recipe
+	err := rsc.db.Replace(rsc.spaceName, *recipe, &recipes)
+	if err != nil {
+		return nil, wrapErr(err)
 	}
-	if err := e.EncodeString(strings.Join(m.Howto[:], ",")); err != nil {
-		return err
+	if len(recipes) == 0 {
+		return nil, fmt.Errorf("replace on %s returned no tuple", rsc.spaceName)
 	}
-	if err := e.EncodeString(m.Video); err != nil {
-		return err
+
+	return &recipes[0], nil
+}
+
+// Update writes only the listed fields of recipe, named as in its json
+// representation, to the document with ID, only while its label overrides
+// are still previous, like Replace
+func (rsc *RecipesRsc) Update(ID int, recipe *Recipe, fields []string, previous map[string]bool) (*Recipe, error) {
+	ops, err := recipeUpdateOps(recipe, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipes []Recipe
+	err = store.UpdateIf(rsc.db, rsc.spaceName, "primary", []interface{}{ID}, fieldLabelOverrides, previous, ops, &recipes)
+	if e, ok := err.(tarantool.Error); ok && (e.Code == tarantool.ErrNoSuchField || e.Code == tarantool.ErrUpdateField) {
+		// tuples written by older versions lack trailing fields, which
+		// can't be assigned by update, and have no overrides to lose, so
+		// the whole tuple is rewritten
+		tuple := *recipe
+		tuple.ID = uint(ID)
+		return rsc.replace(&tuple)
+	}
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(recipes) == 0 {
+		return nil, rsc.overridesChanged(ID)
+	}
+
+	return &recipes[0], nil
+}
+
+// OverrideLabels writes the label overrides and labels of recipe to the
+// document with ID, only while its overrides are still previous, so that an
+// override made in between isn't lost. It returns an ErrConflict error when
+// the overrides changed.
+func (rsc *RecipesRsc) OverrideLabels(ID int, recipe *Recipe, previous map[string]bool) (*Recipe, error) {
+	ops, err := recipeUpdateOps(recipe, []string{"label_overrides", "labels"})
+	if err != nil {
+		return nil, err
+	}
+
+	var recipes []Recipe
+	err = store.UpdateIf(rsc.db, rsc.spaceName, "primary", []interface{}{ID}, fieldLabelOverrides, previous, ops, &recipes)
+	if e, ok := err.(tarantool.Error); ok && (e.Code == tarantool.ErrNoSuchField || e.Code == tarantool.ErrUpdateField) {
+		// tuples written before version 4 lack the label fields and
+		// have no overrides to lose, so they are rewritten like Update
+		// does
+		tuple := *recipe
+		tuple.ID = uint(ID)
+		return rsc.replace(&tuple)
+	}
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(recipes) == 0 {
+		return nil, rsc.overridesChanged(ID)
 	}
-	return nil
+
+	return &recipes[0], nil
 }
 
-func decodeRecipe(d *msgpack.Decoder, v reflect.Value) error {
-	var err error
-	var l int
-	m := v.Addr().Interface().(*Recipe)
-	if l, err = d.DecodeSliceLen(); err != nil {
+// overridesChanged tells why a write guarded on the label overrides of the
+// document with ID wrote nothing: it doesn't exist, or the overrides changed
+func (rsc *RecipesRsc) overridesChanged(ID int) error {
+	if _, err := rsc.getOne(ID); err != nil {
 		return err
 	}
-	if l != 5 {
-		return fmt.Errorf("array len doesn't match: %d", l)
+	return Conflict("label overrides of recipe %d changed", ID)
+}
+
+// SoftDelete moves the document with ID to the trash
+func (rsc *RecipesRsc) SoftDelete(ID int, at time.Time) (*Recipe, error) {
+	recipe, err := rsc.GetOne(ID)
+	if err != nil {
+		return nil, err
 	}
-	if m.ID, err = d.DecodeUint(); err != nil {
-		return err
+
+	recipe.DeletedAt = at.Unix()
+	return rsc.replace(recipe)
+}
+
+// Restore takes the document with ID back from the trash. Restoring a
+// recipe which isn't in the trash is a no-op.
+func (rsc *RecipesRsc) Restore(ID int) (*Recipe, error) {
+	recipe, err := rsc.getOne(ID)
+	if err != nil {
+		return nil, err
 	}
-	if m.Title, err = d.DecodeString(); err != nil {
-		return err
+	if recipe.DeletedAt == 0 {
+		return recipe, nil
 	}
-	if m.Photo, err = d.DecodeString(); err != nil {
-		return err
+
+	recipe.DeletedAt = 0
+	return rsc.replace(recipe)
+}
+
+// GetDeleted finds all documents in the trash
+func (rsc *RecipesRsc) GetDeleted() ([]Recipe, error) {
+	var deleted []Recipe
+	err := rsc.scan(func(recipe *Recipe) error {
+		if recipe.DeletedAt != 0 {
+			deleted = append(deleted, *recipe)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if howtos, err := d.DecodeString(); err == nil {
-		m.Howto = strings.Split(howtos, ",")
-	} else {
-		return err
+
+	return deleted, nil
+}
+
+// Purge permanently deletes documents which were moved to the trash before
+// the given time, and returns their IDs. A document is only deleted while it
+// is still in the trash since the time it was found there, so that one
+// restored in the meantime is kept.
+func (rsc *RecipesRsc) Purge(before time.Time) ([]uint, error) {
+	var expired []Recipe
+	err := rsc.scan(func(recipe *Recipe) error {
+		if recipe.DeletedAt != 0 && recipe.DeletedAt < before.Unix() {
+			expired = append(expired, *recipe)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if m.Video, err = d.DecodeString(); err != nil {
-		return err
+
+	var purged []uint
+	for _, recipe := range expired {
+		var recipes []Recipe
+		err := store.DeleteIf(rsc.db, rsc.spaceName, "primary", []interface{}{recipe.ID}, fieldDeletedAt, recipe.DeletedAt, &recipes)
+		if err != nil {
+			return purged, wrapErr(err)
+		}
+		if len(recipes) > 0 {
+			purged = append(purged, recipe.ID)
+		}
+	}
+
+	return purged, nil
+}
+
+// scan walks the whole space in primary key order and calls fn for every
+// document
+func (rsc *RecipesRsc) scan(fn func(recipe *Recipe) error) error {
+	var after uint
+	for {
+		var recipes []Recipe
+		err := rsc.db.Select(rsc.spaceName, "primary", 0, scanBatchSize, store.IterGt, []interface{}{after}, &recipes)
+		if err != nil {
+			return wrapErr(err)
+		}
+		for i := range recipes {
+			if err := fn(&recipes[i]); err != nil {
+				return err
+			}
+		}
+		if len(recipes) < scanBatchSize {
+			return nil
+		}
+		after = recipes[len(recipes)-1].ID
+	}
+}
+
+// MigrateHowto rewrites the howto of documents stored as a comma-joined
+// string into an array of steps, and returns the number of migrated
+// documents. Documents already migrated are left alone, so it is safe to run
+// more than once.
+func (rsc *RecipesRsc) MigrateHowto() (int, error) {
+	var migrated int
+	var after uint64
+	for {
+		var rows []interface{}
+		err := rsc.db.Select(rsc.spaceName, "primary", 0, scanBatchSize, store.IterGt, []interface{}{after}, &rows)
+		if err != nil {
+			return migrated, wrapErr(err)
+		}
+		for _, row := range rows {
+			tuple, ok := row.([]interface{})
+			if !ok || len(tuple) == 0 {
+				return migrated, fmt.Errorf("unexpected tuple %v in %s", row, rsc.spaceName)
+			}
+			if after, ok = toUint64(tuple[fieldID]); !ok {
+				return migrated, fmt.Errorf("unexpected primary key %v in %s", tuple[fieldID], rsc.spaceName)
+			}
+			if len(tuple) <= fieldHowto {
+				continue
+			}
+			howtos, ok := tuple[fieldHowto].(string)
+			if !ok {
+				continue
+			}
+			ops := []interface{}{[]interface{}{"=", fieldHowto, splitHowto(howtos)}}
+			if err := rsc.db.Update(rsc.spaceName, "primary", []interface{}{after}, ops, nil); err != nil {
+				return migrated, wrapErr(err)
+			}
+			migrated++
+		}
+		if len(rows) < scanBatchSize {
+			return migrated, nil
+		}
+	}
+}
+
+// idIndex is the primary index of spaces keyed by an unsigned ID
+var idIndex = store.Index{Name: "primary", Parts: []store.Part{{Field: 0, Type: "unsigned"}}}
+
+func nextID(db store.Store, sequenceName string) (uint, error) {
+	ID, err := db.NextValue(sequenceName)
+	return ID, wrapErr(err)
+}
+
+// recipeUpdateOps translates fields into tarantool update operations on the
+// tuple layout described in recipes_codec.go
+func recipeUpdateOps(recipe *Recipe, fields []string) ([]interface{}, error) {
+	ops := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "title":
+			ops = append(ops, []interface{}{"=", fieldTitle, recipe.Title})
+		case "photo":
+			ops = append(ops, []interface{}{"=", fieldPhoto, recipe.Photo})
+		case "howto":
+			ops = append(ops, []interface{}{"=", fieldHowto, recipe.Howto})
+		case "video":
+			ops = append(ops, []interface{}{"=", fieldVideo, recipe.Video})
+		case "ingredients":
+			ops = append(ops, []interface{}{"=", fieldIngredients, recipe.Ingredients})
+		case "tags":
+			ops = append(ops, []interface{}{"=", fieldTags, recipe.Tags})
+		case "cuisine":
+			ops = append(ops, []interface{}{"=", fieldCuisine, recipe.Cuisine})
+		case "difficulty":
+			ops = append(ops, []interface{}{"=", fieldDifficulty, recipe.Difficulty})
+		case "prep_time":
+			ops = append(ops, []interface{}{"=", fieldPrepTime, recipe.PrepTime})
+		case "cook_time":
+			ops = append(ops, []interface{}{"=", fieldCookTime, recipe.CookTime})
+		case "rating":
+			ops = append(ops, []interface{}{"=", fieldRating, recipe.Rating})
+		case "servings":
+			ops = append(ops, []interface{}{"=", fieldServings, recipe.Servings})
+		case "labels":
+			ops = append(ops, []interface{}{"=", fieldLabels, recipe.Labels})
+		case "label_overrides":
+			ops = append(ops, []interface{}{"=", fieldLabelOverrides, recipe.LabelOverrides})
+		default:
+			return nil, Invalid(field, "can't be updated")
+		}
+	}
+	return ops, nil
+}
+
+func toUint64(v interface{}) (uint64, bool) {
+	switch v := v.(type) {
+	case uint64:
+		return v, true
+	case int64:
+		return uint64(v), v >= 0
 	}
-	return nil
+	return 0, false
 }
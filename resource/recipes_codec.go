@@ -0,0 +1,219 @@
+package resource
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/diet"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+	"gopkg.in/vmihailenco/msgpack.v2/codes"
+)
+
+// Fields of a recipe tuple. A field keeps its number forever, so that
+// indexes and update operations stay valid across releases; new fields are
+// only ever appended.
+//
+// Tuples written before the format version field existed are version 0.
+// They have 5 to 7 fields, and their howto is either a comma-joined string
+// or an array of steps. Version 1 tuples carry every field up to
+// fieldFormatVersion, version 2 tuples every field up to fieldRating,
+// version 3 tuples every field up to fieldServings and version 4 tuples
+// every field up to fieldLabelOverrides.
+const (
+	fieldID = iota
+	fieldTitle
+	fieldPhoto
+	fieldHowto
+	fieldVideo
+	fieldDeletedAt
+	fieldIngredients
+	fieldFormatVersion
+	fieldTags
+	fieldCuisine
+	fieldDifficulty
+	fieldPrepTime
+	fieldCookTime
+	fieldRating
+	fieldServings
+	fieldLabels
+	fieldLabelOverrides
+)
+
+// RecipeFormatVersion is the version of the tuples written by this release
+const RecipeFormatVersion = 4
+
+// recipeField encodes and decodes one field of a recipe tuple. decode is
+// only called when the tuple has the field and it isn't nil.
+type recipeField struct {
+	encode func(e *msgpack.Encoder, m *Recipe) error
+	decode func(d *msgpack.Decoder, m *Recipe) error
+}
+
+// recipeFields is indexed by field number
+var recipeFields = []recipeField{
+	fieldID: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeUint(m.ID) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.ID, err = d.DecodeUint(); return },
+	},
+	fieldTitle: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeString(m.Title) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.Title, err = d.DecodeString(); return },
+	},
+	fieldPhoto: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeString(m.Photo) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.Photo, err = d.DecodeString(); return },
+	},
+	fieldHowto: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.Encode(m.Howto) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.Howto, err = decodeHowto(d); return },
+	},
+	fieldVideo: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeString(m.Video) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.Video, err = d.DecodeString(); return },
+	},
+	fieldDeletedAt: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeInt64(m.DeletedAt) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.DeletedAt, err = d.DecodeInt64(); return },
+	},
+	fieldIngredients: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.Encode(m.Ingredients) },
+		func(d *msgpack.Decoder, m *Recipe) error { return d.Decode(&m.Ingredients) },
+	},
+	fieldFormatVersion: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeUint(RecipeFormatVersion) },
+		// every layout so far can be told apart without the version
+		func(d *msgpack.Decoder, m *Recipe) error { _, err := d.DecodeUint(); return err },
+	},
+	fieldTags: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.Encode(m.Tags) },
+		func(d *msgpack.Decoder, m *Recipe) error { return d.Decode(&m.Tags) },
+	},
+	fieldCuisine: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeString(m.Cuisine) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.Cuisine, err = d.DecodeString(); return },
+	},
+	fieldDifficulty: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeString(m.Difficulty) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.Difficulty, err = d.DecodeString(); return },
+	},
+	fieldPrepTime: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeInt(m.PrepTime) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.PrepTime, err = d.DecodeInt(); return },
+	},
+	fieldCookTime: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeInt(m.CookTime) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.CookTime, err = d.DecodeInt(); return },
+	},
+	fieldRating: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeFloat64(m.Rating) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.Rating, err = d.DecodeFloat64(); return },
+	},
+	fieldServings: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.EncodeInt(m.Servings) },
+		func(d *msgpack.Decoder, m *Recipe) (err error) { m.Servings, err = d.DecodeInt(); return },
+	},
+	fieldLabels: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.Encode(m.Labels) },
+		func(d *msgpack.Decoder, m *Recipe) error { return d.Decode(&m.Labels) },
+	},
+	fieldLabelOverrides: {
+		func(e *msgpack.Encoder, m *Recipe) error { return e.Encode(m.LabelOverrides) },
+		func(d *msgpack.Decoder, m *Recipe) error { return d.Decode(&m.LabelOverrides) },
+	},
+}
+
+func init() {
+	msgpack.Register(reflect.TypeOf(Recipe{}), encodeRecipe, decodeRecipe)
+}
+
+func encodeRecipe(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(Recipe)
+	if err := e.EncodeSliceLen(len(recipeFields)); err != nil {
+		return err
+	}
+	for i, f := range recipeFields {
+		if err := f.encode(e, &m); err != nil {
+			return fmt.Errorf("recipe field %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// decodeRecipe reads a recipe tuple of any version. Missing trailing fields
+// and nil fields are left at their zero value, fields added by newer
+// versions are skipped.
+func decodeRecipe(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*Recipe)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l < 1 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+
+	*m = Recipe{}
+	for i := 0; i < l; i++ {
+		if i >= len(recipeFields) {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if c, err := d.PeekCode(); err != nil {
+			return err
+		} else if c == codes.Nil {
+			if err := d.DecodeNil(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := recipeFields[i].decode(d, m); err != nil {
+			return fmt.Errorf("recipe field %d: %s", i, err)
+		}
+	}
+	if l <= fieldLabels {
+		// tuples written before version 4 have no labels stored, so they
+		// are derived from the ingredients until the recipe is written
+		names := make([]string, len(m.Ingredients))
+		for i, ingredient := range m.Ingredients {
+			names[i] = ingredient.Name
+		}
+		m.Labels = diet.Apply(diet.Analyze(names).Derived, m.LabelOverrides)
+	}
+	return nil
+}
+
+// decodeHowto reads the array of steps, or the comma-joined string version 0
+// tuples may have
+func decodeHowto(d *msgpack.Decoder) ([]Step, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+	if !isStringCode(c) {
+		var howto []Step
+		err := d.Decode(&howto)
+		return howto, err
+	}
+
+	howtos, err := d.DecodeString()
+	if err != nil {
+		return nil, err
+	}
+	return splitHowto(howtos), nil
+}
+
+// splitHowto splits a comma-joined howto into steps, dropping the spaces
+// around them and the empty ones
+func splitHowto(howtos string) []Step {
+	var howto []Step
+	for _, text := range strings.Split(howtos, ",") {
+		if text = strings.TrimSpace(text); text != "" {
+			howto = append(howto, Step{Text: text})
+		}
+	}
+	return howto
+}
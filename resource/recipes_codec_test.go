@@ -0,0 +1,102 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+func TestDecodeRecipeVersions(t *testing.T) {
+	type (
+		in struct {
+			tuple []interface{}
+		}
+		out struct {
+			recipe Recipe
+		}
+	)
+
+	steps := []Step{{Text: "mix"}, {Text: "bake", Duration: 1800}}
+	ingredients := []Ingredient{{Name: "flour", Quantity: Quantity{2, 1}, Unit: "cup"}}
+	// tuples before version 4 have their labels derived from the ingredients
+	anyLabels := []string{"vegan", "vegetarian", "gluten-free", "nut-free", "dairy-free"}
+	flourLabels := []string{"vegan", "vegetarian", "nut-free", "dairy-free"}
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		// version 0 as first written, howto comma-joined
+		"case-01": {
+			in{[]interface{}{1, "Bread", "http://p", "mix, bake ,", "http://v"}},
+			out{Recipe{ID: 1, Title: "Bread", Photo: "http://p", Howto: []Step{{Text: "mix"}, {Text: "bake"}}, Video: "http://v", Labels: anyLabels}},
+		},
+		// version 0 with deleted_at
+		"case-02": {
+			in{[]interface{}{2, "Bread", "", "mix", "", 1500000000}},
+			out{Recipe{ID: 2, Title: "Bread", Howto: []Step{{Text: "mix"}}, DeletedAt: 1500000000, Labels: anyLabels}},
+		},
+		// version 0 with ingredients and steps
+		"case-03": {
+			in{[]interface{}{3, "Bread", "", steps, "", 0, ingredients}},
+			out{Recipe{ID: 3, Title: "Bread", Howto: steps, Ingredients: ingredients, Labels: flourLabels}},
+		},
+		// version 1
+		"case-04": {
+			in{[]interface{}{4, "Bread", "", steps, "", 0, ingredients, 1}},
+			out{Recipe{ID: 4, Title: "Bread", Howto: steps, Ingredients: ingredients, Labels: flourLabels}},
+		},
+		// written by a newer version with more fields
+		"case-05": {
+			in{[]interface{}{5, "Bread", "", steps, "", 0, ingredients, 7, []string{"baking"}, "french", "easy", 600, 1800, 4.5, 4, []string{"vegan"}, map[string]bool{"vegan": true}, "new", []int{1}}},
+			out{Recipe{ID: 5, Title: "Bread", Howto: steps, Ingredients: ingredients, Tags: []string{"baking"}, Cuisine: "french", Difficulty: "easy", PrepTime: 600, CookTime: 1800, Rating: 4.5, Servings: 4, Labels: []string{"vegan"}, LabelOverrides: map[string]bool{"vegan": true}}},
+		},
+		// missing trailing fields and nil fields
+		"case-06": {
+			in{[]interface{}{6, "Bread", nil}},
+			out{Recipe{ID: 6, Title: "Bread", Labels: anyLabels}},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			b, err := msgpack.Marshal(in.tuple)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var recipe Recipe
+			if err := msgpack.Unmarshal(b, &recipe); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(recipe, out.recipe) {
+				t.Errorf("actual recipe %+v, expected recipe %+v", recipe, out.recipe)
+			}
+
+			// whatever version was read, it is written back as the current one
+			b, err = msgpack.Marshal(recipe)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tuple []interface{}
+			if err := msgpack.Unmarshal(b, &tuple); err != nil {
+				t.Fatal(err)
+			}
+			if l := len(tuple); l != len(recipeFields) {
+				t.Fatalf("actual tuple len %d, expected tuple len %d", l, len(recipeFields))
+			}
+			if v, _ := toUint64(tuple[fieldFormatVersion]); v != RecipeFormatVersion {
+				t.Errorf("actual format version %v, expected format version %d", tuple[fieldFormatVersion], RecipeFormatVersion)
+			}
+			var decoded Recipe
+			if err := msgpack.Unmarshal(b, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, out.recipe) {
+				t.Errorf("actual round trip %+v, expected round trip %+v", decoded, out.recipe)
+			}
+		})
+	}
+}
@@ -0,0 +1,441 @@
+package resource
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// Sort orders of a recipe listing
+const (
+	// SortID lists recipes in the order they were created
+	SortID = "id"
+	// SortNewest lists the most recently created recipes first
+	SortNewest = "newest"
+	// SortRating lists the best rated recipes first
+	SortRating = "rating"
+	// SortCookTime lists the quickest recipes first. Recipes whose cook time
+	// is unknown come before all others.
+	SortCookTime = "cook_time"
+)
+
+// Sorts are the sort orders of a recipe listing
+var Sorts = []string{SortID, SortNewest, SortRating, SortCookTime}
+
+// Difficulties are the values Recipe.Difficulty may take besides empty
+var Difficulties = []string{"easy", "medium", "hard"}
+
+// RecipeQuery filters and orders a recipe listing. Zero fields don't filter.
+type RecipeQuery struct {
+	// Tags are the tags a recipe must all have
+	Tags []string
+	// Labels are the dietary labels a recipe must all have
+	Labels     []string
+	Cuisine    string
+	Difficulty string
+	// MaxTotalTime is the longest prep and cook time in seconds. Recipes
+	// whose total time is unknown don't match it.
+	MaxTotalTime int
+	HasVideo     *bool
+	// Includes and Excludes are words which the ingredient names must, or
+	// must not, contain
+	Includes []string
+	Excludes []string
+	// Sort is one of the Sort constants, SortID when empty
+	Sort string
+}
+
+// RecipeFacets counts the values of the recipes matching a query, so that
+// clients can show how many recipes each further filter would leave
+type RecipeFacets struct {
+	Tags       map[string]int `json:"tags"`
+	Labels     map[string]int `json:"labels"`
+	Cuisine    map[string]int `json:"cuisine"`
+	Difficulty map[string]int `json:"difficulty"`
+	HasVideo   int            `json:"has_video"`
+}
+
+// recipeIndex is a secondary index of the recipes space. Every one of them
+// ends with the primary key, so that keys are unique and a listing can
+// continue after any document.
+type recipeIndex struct {
+	name  string
+	parts []store.Part
+	key   func(r *Recipe) []interface{}
+}
+
+// recipeIndexes are the secondary indexes the query planner makes use of
+// when they exist. CreateIndexes creates them.
+var recipeIndexes = []recipeIndex{
+	{
+		"cuisine",
+		[]store.Part{{Field: fieldCuisine, Type: "string"}, {Field: fieldID, Type: "unsigned"}},
+		func(r *Recipe) []interface{} { return []interface{}{r.Cuisine, r.ID} },
+	},
+	{
+		"difficulty",
+		[]store.Part{{Field: fieldDifficulty, Type: "string"}, {Field: fieldID, Type: "unsigned"}},
+		func(r *Recipe) []interface{} { return []interface{}{r.Difficulty, r.ID} },
+	},
+	{
+		"rating",
+		[]store.Part{{Field: fieldRating, Type: "number"}, {Field: fieldID, Type: "unsigned"}},
+		func(r *Recipe) []interface{} { return []interface{}{r.Rating, r.ID} },
+	},
+	{
+		"cook_time",
+		[]store.Part{{Field: fieldCookTime, Type: "integer"}, {Field: fieldID, Type: "unsigned"}},
+		func(r *Recipe) []interface{} { return []interface{}{r.CookTime, r.ID} },
+	},
+}
+
+func primaryKey(r *Recipe) []interface{} {
+	return []interface{}{r.ID}
+}
+
+// recipeSort is a sort order and the index which yields it
+type recipeSort struct {
+	index string
+	desc  bool
+	less  func(a, b *Recipe) bool
+}
+
+var recipeSorts = map[string]recipeSort{
+	SortID: {"primary", false, func(a, b *Recipe) bool {
+		return a.ID < b.ID
+	}},
+	SortNewest: {"primary", true, func(a, b *Recipe) bool {
+		return a.ID > b.ID
+	}},
+	SortRating: {"rating", true, func(a, b *Recipe) bool {
+		return a.Rating > b.Rating || a.Rating == b.Rating && a.ID > b.ID
+	}},
+	SortCookTime: {"cook_time", false, func(a, b *Recipe) bool {
+		return a.CookTime < b.CookTime || a.CookTime == b.CookTime && a.ID < b.ID
+	}},
+}
+
+// recipePlan is how the documents of a query are read
+type recipePlan struct {
+	index string
+	desc  bool
+	// prefix is the key every document of the plan has, nil for the whole
+	// index
+	prefix []interface{}
+	// key returns the index key of a document, which reading continues after
+	key func(r *Recipe) []interface{}
+	// ordered is whether the index yields the sort order of the query.
+	// Otherwise all documents are read and sorted in memory.
+	ordered bool
+}
+
+// planRecipeQuery picks the index a query is read from. The index of the sort
+// order is used when the order isn't by ID, since it saves sorting every
+// matching document. Listings by ID narrow down to one cuisine or difficulty
+// instead, which their indexes keep in ID order. All filters are applied to
+// the documents read in any case.
+func planRecipeQuery(query *RecipeQuery, hasIndex func(name string) bool) recipePlan {
+	s := query.sort()
+	if s.index != "primary" {
+		if hasIndex(s.index) {
+			return recipePlan{index: s.index, desc: s.desc, key: indexKey(s.index), ordered: true}
+		}
+		return recipePlan{index: "primary", key: primaryKey}
+	}
+
+	for _, eq := range []struct{ index, value string }{
+		{"cuisine", query.Cuisine},
+		{"difficulty", query.Difficulty},
+	} {
+		if eq.value == "" || !hasIndex(eq.index) {
+			continue
+		}
+		value := eq.value
+		return recipePlan{
+			index:   eq.index,
+			desc:    s.desc,
+			prefix:  []interface{}{value},
+			key:     func(r *Recipe) []interface{} { return []interface{}{value, r.ID} },
+			ordered: true,
+		}
+	}
+	return recipePlan{index: "primary", desc: s.desc, key: primaryKey, ordered: true}
+}
+
+// sort returns the sort order of the query, by ID unless it names another
+// one
+func (query *RecipeQuery) sort() recipeSort {
+	if s, ok := recipeSorts[query.Sort]; ok {
+		return s
+	}
+	return recipeSorts[SortID]
+}
+
+func indexKey(name string) func(r *Recipe) []interface{} {
+	for _, index := range recipeIndexes {
+		if index.name == name {
+			return index.key
+		}
+	}
+	return primaryKey
+}
+
+// Find finds up to limit documents matching query, in its sort order, which
+// follow the document after, or from the first one when after is nil. Only
+// the fields of after which the sort order depends on are used. It also
+// reports whether more documents follow.
+func (rsc *RecipesRsc) Find(query *RecipeQuery, after *Recipe, limit int) ([]Recipe, bool, error) {
+	plan := planRecipeQuery(query, rsc.hasIndex)
+	if !plan.ordered {
+		var matched []Recipe
+		err := rsc.walk(plan, nil, func(recipe *Recipe) bool {
+			if query.matches(recipe) {
+				matched = append(matched, *recipe)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		page, more := sortedPage(matched, query.sort().less, after, limit)
+		return page, more, nil
+	}
+
+	page := make([]Recipe, 0, limit)
+	var more bool
+	err := rsc.walk(plan, after, func(recipe *Recipe) bool {
+		if !query.matches(recipe) {
+			return true
+		}
+		if len(page) == limit {
+			more = true
+			return false
+		}
+		page = append(page, *recipe)
+		return true
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return page, more, nil
+}
+
+// Facets counts the tags, cuisines, difficulties and videos of every
+// document matching query
+func (rsc *RecipesRsc) Facets(query *RecipeQuery) (*RecipeFacets, error) {
+	facets := &RecipeFacets{
+		Tags:       map[string]int{},
+		Labels:     map[string]int{},
+		Cuisine:    map[string]int{},
+		Difficulty: map[string]int{},
+	}
+
+	// the order doesn't matter to counting
+	unsorted := *query
+	unsorted.Sort = SortID
+	err := rsc.walk(planRecipeQuery(&unsorted, rsc.hasIndex), nil, func(recipe *Recipe) bool {
+		if !query.matches(recipe) {
+			return true
+		}
+		for _, tag := range recipe.Tags {
+			facets.Tags[tag]++
+		}
+		for _, label := range recipe.Labels {
+			facets.Labels[label]++
+		}
+		if recipe.Cuisine != "" {
+			facets.Cuisine[recipe.Cuisine]++
+		}
+		if recipe.Difficulty != "" {
+			facets.Difficulty[recipe.Difficulty]++
+		}
+		if recipe.Video != "" {
+			facets.HasVideo++
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// walk calls fn with the documents of plan in index order, starting after
+// the document after unless it is nil, until fn returns false
+func (rsc *RecipesRsc) walk(plan recipePlan, after *Recipe, fn func(recipe *Recipe) bool) error {
+	first, next := store.IterGe, store.IterGt
+	if plan.desc {
+		first, next = store.IterLe, store.IterLt
+	}
+
+	iterator, key := first, plan.prefix
+	if key == nil {
+		key = []interface{}{}
+	}
+	if after != nil {
+		iterator, key = next, plan.key(after)
+	}
+	for {
+		var recipes []Recipe
+		err := rsc.db.Select(rsc.spaceName, plan.index, 0, scanBatchSize, iterator, key, &recipes)
+		if err != nil {
+			return wrapErr(err)
+		}
+		for i := range recipes {
+			if !plan.within(&recipes[i]) || !fn(&recipes[i]) {
+				return nil
+			}
+		}
+		if len(recipes) < scanBatchSize {
+			return nil
+		}
+		iterator, key = next, plan.key(&recipes[len(recipes)-1])
+	}
+}
+
+// within reports whether recipe has the prefix of the plan. Iterators run
+// past the prefix into the rest of the index, so reading stops at the first
+// document without it.
+func (plan recipePlan) within(recipe *Recipe) bool {
+	key := plan.key(recipe)
+	for i, v := range plan.prefix {
+		if key[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// hasIndex reports whether the recipes space has the index
+func (rsc *RecipesRsc) hasIndex(name string) bool {
+	return rsc.db.HasIndex(rsc.spaceName, name)
+}
+
+// CreateIndexes rewrites every document in the current tuple format, since
+// an index can't be built while some tuples lack its fields, then creates
+// the secondary indexes the query planner makes use of. It is safe to run
+// more than once, and rewrites nothing once every index exists. Servers only
+// start using the indexes once they reconnect.
+func (rsc *RecipesRsc) CreateIndexes() error {
+	missing := false
+	for _, index := range recipeIndexes {
+		missing = missing || !rsc.hasIndex(index.name)
+	}
+	if !missing {
+		return nil
+	}
+
+	err := rsc.scan(func(recipe *Recipe) error {
+		_, err := rsc.replace(recipe)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return wrapErr(rsc.db.CreateSpace(rsc.space()))
+}
+
+// CreateSpace creates the recipes space, its sequence and its primary
+// index. It is safe to run more than once.
+func (rsc *RecipesRsc) CreateSpace() error {
+	space := rsc.space()
+	space.Indexes = space.Indexes[:1]
+	return wrapErr(rsc.db.CreateSpace(space))
+}
+
+// space defines the recipes space with every index
+func (rsc *RecipesRsc) space() *store.Space {
+	indexes := []store.Index{idIndex}
+	for _, index := range recipeIndexes {
+		indexes = append(indexes, store.Index{Name: index.name, Parts: index.parts})
+	}
+	return &store.Space{
+		Name:     rsc.spaceName,
+		Sequence: rsc.sequenceName,
+		Indexes:  indexes,
+	}
+}
+
+// matches reports whether recipe passes every filter of the query. Recipes
+// in the trash never do.
+func (query *RecipeQuery) matches(recipe *Recipe) bool {
+	if recipe.DeletedAt != 0 {
+		return false
+	}
+	if query.Cuisine != "" && recipe.Cuisine != query.Cuisine {
+		return false
+	}
+	if query.Difficulty != "" && recipe.Difficulty != query.Difficulty {
+		return false
+	}
+	for _, tag := range query.Tags {
+		if !hasString(recipe.Tags, tag) {
+			return false
+		}
+	}
+	for _, label := range query.Labels {
+		if !hasString(recipe.Labels, label) {
+			return false
+		}
+	}
+	if query.MaxTotalTime > 0 {
+		total := recipe.PrepTime + recipe.CookTime
+		if total == 0 || total > query.MaxTotalTime {
+			return false
+		}
+	}
+	if query.HasVideo != nil && (recipe.Video != "") != *query.HasVideo {
+		return false
+	}
+	for _, word := range query.Includes {
+		if !hasIngredient(recipe, word) {
+			return false
+		}
+	}
+	for _, word := range query.Excludes {
+		if hasIngredient(recipe, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIngredient reports whether the name of any ingredient of recipe
+// contains word, ignoring case
+func hasIngredient(recipe *Recipe, word string) bool {
+	word = strings.ToLower(word)
+	for _, ingredient := range recipe.Ingredients {
+		if strings.Contains(strings.ToLower(ingredient.Name), word) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedPage sorts recipes and cuts the page following after out of them
+func sortedPage(recipes []Recipe, less func(a, b *Recipe) bool, after *Recipe, limit int) ([]Recipe, bool) {
+	sort.Slice(recipes, func(i, j int) bool {
+		return less(&recipes[i], &recipes[j])
+	})
+	if after != nil {
+		start := sort.Search(len(recipes), func(i int) bool {
+			return less(after, &recipes[i])
+		})
+		recipes = recipes[start:]
+	}
+	if len(recipes) > limit {
+		return recipes[:limit], true
+	}
+	return recipes, false
+}
@@ -0,0 +1,140 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanRecipeQuery(t *testing.T) {
+	type (
+		in struct {
+			query   RecipeQuery
+			indexes []string
+		}
+		out struct {
+			index   string
+			desc    bool
+			prefix  []interface{}
+			ordered bool
+		}
+	)
+
+	all := []string{"cuisine", "difficulty", "rating", "cook_time"}
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {
+			in{RecipeQuery{}, all},
+			out{"primary", false, nil, true},
+		},
+		"case-02": {
+			in{RecipeQuery{Sort: SortNewest, Cuisine: "thai", Difficulty: "easy"}, all},
+			out{"cuisine", true, []interface{}{"thai"}, true},
+		},
+		"case-03": {
+			in{RecipeQuery{Cuisine: "thai", Difficulty: "easy"}, []string{"difficulty"}},
+			out{"difficulty", false, []interface{}{"easy"}, true},
+		},
+		"case-04": {
+			in{RecipeQuery{Sort: SortRating, Cuisine: "thai"}, all},
+			out{"rating", true, nil, true},
+		},
+		// sorted in memory without the index of the sort order
+		"case-05": {
+			in{RecipeQuery{Sort: SortCookTime, Cuisine: "thai"}, nil},
+			out{"primary", false, nil, false},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			plan := planRecipeQuery(&in.query, func(name string) bool {
+				return hasString(in.indexes, name)
+			})
+			if plan.index != out.index || plan.desc != out.desc || plan.ordered != out.ordered {
+				t.Errorf("actual plan %+v, expected plan %+v", plan, out)
+			}
+			if !reflect.DeepEqual(plan.prefix, out.prefix) {
+				t.Errorf("actual prefix %v, expected prefix %v", plan.prefix, out.prefix)
+			}
+		})
+	}
+}
+
+func TestRecipeQueryMatches(t *testing.T) {
+	yes, no := true, false
+	recipe := &Recipe{
+		ID:          1,
+		Ingredients: []Ingredient{{Name: "Chicken thigh"}, {Name: "coconut milk"}},
+		Tags:        []string{"spicy", "quick"},
+		Labels:      []string{"gluten-free", "nut-free", "dairy-free"},
+		Cuisine:     "thai",
+		Difficulty:  "easy",
+		PrepTime:    600,
+		CookTime:    900,
+	}
+
+	type (
+		in struct {
+			query RecipeQuery
+		}
+		out struct {
+			matches bool
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{RecipeQuery{}}, out{true}},
+		"case-02": {in{RecipeQuery{Tags: []string{"quick", "spicy"}, Cuisine: "thai", Difficulty: "easy"}}, out{true}},
+		"case-03": {in{RecipeQuery{Tags: []string{"quick", "vegan"}}}, out{false}},
+		"case-04": {in{RecipeQuery{MaxTotalTime: 1500}}, out{true}},
+		"case-05": {in{RecipeQuery{MaxTotalTime: 1499}}, out{false}},
+		"case-06": {in{RecipeQuery{HasVideo: &no}}, out{true}},
+		"case-07": {in{RecipeQuery{HasVideo: &yes}}, out{false}},
+		"case-08": {in{RecipeQuery{Includes: []string{"chicken"}, Excludes: []string{"peanut"}}}, out{true}},
+		"case-09": {in{RecipeQuery{Excludes: []string{"milk"}}}, out{false}},
+		"case-10": {in{RecipeQuery{Labels: []string{"gluten-free", "dairy-free"}}}, out{true}},
+		"case-11": {in{RecipeQuery{Labels: []string{"gluten-free", "vegan"}}}, out{false}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			if matches := in.query.matches(recipe); matches != out.matches {
+				t.Errorf("actual matches %v, expected matches %v", matches, out.matches)
+			}
+		})
+	}
+}
+
+func TestSortedPage(t *testing.T) {
+	recipes := []Recipe{
+		{ID: 1, Rating: 4}, {ID: 2, Rating: 5}, {ID: 3, Rating: 4}, {ID: 4, Rating: 3},
+	}
+	less := recipeSorts[SortRating].less
+
+	page, more := sortedPage(recipes, less, nil, 2)
+	if ids := recipeIDs(page); !reflect.DeepEqual(ids, []uint{2, 3}) || !more {
+		t.Fatalf("actual first page %v %v", ids, more)
+	}
+	last := page[1]
+	page, more = sortedPage(recipes, less, &last, 2)
+	if ids := recipeIDs(page); !reflect.DeepEqual(ids, []uint{1, 4}) || more {
+		t.Errorf("actual second page %v %v", ids, more)
+	}
+}
+
+func recipeIDs(recipes []Recipe) []uint {
+	var ids []uint
+	for _, recipe := range recipes {
+		ids = append(ids, recipe.ID)
+	}
+	return ids
+}
@@ -0,0 +1,108 @@
+package resource
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+func TestMigrateHowto(t *testing.T) {
+	db := store.NewMemory()
+	if err := db.CreateSpace(&store.Space{Name: "recipes", Indexes: []store.Index{idIndex}}); err != nil {
+		t.Fatal(err)
+	}
+	// a whole batch of tuples too short to have a howto comes first
+	for ID := 1; ID <= scanBatchSize; ID++ {
+		if err := db.Insert("recipes", []interface{}{ID, "Toast"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Insert("recipes", []interface{}{scanBatchSize + 1, "Soup", "", "stir, serve"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rsc := NewRecipesRsc(db)
+	n, err := rsc.MigrateHowto()
+	if err != nil || n != 1 {
+		t.Fatalf("migrated %d recipes, %v", n, err)
+	}
+	recipe, err := rsc.getOne(scanBatchSize + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Step{{Text: "stir"}, {Text: "serve"}}; !reflect.DeepEqual(recipe.Howto, expected) {
+		t.Errorf("actual howto %v, expected howto %v", recipe.Howto, expected)
+	}
+}
+
+func TestOverrideLabels(t *testing.T) {
+	db := store.NewMemory()
+	if err := CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	rsc := NewRecipesRsc(db)
+	recipe, err := rsc.Insert(&Recipe{Title: "Toast", LabelOverrides: map[string]bool{"vegan": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an override read before another one was made
+	recipe.LabelOverrides = map[string]bool{"nut-free": false}
+	if _, err := rsc.OverrideLabels(int(recipe.ID), recipe, nil); !errors.Is(err, ErrConflict) {
+		t.Errorf("actual error %v, expected a conflict", err)
+	}
+	recipe.LabelOverrides = map[string]bool{"vegan": true, "nut-free": false}
+	updated, err := rsc.OverrideLabels(int(recipe.ID), recipe, map[string]bool{"vegan": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(updated.LabelOverrides, recipe.LabelOverrides) {
+		t.Errorf("actual overrides %v, expected overrides %v", updated.LabelOverrides, recipe.LabelOverrides)
+	}
+	if _, err := rsc.OverrideLabels(99, recipe, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("actual error %v, expected not found", err)
+	}
+}
+
+func TestWriteKeepsOverrides(t *testing.T) {
+	db := store.NewMemory()
+	if err := CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	rsc := NewRecipesRsc(db)
+	recipe, err := rsc.Insert(&Recipe{Title: "Toast"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an override made after the recipe was read
+	overridden := *recipe
+	overridden.LabelOverrides = map[string]bool{"vegan": true}
+	if _, err := rsc.OverrideLabels(int(recipe.ID), &overridden, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	recipe.Title = "Cheese toast"
+	if _, err := rsc.Replace(recipe, nil); !errors.Is(err, ErrConflict) {
+		t.Errorf("actual replace error %v, expected a conflict", err)
+	}
+	if _, err := rsc.Update(int(recipe.ID), recipe, []string{"title"}, nil); !errors.Is(err, ErrConflict) {
+		t.Errorf("actual update error %v, expected a conflict", err)
+	}
+	current, err := rsc.GetOne(int(recipe.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Title != "Toast" || !current.LabelOverrides["vegan"] {
+		t.Errorf("actual recipe %+v, expected the override alone", current)
+	}
+
+	recipe.LabelOverrides = overridden.LabelOverrides
+	if _, err := rsc.Replace(recipe, overridden.LabelOverrides); err != nil {
+		t.Errorf("actual replace error %v with the current overrides", err)
+	}
+	if _, err := rsc.Update(99, recipe, []string{"title"}, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("actual error %v, expected not found", err)
+	}
+}
@@ -0,0 +1,182 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// ShoppingListsRscInterface is an interface to test ShoppingListsRsc
+type ShoppingListsRscInterface interface {
+	GetOne(ID int) (*ShoppingList, error)
+	GetPage(after uint, limit int) ([]ShoppingList, bool, error)
+	Insert(list *ShoppingList) (*ShoppingList, error)
+	Replace(list *ShoppingList) (*ShoppingList, error)
+	UpdateItems(ID int, items, previous []ShoppingItem) (*ShoppingList, error)
+	Delete(ID int) error
+}
+
+// ShoppingListsRsc provides api to manipulate shopping lists in the store
+type ShoppingListsRsc struct {
+	db           store.Store
+	spaceName    string
+	sequenceName string
+}
+
+// ShoppingList is what to buy to cook a set of recipes
+type ShoppingList struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	// Recipes are the recipes the list was made for
+	Recipes []ShoppingRecipe `json:"recipes"`
+	// Items are sorted by aisle, and keep their position for the life of
+	// the list
+	Items []ShoppingItem `json:"items"`
+	// CreatedAt is the unix time the list was made
+	CreatedAt int64 `json:"created_at"`
+}
+
+// ShoppingRecipe is a recipe of a shopping list and the servings to shop
+// for, 0 for the servings of the recipe
+type ShoppingRecipe struct {
+	RecipeID uint `json:"recipe_id"`
+	Servings int  `json:"servings"`
+}
+
+// ShoppingItem is an ingredient to buy, summed up over the recipes which
+// need it
+type ShoppingItem struct {
+	Name     string   `json:"name"`
+	Quantity Quantity `json:"quantity"`
+	Unit     string   `json:"unit,omitempty"`
+	Aisle    string   `json:"aisle"`
+	Checked  bool     `json:"checked"`
+	// Recipes are the IDs of the recipes which need the item
+	Recipes []uint `json:"recipes"`
+}
+
+// NewShoppingListsRsc initiates ShoppingListsRsc
+func NewShoppingListsRsc(db store.Store) *ShoppingListsRsc {
+	return &ShoppingListsRsc{
+		db:           db,
+		spaceName:    "shopping_lists",
+		sequenceName: "shopping_lists_id",
+	}
+}
+
+// GetOne finds the shopping list with ID
+func (rsc *ShoppingListsRsc) GetOne(ID int) (*ShoppingList, error) {
+	var lists []ShoppingList
+	err := rsc.db.Select(rsc.spaceName, "primary", 0, 1, store.IterEq, []interface{}{ID}, &lists)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(lists) == 0 {
+		return nil, NotFound("shopping list %d doesn't exist", ID)
+	}
+
+	return &lists[0], nil
+}
+
+// GetPage finds up to limit shopping lists whose ID is greater than after,
+// in ID order. It also reports whether more lists follow.
+func (rsc *ShoppingListsRsc) GetPage(after uint, limit int) ([]ShoppingList, bool, error) {
+	var lists []ShoppingList
+	err := rsc.db.Select(rsc.spaceName, "primary", 0, uint32(limit+1), store.IterGt, []interface{}{after}, &lists)
+	if err != nil {
+		return nil, false, wrapErr(err)
+	}
+	if len(lists) > limit {
+		return lists[:limit], true, nil
+	}
+
+	return lists, false, nil
+}
+
+// Insert stores list as a new document with an ID from the sequence
+func (rsc *ShoppingListsRsc) Insert(list *ShoppingList) (*ShoppingList, error) {
+	ID, err := nextID(rsc.db, rsc.sequenceName)
+	if err != nil {
+		return nil, err
+	}
+
+	tuple := *list
+	tuple.ID = ID
+	var lists []ShoppingList
+	err = rsc.db.Insert(rsc.spaceName, tuple, &lists)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(lists) == 0 {
+		return nil, fmt.Errorf("insert into %s returned no tuple", rsc.spaceName)
+	}
+
+	return &lists[0], nil
+}
+
+// Replace overwrites the existing list with the ID of list
+func (rsc *ShoppingListsRsc) Replace(list *ShoppingList) (*ShoppingList, error) {
+	if _, err := rsc.GetOne(int(list.ID)); err != nil {
+		return nil, err
+	}
+
+	var lists []ShoppingList
+	err := rsc.db.Replace(rsc.spaceName, *list, &lists)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(lists) == 0 {
+		return nil, fmt.Errorf("replace on %s returned no tuple", rsc.spaceName)
+	}
+
+	return &lists[0], nil
+}
+
+// UpdateItems writes items to the shopping list with ID, only while its
+// items are still previous, so that an item checked in between isn't lost.
+// It returns an ErrConflict error when the items changed.
+func (rsc *ShoppingListsRsc) UpdateItems(ID int, items, previous []ShoppingItem) (*ShoppingList, error) {
+	var lists []ShoppingList
+	ops := []interface{}{[]interface{}{"=", shoppingListFieldItems, items}}
+	err := store.UpdateIf(rsc.db, rsc.spaceName, "primary", []interface{}{ID}, shoppingListFieldItems, previous, ops, &lists)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if len(lists) == 0 {
+		if _, err := rsc.GetOne(ID); err != nil {
+			return nil, err
+		}
+		return nil, Conflict("items of shopping list %d changed", ID)
+	}
+
+	return &lists[0], nil
+}
+
+// Delete deletes the shopping list with ID
+func (rsc *ShoppingListsRsc) Delete(ID int) error {
+	var lists []ShoppingList
+	err := rsc.db.Delete(rsc.spaceName, "primary", []interface{}{ID}, &lists)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if len(lists) == 0 {
+		return NotFound("shopping list %d doesn't exist", ID)
+	}
+
+	return nil
+}
+
+// CreateSpace creates the space of shopping lists and its sequence. It is
+// safe to run more than once.
+func (rsc *ShoppingListsRsc) CreateSpace() error {
+	return wrapErr(rsc.db.CreateSpace(rsc.space()))
+}
+
+// space defines the space of shopping lists
+func (rsc *ShoppingListsRsc) space() *store.Space {
+	return &store.Space{
+		Name:     rsc.spaceName,
+		Sequence: rsc.sequenceName,
+		Indexes:  []store.Index{idIndex},
+	}
+}
@@ -0,0 +1,154 @@
+package resource
+
+import (
+	"fmt"
+	"reflect"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+// Shopping lists are stored as
+// [id, name, [[recipe_id, servings]...], [item...], created_at]
+// with items as [name, num, den, unit, aisle, checked, [recipe_id...]]
+
+// shoppingListFieldItems is the field number of the items
+const shoppingListFieldItems = 3
+
+func init() {
+	msgpack.Register(reflect.TypeOf(ShoppingList{}), encodeShoppingList, decodeShoppingList)
+	msgpack.Register(reflect.TypeOf(ShoppingRecipe{}), encodeShoppingRecipe, decodeShoppingRecipe)
+	msgpack.Register(reflect.TypeOf(ShoppingItem{}), encodeShoppingItem, decodeShoppingItem)
+}
+
+func encodeShoppingList(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(ShoppingList)
+	if err := e.EncodeSliceLen(5); err != nil {
+		return err
+	}
+	if err := e.EncodeUint(m.ID); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Name); err != nil {
+		return err
+	}
+	if err := e.Encode(m.Recipes); err != nil {
+		return err
+	}
+	if err := e.Encode(m.Items); err != nil {
+		return err
+	}
+	return e.EncodeInt64(m.CreatedAt)
+}
+
+func decodeShoppingList(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*ShoppingList)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != 5 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+	*m = ShoppingList{}
+	if m.ID, err = d.DecodeUint(); err != nil {
+		return err
+	}
+	if m.Name, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if err = d.Decode(&m.Recipes); err != nil {
+		return err
+	}
+	if err = d.Decode(&m.Items); err != nil {
+		return err
+	}
+	m.CreatedAt, err = d.DecodeInt64()
+	return err
+}
+
+func encodeShoppingRecipe(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(ShoppingRecipe)
+	if err := e.EncodeSliceLen(2); err != nil {
+		return err
+	}
+	if err := e.EncodeUint(m.RecipeID); err != nil {
+		return err
+	}
+	return e.EncodeInt(m.Servings)
+}
+
+func decodeShoppingRecipe(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*ShoppingRecipe)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != 2 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+	if m.RecipeID, err = d.DecodeUint(); err != nil {
+		return err
+	}
+	m.Servings, err = d.DecodeInt()
+	return err
+}
+
+func encodeShoppingItem(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(ShoppingItem)
+	if err := e.EncodeSliceLen(7); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Name); err != nil {
+		return err
+	}
+	if err := e.EncodeInt64(m.Quantity.Num); err != nil {
+		return err
+	}
+	if err := e.EncodeInt64(m.Quantity.Den); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Unit); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Aisle); err != nil {
+		return err
+	}
+	if err := e.EncodeBool(m.Checked); err != nil {
+		return err
+	}
+	return e.Encode(m.Recipes)
+}
+
+func decodeShoppingItem(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*ShoppingItem)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != 7 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+	*m = ShoppingItem{}
+	if m.Name, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Quantity.Num, err = d.DecodeInt64(); err != nil {
+		return err
+	}
+	if m.Quantity.Den, err = d.DecodeInt64(); err != nil {
+		return err
+	}
+	if m.Unit, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Aisle, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Checked, err = d.DecodeBool(); err != nil {
+		return err
+	}
+	return d.Decode(&m.Recipes)
+}
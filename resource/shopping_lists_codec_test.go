@@ -0,0 +1,33 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+func TestShoppingListCodec(t *testing.T) {
+	list := ShoppingList{
+		ID:      7,
+		Name:    "Weekend",
+		Recipes: []ShoppingRecipe{{RecipeID: 1, Servings: 4}, {RecipeID: 2}},
+		Items: []ShoppingItem{
+			{Name: "flour", Quantity: NewQuantity(3, 2), Unit: "cup", Aisle: "baking", Recipes: []uint{1, 2}},
+			{Name: "salt", Aisle: "spices & seasonings", Checked: true, Recipes: []uint{2}},
+		},
+		CreatedAt: 1700000000,
+	}
+
+	b, err := msgpack.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ShoppingList
+	if err := msgpack.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, list) {
+		t.Errorf("actual list %+v, expected list %+v", decoded, list)
+	}
+}
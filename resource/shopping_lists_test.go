@@ -0,0 +1,43 @@
+package resource
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+func TestUpdateItems(t *testing.T) {
+	db := store.NewMemory()
+	if err := CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	rsc := NewShoppingListsRsc(db)
+	list, err := rsc.Insert(&ShoppingList{Name: "Week", Items: []ShoppingItem{
+		{Name: "flour", Quantity: NewQuantity(2, 1), Unit: "cup", Aisle: "baking", Recipes: []uint{1}},
+		{Name: "egg", Quantity: NewQuantity(3, 1), Aisle: "dairy", Recipes: []uint{1, 2}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := append([]ShoppingItem(nil), list.Items...)
+	items[0].Checked = true
+	updated, err := rsc.UpdateItems(int(list.ID), items, list.Items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Items[0].Checked {
+		t.Errorf("actual items %+v, expected the first one checked", updated.Items)
+	}
+
+	// items read before the first one was checked
+	items = append([]ShoppingItem(nil), list.Items...)
+	items[1].Checked = true
+	if _, err := rsc.UpdateItems(int(list.ID), items, list.Items); !errors.Is(err, ErrConflict) {
+		t.Errorf("actual error %v, expected a conflict", err)
+	}
+	if _, err := rsc.UpdateItems(99, items, list.Items); !errors.Is(err, ErrNotFound) {
+		t.Errorf("actual error %v, expected not found", err)
+	}
+}
@@ -0,0 +1,45 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// CreateSpaces creates every space resources are kept in and their
+// indexes, and rewrites stored recipes in the current format. It is safe to
+// run more than once. It sets up the embedded stores; the schema of
+// tarantool is made by the migrations of package migrate instead.
+func CreateSpaces(db store.Store) error {
+	recipes := NewRecipesRsc(db)
+	steps := []struct {
+		name   string
+		create func() error
+	}{
+		{"recipes space", recipes.CreateSpace},
+		{"recipe indexes", recipes.CreateIndexes},
+		{"nutrition space", NewNutritionRsc(db).CreateSpace},
+		{"label audit space", NewLabelAuditRsc(db).CreateSpace},
+		{"shopping lists space", NewShoppingListsRsc(db).CreateSpace},
+		{"meal plans space", NewMealPlansRsc(db).CreateSpace},
+		{"pantry space", NewPantryRsc(db).CreateSpace},
+	}
+	for _, step := range steps {
+		if err := step.create(); err != nil {
+			return fmt.Errorf("%s: %s", step.name, err)
+		}
+	}
+	return nil
+}
+
+// Spaces defines every space resources are kept in, with their indexes
+func Spaces() []*store.Space {
+	return []*store.Space{
+		NewRecipesRsc(nil).space(),
+		NewNutritionRsc(nil).space(),
+		NewLabelAuditRsc(nil).space(),
+		NewShoppingListsRsc(nil).space(),
+		NewMealPlansRsc(nil).space(),
+		NewPantryRsc(nil).space(),
+	}
+}
@@ -0,0 +1,123 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+	"gopkg.in/vmihailenco/msgpack.v2/codes"
+)
+
+// Step is one instruction of the howto of a recipe
+type Step struct {
+	Text string `json:"text"`
+	// Duration is how long the step takes, in seconds
+	Duration    int          `json:"duration,omitempty"`
+	Temperature *Temperature `json:"temperature,omitempty"`
+	// Ingredients are indexes into the ingredients of the recipe used in
+	// this step
+	Ingredients []int  `json:"ingredients,omitempty"`
+	Photo       string `json:"photo,omitempty"`
+}
+
+// Temperature is an oven or cooking temperature
+type Temperature struct {
+	Value float64 `json:"value"`
+	// Unit is "C" or "F"
+	Unit string `json:"unit"`
+}
+
+// step is Step without its json methods
+type step Step
+
+func init() {
+	msgpack.Register(reflect.TypeOf(Step{}), encodeStep, decodeStep)
+}
+
+// UnmarshalJSON reads a step object, or a plain string as the text of the
+// step like howto was written before steps were structured
+func (s *Step) UnmarshalJSON(b []byte) error {
+	var text string
+	if err := json.Unmarshal(b, &text); err == nil {
+		*s = Step{Text: text}
+		return nil
+	}
+	var v step
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*s = Step(v)
+	return nil
+}
+
+func encodeStep(e *msgpack.Encoder, v reflect.Value) error {
+	m := v.Interface().(Step)
+	if err := e.EncodeSliceLen(6); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Text); err != nil {
+		return err
+	}
+	if err := e.EncodeInt(m.Duration); err != nil {
+		return err
+	}
+	var temperature Temperature
+	if m.Temperature != nil {
+		temperature = *m.Temperature
+	}
+	if err := e.EncodeFloat64(temperature.Value); err != nil {
+		return err
+	}
+	if err := e.EncodeString(temperature.Unit); err != nil {
+		return err
+	}
+	if err := e.Encode(m.Ingredients); err != nil {
+		return err
+	}
+	if err := e.EncodeString(m.Photo); err != nil {
+		return err
+	}
+	return nil
+}
+
+func decodeStep(d *msgpack.Decoder, v reflect.Value) error {
+	var err error
+	var l int
+	m := v.Addr().Interface().(*Step)
+	if l, err = d.DecodeSliceLen(); err != nil {
+		return err
+	}
+	if l != 6 {
+		return fmt.Errorf("array len doesn't match: %d", l)
+	}
+	if m.Text, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if m.Duration, err = d.DecodeInt(); err != nil {
+		return err
+	}
+	var temperature Temperature
+	if temperature.Value, err = d.DecodeFloat64(); err != nil {
+		return err
+	}
+	if temperature.Unit, err = d.DecodeString(); err != nil {
+		return err
+	}
+	m.Temperature = nil
+	if temperature.Unit != "" {
+		m.Temperature = &temperature
+	}
+	m.Ingredients = nil
+	if err = d.Decode(&m.Ingredients); err != nil {
+		return err
+	}
+	if m.Photo, err = d.DecodeString(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func isStringCode(c byte) bool {
+	return codes.IsFixedString(c) || c == codes.Str8 || c == codes.Str16 || c == codes.Str32
+}
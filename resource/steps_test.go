@@ -0,0 +1,49 @@
+package resource
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+func TestDecodeLegacyHowto(t *testing.T) {
+	b, _ := msgpack.Marshal([]interface{}{uint(3), "Soup", "", "stir, then simmer,serve", ""})
+
+	var recipe Recipe
+	if err := msgpack.Unmarshal(b, &recipe); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Step{{Text: "stir"}, {Text: "then simmer"}, {Text: "serve"}}
+	if !reflect.DeepEqual(recipe.Howto, expected) {
+		t.Errorf("actual howto %v, expected howto %v", recipe.Howto, expected)
+	}
+}
+
+func TestStepJSON(t *testing.T) {
+	var howto []Step
+	err := json.Unmarshal([]byte(`["stir, then simmer",{"text":"bake","duration":1800,"temperature":{"value":180,"unit":"C"}}]`), &howto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Step{
+		{Text: "stir, then simmer"},
+		{Text: "bake", Duration: 1800, Temperature: &Temperature{180, "C"}},
+	}
+	if !reflect.DeepEqual(howto, expected) {
+		t.Errorf("actual howto %v, expected howto %v", howto, expected)
+	}
+
+	b, err := msgpack.Marshal(howto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Step
+	if err := msgpack.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("actual steps %v, expected steps %v", decoded, expected)
+	}
+}
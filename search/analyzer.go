@@ -0,0 +1,118 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Token is a term produced by an analyzer, with the byte offsets of the text
+// it was produced from
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Analyzer turns text into the terms which are indexed and searched
+type Analyzer interface {
+	Analyze(text string) []Token
+}
+
+// Analyzers are the analyzers which can be selected for a field by name
+var Analyzers = map[string]Analyzer{
+	"english": EnglishAnalyzer{},
+	"cjk":     CJKAnalyzer{Other: EnglishAnalyzer{}},
+}
+
+// WithAnalyzers returns a copy of fields with analyzers replaced as listed
+// in spec, a comma separated list like "title=cjk,steps=english"
+func WithAnalyzers(fields []Field, spec string) ([]Field, error) {
+	selected := map[string]Analyzer{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid analyzer selection %q", item)
+		}
+		analyzer, ok := Analyzers[kv[1]]
+		if !ok {
+			return nil, fmt.Errorf("unknown analyzer %q", kv[1])
+		}
+		selected[kv[0]] = analyzer
+	}
+
+	result := make([]Field, len(fields))
+	for i, field := range fields {
+		if analyzer, ok := selected[field.Name]; ok {
+			field.Analyzer = analyzer
+			delete(selected, field.Name)
+		}
+		result[i] = field
+	}
+	for name := range selected {
+		return nil, fmt.Errorf("unknown field %q", name)
+	}
+	return result, nil
+}
+
+// EnglishAnalyzer splits text into words, lower cases them, drops stop
+// words and reduces the rest to their Porter stem
+type EnglishAnalyzer struct{}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "no": true, "not": true, "of": true,
+	"on": true, "or": true, "such": true, "that": true, "the": true,
+	"their": true, "then": true, "there": true, "these": true, "they": true,
+	"this": true, "to": true, "was": true, "will": true, "with": true,
+}
+
+// Analyze implements Analyzer
+func (EnglishAnalyzer) Analyze(text string) []Token {
+	var tokens []Token
+	for _, word := range words(text) {
+		term := strings.ToLower(text[word.Start:word.End])
+		if stopWords[term] {
+			continue
+		}
+		word.Term = stem(term)
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// words splits text into runs of letters and digits
+func words(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			tokens = append(tokens, Token{Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, Token{Start: start, End: len(text)})
+	}
+	return tokens
+}
+
+// isASCIIWord reports whether s only has ASCII lower case letters, the only
+// words the stemmer knows about
+func isASCIIWord(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'a' || s[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package search
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// CJKAnalyzer indexes Chinese, Japanese and Korean text, which isn't
+// separated by spaces, as overlapping character bigrams. Text is normalized
+// first: full-width ASCII and half-width katakana take their usual width,
+// and katakana is folded into hiragana, so that 「ニクジャガ」, 「ﾆｸｼﾞｬｶﾞ」
+// and 「にくじゃが」 all meet. Runs of other scripts in the same text are
+// handed to Other, which makes mixed-script queries work.
+type CJKAnalyzer struct {
+	Other Analyzer
+}
+
+// normRune is a normalized rune with the byte offsets of the text it was
+// normalized from
+type normRune struct {
+	r          rune
+	start, end int
+}
+
+// Analyze implements Analyzer
+func (a CJKAnalyzer) Analyze(text string) []Token {
+	chars := normalize(text)
+
+	var tokens []Token
+	for i := 0; i < len(chars); {
+		j := i
+		cjk := isCJK(chars[i].r)
+		for j < len(chars) && isCJK(chars[j].r) == cjk {
+			j++
+		}
+		if cjk {
+			tokens = append(tokens, bigrams(chars[i:j])...)
+		} else if a.Other != nil {
+			tokens = append(tokens, a.analyzeOther(chars[i:j])...)
+		}
+		i = j
+	}
+	return tokens
+}
+
+func bigrams(chars []normRune) []Token {
+	if len(chars) == 1 {
+		return []Token{{string(chars[0].r), chars[0].start, chars[0].end}}
+	}
+	tokens := make([]Token, 0, len(chars)-1)
+	for i := 0; i+1 < len(chars); i++ {
+		tokens = append(tokens, Token{
+			Term:  string([]rune{chars[i].r, chars[i+1].r}),
+			Start: chars[i].start,
+			End:   chars[i+1].end,
+		})
+	}
+	return tokens
+}
+
+// analyzeOther runs Other over the normalized text of chars and maps the
+// offsets of its tokens back to the original text
+func (a CJKAnalyzer) analyzeOther(chars []normRune) []Token {
+	var buf []byte
+	var offsets []int
+	for _, c := range chars {
+		n := utf8.RuneLen(c.r)
+		buf = append(buf, string(c.r)...)
+		for k := 0; k < n; k++ {
+			offsets = append(offsets, c.start)
+		}
+	}
+	offsets = append(offsets, chars[len(chars)-1].end)
+
+	tokens := a.Other.Analyze(string(buf))
+	for i := range tokens {
+		tokens[i].Start = offsets[tokens[i].Start]
+		tokens[i].End = offsets[tokens[i].End]
+	}
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	switch r {
+	case 'ー', '々', '〆':
+		return true
+	}
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// normalize folds the width and kana variants of text, keeping the offsets
+// of each rune in text
+func normalize(text string) []normRune {
+	chars := make([]normRune, 0, len(text))
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		c := normRune{r, i, i + n}
+		i += n
+
+		switch {
+		case r == '　':
+			c.r = ' '
+		case r >= '！' && r <= '～':
+			c.r = r - 0xfee0
+		case r >= '｡' && r <= 'ﾟ':
+			c.r = halfwidthKana[r-0xff61]
+			// a half-width voiced sound mark is a rune of its own
+			if i < len(text) {
+				next, m := utf8.DecodeRuneInString(text[i:])
+				if voiced, ok := voice(c.r, next); ok {
+					c.r = voiced
+					c.end += m
+					i += m
+				}
+			}
+		}
+		if c.r >= 'ァ' && c.r <= 'ヴ' {
+			c.r -= 0x60
+		}
+		c.r = unicode.ToLower(c.r)
+		chars = append(chars, c)
+	}
+	return chars
+}
+
+// halfwidthKana maps U+FF61 to U+FF9F to their full-width forms
+var halfwidthKana = []rune("。「」、・ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン゛゜")
+
+// voice combines a full-width katakana with a following half-width voiced
+// (ﾞ) or semi-voiced (ﾟ) sound mark
+func voice(kana, mark rune) (rune, bool) {
+	switch mark {
+	case 'ﾞ':
+		switch {
+		case kana == 'ウ':
+			return 'ヴ', true
+		case kana >= 'カ' && kana <= 'ト' && kana != 'ッ' && (kana < 'ツ' && (kana-'カ')%2 == 0 || kana >= 'ツ' && (kana-'ツ')%2 == 0):
+			return kana + 1, true
+		case kana >= 'ハ' && kana <= 'ホ' && (kana-'ハ')%3 == 0:
+			return kana + 1, true
+		}
+	case 'ﾟ':
+		if kana >= 'ハ' && kana <= 'ホ' && (kana-'ハ')%3 == 0 {
+			return kana + 2, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCJKAnalyzer(t *testing.T) {
+	type (
+		in struct {
+			text string
+		}
+		out struct {
+			tokens []Token
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {
+			in{"肉じゃが"},
+			out{[]Token{{"肉じ", 0, 6}, {"じゃ", 3, 9}, {"ゃが", 6, 12}}},
+		},
+		// katakana folds into hiragana
+		"case-02": {
+			in{"カレー"},
+			out{[]Token{{"かれ", 0, 6}, {"れー", 3, 9}}},
+		},
+		// half-width katakana with voiced sound marks
+		"case-03": {
+			in{"ｼﾞｬｶﾞ"},
+			out{[]Token{{"じゃ", 0, 9}, {"ゃが", 6, 15}}},
+		},
+		// full-width ASCII and mixed scripts
+		"case-04": {
+			in{"ＣＵＲＲＹ鍋 onions"},
+			out{[]Token{{"curri", 0, 15}, {"鍋", 15, 18}, {"onion", 19, 25}}},
+		},
+	}
+
+	analyzer := Analyzers["cjk"]
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			if tokens := analyzer.Analyze(in.text); !reflect.DeepEqual(tokens, out.tokens) {
+				t.Errorf("actual tokens %v, expected tokens %v", tokens, out.tokens)
+			}
+		})
+	}
+}
+
+func TestCJKSearch(t *testing.T) {
+	idx := NewMemoryIndex(Field{Name: "title", Weight: 1, Analyzer: Analyzers["cjk"]})
+	idx.Index(Document{ID: 1, Fields: map[string]string{"title": "ニクジャガ"}})
+	idx.Index(Document{ID: 2, Fields: map[string]string{"title": "肉じゃがコロッケ"}})
+	idx.Index(Document{ID: 3, Fields: map[string]string{"title": "Beef curry カレー"}})
+
+	hits, _ := idx.Search("肉じゃが", 10)
+	if len(hits) == 0 || hits[0].ID != 2 {
+		t.Fatalf("actual hits %v", hits)
+	}
+	if s := hits[0].Snippets["title"]; s != "<mark>肉じゃが</mark>コロッケ" {
+		t.Errorf("actual snippet %s", s)
+	}
+
+	hits, _ = idx.Search("にくじゃが", 10)
+	if len(hits) == 0 || hits[0].ID != 1 {
+		t.Errorf("actual hits %v", hits)
+	}
+
+	hits, _ = idx.Search("かれー beef", 10)
+	if len(hits) != 1 || hits[0].ID != 3 {
+		t.Errorf("actual hits %v", hits)
+	}
+}
@@ -0,0 +1,95 @@
+package search
+
+import "strings"
+
+// Keywords finds known words and phrases in short texts like ingredient
+// names. Keywords in Latin script match whole words after stemming, so that
+// "egg" is found in "eggs" but not in "eggplant"; CJK keywords, whose words
+// aren't separated, match anywhere in the folded text. When keywords
+// overlap, like "butter" and "peanut butter", only the longest counts.
+type Keywords struct {
+	keywords []keyword
+}
+
+// keyword is a keyword with its analyzed terms, or its folded text for CJK
+// keywords
+type keyword struct {
+	text   string
+	terms  map[string]bool
+	folded string
+}
+
+var keywordAnalyzer = Analyzers["cjk"]
+
+// Add adds keywords to look for
+func (k *Keywords) Add(texts ...string) {
+	for _, text := range texts {
+		kw := keyword{text: text}
+		if strings.IndexFunc(text, isCJK) >= 0 {
+			kw.folded = Fold(text)
+		} else {
+			kw.terms = termSet(text)
+		}
+		k.keywords = append(k.keywords, kw)
+	}
+}
+
+func termSet(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, token := range keywordAnalyzer.Analyze(text) {
+		set[token.Term] = true
+	}
+	return set
+}
+
+// Find returns the keywords in text, in the order they were added,
+// leaving out those overlapped by a longer one
+func (k *Keywords) Find(text string) []string {
+	terms, folded := termSet(text), Fold(text)
+	var found []*keyword
+	for i := range k.keywords {
+		if k.keywords[i].in(terms, folded) {
+			found = append(found, &k.keywords[i])
+		}
+	}
+
+	var texts []string
+	for _, kw := range found {
+		shadowed := false
+		for _, other := range found {
+			if other.text != kw.text && kw.in(other.terms, Fold(other.text)) {
+				shadowed = true
+				break
+			}
+		}
+		if !shadowed && !containsText(texts, kw.text) {
+			texts = append(texts, kw.text)
+		}
+	}
+	return texts
+}
+
+// in reports whether kw is found in a text with terms and folded text
+func (kw *keyword) in(terms map[string]bool, folded string) bool {
+	if kw.terms == nil {
+		return strings.Contains(folded, kw.folded)
+	}
+	if len(kw.terms) == 0 {
+		return false
+	}
+	for term := range kw.terms {
+		if !terms[term] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsText(texts []string, text string) bool {
+	for _, t := range texts {
+		if t == text {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// snippetRadius is how many bytes of context a snippet keeps around the
+// first match
+const snippetRadius = 60
+
+// MemoryIndex is an Index kept in process memory. It ranks documents with
+// BM25 over the configured fields.
+type MemoryIndex struct {
+	mu     sync.RWMutex
+	fields []Field
+	docs   map[uint]*memoryDoc
+	// postings maps field, then term, to the term frequency per document
+	postings map[string]map[string]map[uint]int
+	// totalLen is the number of tokens of each field over all documents
+	totalLen map[string]int
+}
+
+type memoryDoc struct {
+	fields  map[string]string
+	lengths map[string]int
+	terms   map[string][]string
+}
+
+// NewMemoryIndex initiates MemoryIndex for documents with fields
+func NewMemoryIndex(fields ...Field) *MemoryIndex {
+	return &MemoryIndex{
+		fields:   fields,
+		docs:     map[uint]*memoryDoc{},
+		postings: map[string]map[string]map[uint]int{},
+		totalLen: map[string]int{},
+	}
+}
+
+// Index implements Index
+func (idx *MemoryIndex) Index(doc Document) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.delete(doc.ID)
+
+	d := &memoryDoc{
+		fields:  map[string]string{},
+		lengths: map[string]int{},
+		terms:   map[string][]string{},
+	}
+	for _, field := range idx.fields {
+		text := doc.Fields[field.Name]
+		tokens := field.Analyzer.Analyze(text)
+		d.fields[field.Name] = text
+		d.lengths[field.Name] = len(tokens)
+		idx.totalLen[field.Name] += len(tokens)
+
+		postings := idx.postings[field.Name]
+		if postings == nil {
+			postings = map[string]map[uint]int{}
+			idx.postings[field.Name] = postings
+		}
+		for _, token := range tokens {
+			if postings[token.Term] == nil {
+				postings[token.Term] = map[uint]int{}
+			}
+			if postings[token.Term][doc.ID] == 0 {
+				d.terms[field.Name] = append(d.terms[field.Name], token.Term)
+			}
+			postings[token.Term][doc.ID]++
+		}
+	}
+	idx.docs[doc.ID] = d
+	return nil
+}
+
+// Delete implements Index
+func (idx *MemoryIndex) Delete(ID uint) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.delete(ID)
+	return nil
+}
+
+// IDs implements Index
+func (idx *MemoryIndex) IDs() ([]uint, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	IDs := make([]uint, 0, len(idx.docs))
+	for ID := range idx.docs {
+		IDs = append(IDs, ID)
+	}
+	return IDs, nil
+}
+
+func (idx *MemoryIndex) delete(ID uint) {
+	d, ok := idx.docs[ID]
+	if !ok {
+		return
+	}
+	for field, terms := range d.terms {
+		for _, term := range terms {
+			delete(idx.postings[field][term], ID)
+			if len(idx.postings[field][term]) == 0 {
+				delete(idx.postings[field], term)
+			}
+		}
+	}
+	for field, l := range d.lengths {
+		idx.totalLen[field] -= l
+	}
+	delete(idx.docs, ID)
+}
+
+// Search implements Index. Documents match when they contain any of the
+// query terms, and are ranked by their BM25 score scaled by the share of the
+// query they match.
+func (idx *MemoryIndex) Search(query string, limit int) ([]Hit, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := float64(len(idx.docs))
+	scores := map[uint]float64{}
+	// matched records which query tokens, by offset, each document matches
+	matched := map[uint]map[int]bool{}
+	queryTokens := map[int]bool{}
+	queryTerms := map[string]map[string]bool{}
+
+	for _, field := range idx.fields {
+		queryTerms[field.Name] = map[string]bool{}
+		avgLen := 1.0
+		if len(idx.docs) > 0 && idx.totalLen[field.Name] > 0 {
+			avgLen = float64(idx.totalLen[field.Name]) / n
+		}
+		for _, token := range field.Analyzer.Analyze(query) {
+			queryTokens[token.Start] = true
+			queryTerms[field.Name][token.Term] = true
+
+			postings := idx.postings[field.Name][token.Term]
+			df := float64(len(postings))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for ID, tf := range postings {
+				dl := float64(idx.docs[ID].lengths[field.Name])
+				f := float64(tf)
+				scores[ID] += field.Weight * idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*dl/avgLen))
+				if matched[ID] == nil {
+					matched[ID] = map[int]bool{}
+				}
+				matched[ID][token.Start] = true
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for ID, score := range scores {
+		coverage := float64(len(matched[ID])) / float64(len(queryTokens))
+		hits = append(hits, Hit{ID: ID, Score: score * coverage})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if limit >= 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	for i := range hits {
+		hits[i].Snippets = map[string]string{}
+		d := idx.docs[hits[i].ID]
+		for _, field := range idx.fields {
+			if s, ok := snippet(d.fields[field.Name], field.Analyzer, queryTerms[field.Name]); ok {
+				hits[i].Snippets[field.Name] = s
+			}
+		}
+	}
+	return hits, nil
+}
+
+// snippet cuts the text around the first token matching terms and marks the
+// matching tokens in it
+func snippet(text string, analyzer Analyzer, terms map[string]bool) (string, bool) {
+	var matches []Token
+	for _, token := range analyzer.Analyze(text) {
+		if terms[token.Term] {
+			matches = append(matches, token)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start := runeStart(text, matches[0].Start-snippetRadius)
+	end := runeStart(text, matches[0].End+snippetRadius)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m.Start < pos {
+			// overlapping matches, as n-grams produce, extend the mark
+			// already written
+			continue
+		}
+		if m.End > end {
+			break
+		}
+		b.WriteString(html.EscapeString(text[pos:m.Start]))
+		mEnd := m.End
+		for _, next := range matches {
+			if next.Start >= m.Start && next.Start < mEnd && next.End > mEnd && next.End <= end {
+				mEnd = next.End
+			}
+		}
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[m.Start:mEnd]))
+		b.WriteString("</mark>")
+		pos = mEnd
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// runeStart clamps i into text and moves it back to the start of a rune
+func runeStart(text string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	if i >= len(text) {
+		return len(text)
+	}
+	for i > 0 && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
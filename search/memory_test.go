@@ -0,0 +1,90 @@
+package search
+
+import (
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := map[string]struct {
+		in, out string
+	}{
+		"case-01": {"baking", "bake"},
+		"case-02": {"baked", "bake"},
+		"case-03": {"bake", "bake"},
+		"case-04": {"tomatoes", "tomato"},
+		"case-05": {"chopped", "chop"},
+		"case-06": {"caresses", "caress"},
+		"case-07": {"ponies", "poni"},
+		"case-08": {"relational", "relat"},
+		"case-09": {"generalization", "gener"},
+		"case-10": {"hopping", "hop"},
+		"case-11": {"filing", "file"},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			if out := stem(test.in); out != test.out {
+				t.Errorf("actual stem %s, expected stem %s", out, test.out)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	fields := []Field{
+		{Name: "title", Weight: 3, Analyzer: EnglishAnalyzer{}},
+		{Name: "steps", Weight: 1, Analyzer: EnglishAnalyzer{}},
+	}
+	idx := NewMemoryIndex(fields...)
+	idx.Index(Document{ID: 1, Fields: map[string]string{"title": "Baked potatoes", "steps": "Bake the potatoes for an hour."}})
+	idx.Index(Document{ID: 2, Fields: map[string]string{"title": "Potato salad", "steps": "Boil potatoes, then mix with <mayo>."}})
+	idx.Index(Document{ID: 3, Fields: map[string]string{"title": "Pancakes", "steps": "Mix flour and milk, then fry."}})
+
+	type (
+		in struct {
+			query string
+		}
+		out struct {
+			ids []uint
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"baking potato"}, out{[]uint{1, 2}}},
+		"case-02": {in{"mix"}, out{[]uint{2, 3}}},
+		"case-03": {in{"the"}, out{[]uint{}}},
+		"case-04": {in{"pancake"}, out{[]uint{3}}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			hits, err := idx.Search(in.query, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hits) != len(out.ids) {
+				t.Fatalf("actual hits %v, expected ids %v", hits, out.ids)
+			}
+			for i, hit := range hits {
+				if hit.ID != out.ids[i] {
+					t.Errorf("actual hits %v, expected ids %v", hits, out.ids)
+				}
+			}
+		})
+	}
+
+	hits, _ := idx.Search("mix", 10)
+	if s := hits[0].Snippets["steps"]; s != "Boil potatoes, then <mark>mix</mark> with &lt;mayo&gt;." {
+		t.Errorf("actual snippet %s", s)
+	}
+
+	idx.Delete(1)
+	if hits, _ := idx.Search("baked potatoes", 10); len(hits) != 1 || hits[0].ID != 2 {
+		t.Errorf("actual hits after delete %v", hits)
+	}
+}
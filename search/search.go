@@ -0,0 +1,40 @@
+// Package search provides full text search over recipes. Index is the
+// extension point for search backends; MemoryIndex is an embedded, in-process
+// implementation which needs no external service.
+package search
+
+// Document is the searchable text of one recipe, keyed by field name
+type Document struct {
+	ID     uint
+	Fields map[string]string
+}
+
+// Hit is a document matching a query
+type Hit struct {
+	ID    uint    `json:"id"`
+	Score float64 `json:"score"`
+	// Snippets are excerpts of the matching fields, HTML escaped, with the
+	// matching words wrapped in <mark> elements
+	Snippets map[string]string `json:"snippets"`
+}
+
+// Index is a full text index of documents
+type Index interface {
+	// Index adds doc to the index, replacing the document with the same ID
+	Index(doc Document) error
+	// Delete removes the document with ID from the index
+	Delete(ID uint) error
+	// Search returns up to limit documents matching query, best match
+	// first, or every matching document when limit is negative
+	Search(query string, limit int) ([]Hit, error)
+	// IDs returns the IDs of the documents in the index
+	IDs() ([]uint, error)
+}
+
+// Field configures how a document field is analyzed and how much a match on
+// it counts
+type Field struct {
+	Name     string
+	Weight   float64
+	Analyzer Analyzer
+}
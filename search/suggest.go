@@ -0,0 +1,302 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fuzzyMinLen is the length, in runes, a prefix needs to exceed before
+// completions one typo away are suggested as well
+const fuzzyMinLen = 4
+
+// fuzzyPenalty scales the score of completions found through a typo
+const fuzzyPenalty = 0.5
+
+// wordPenalty scales the score of completions found by a word other than
+// the first
+const wordPenalty = 0.75
+
+// Phrase is a text of a document which can be suggested, like a title or an
+// ingredient name, and the kind of text it is
+type Phrase struct {
+	Text string
+	Kind string
+}
+
+// Suggestion is a completion of a prefix
+type Suggestion struct {
+	Text string `json:"text"`
+	Kind string `json:"kind"`
+	// Count is the number of documents with the phrase
+	Count int     `json:"count"`
+	Score float64 `json:"score"`
+}
+
+// Suggester completes prefixes to phrases of documents with a trie. Phrases
+// are found by the start of any of their words, ignoring case, width and
+// kana variants, and prefixes longer than fuzzyMinLen runes also find
+// phrases one typo away. Phrases are ranked by the number of documents
+// having them, weighted by their kind.
+type Suggester struct {
+	mu      sync.RWMutex
+	weights map[string]float64
+	root    *trieNode
+	entries map[phraseKey]*phraseEntry
+	docs    map[uint][]Phrase
+}
+
+type phraseKey struct {
+	kind, folded string
+}
+
+type phraseEntry struct {
+	text  string
+	count int
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	// phrases lists the phrases a key ending here leads to, and whether the
+	// key is the whole phrase rather than one of its later words
+	phrases map[phraseKey]bool
+}
+
+// NewSuggester initiates Suggester. weights scales the score of phrases by
+// their kind, kinds missing from it weigh 1.
+func NewSuggester(weights map[string]float64) *Suggester {
+	return &Suggester{
+		weights: weights,
+		root:    &trieNode{},
+		entries: map[phraseKey]*phraseEntry{},
+		docs:    map[uint][]Phrase{},
+	}
+}
+
+// Set replaces the phrases of the document with ID
+func (s *Suggester) Set(ID uint, phrases []Phrase) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delete(ID)
+	seen := map[phraseKey]bool{}
+	for _, phrase := range phrases {
+		key := phraseKey{phrase.Kind, Fold(phrase.Text)}
+		if key.folded == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		s.docs[ID] = append(s.docs[ID], phrase)
+
+		entry := s.entries[key]
+		if entry == nil {
+			entry = &phraseEntry{text: strings.TrimSpace(phrase.Text)}
+			s.entries[key] = entry
+			for i, suffix := range wordSuffixes(key.folded) {
+				s.root.insert([]rune(suffix), key, i == 0)
+			}
+		}
+		entry.count++
+	}
+}
+
+// Delete removes the phrases of the document with ID
+func (s *Suggester) Delete(ID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delete(ID)
+}
+
+// IDs returns the IDs of the documents with phrases
+func (s *Suggester) IDs() []uint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	IDs := make([]uint, 0, len(s.docs))
+	for ID := range s.docs {
+		IDs = append(IDs, ID)
+	}
+	return IDs
+}
+
+func (s *Suggester) delete(ID uint) {
+	for _, phrase := range s.docs[ID] {
+		key := phraseKey{phrase.Kind, Fold(phrase.Text)}
+		entry := s.entries[key]
+		if entry == nil {
+			continue
+		}
+		if entry.count--; entry.count > 0 {
+			continue
+		}
+		delete(s.entries, key)
+		for _, suffix := range wordSuffixes(key.folded) {
+			s.root.remove([]rune(suffix), key)
+		}
+	}
+	delete(s.docs, ID)
+}
+
+// Suggest returns up to limit completions of prefix, best first
+func (s *Suggester) Suggest(prefix string, limit int) []Suggestion {
+	q := []rune(strings.TrimLeft(Fold(prefix), " "))
+	if len(q) == 0 {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	edits := 0
+	if len(q) > fuzzyMinLen {
+		edits = 1
+	}
+	found := map[*trieNode]int{}
+	s.root.match(q, edits, 0, found)
+
+	scores := map[phraseKey]float64{}
+	for node, typos := range found {
+		node.each(func(key phraseKey, whole bool) {
+			weight, ok := s.weights[key.kind]
+			if !ok {
+				weight = 1
+			}
+			score := float64(s.entries[key].count) * weight
+			if typos > 0 {
+				score *= fuzzyPenalty
+			}
+			if !whole {
+				score *= wordPenalty
+			}
+			if score > scores[key] {
+				scores[key] = score
+			}
+		})
+	}
+
+	suggestions := make([]Suggestion, 0, len(scores))
+	for key, score := range scores {
+		entry := s.entries[key]
+		suggestions = append(suggestions, Suggestion{entry.text, key.kind, entry.count, score})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Text != b.Text {
+			return a.Text < b.Text
+		}
+		return a.Kind < b.Kind
+	})
+	if limit >= 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+func (n *trieNode) insert(key []rune, phrase phraseKey, whole bool) {
+	for _, r := range key {
+		if n.children == nil {
+			n.children = map[rune]*trieNode{}
+		}
+		child := n.children[r]
+		if child == nil {
+			child = &trieNode{}
+			n.children[r] = child
+		}
+		n = child
+	}
+	if n.phrases == nil {
+		n.phrases = map[phraseKey]bool{}
+	}
+	n.phrases[phrase] = whole
+}
+
+// remove takes phrase off the node at key, pruning the nodes left empty, and
+// reports whether n itself is left empty
+func (n *trieNode) remove(key []rune, phrase phraseKey) bool {
+	if len(key) == 0 {
+		delete(n.phrases, phrase)
+	} else if child := n.children[key[0]]; child != nil && child.remove(key[1:], phrase) {
+		delete(n.children, key[0])
+	}
+	return len(n.children) == 0 && len(n.phrases) == 0
+}
+
+// match finds the nodes reached by q with up to edits substituted, missing,
+// extra or swapped runes, and records the fewest typos each is reached with
+func (n *trieNode) match(q []rune, edits, typos int, found map[*trieNode]int) {
+	if len(q) == 0 {
+		if t, ok := found[n]; !ok || typos < t {
+			found[n] = typos
+		}
+		return
+	}
+	if child := n.children[q[0]]; child != nil {
+		child.match(q[1:], edits, typos, found)
+	}
+	if edits == 0 {
+		return
+	}
+
+	// an extra rune in q
+	n.match(q[1:], edits-1, typos+1, found)
+	for r, child := range n.children {
+		if r != q[0] {
+			// a wrong rune in q
+			child.match(q[1:], edits-1, typos+1, found)
+		}
+		// a rune missing from q
+		child.match(q, edits-1, typos+1, found)
+	}
+	// two runes swapped in q
+	if len(q) > 1 && q[0] != q[1] {
+		if child := n.children[q[1]]; child != nil {
+			if grandchild := child.children[q[0]]; grandchild != nil {
+				grandchild.match(q[2:], edits-1, typos+1, found)
+			}
+		}
+	}
+}
+
+// each calls fn with every phrase in the subtree of n
+func (n *trieNode) each(fn func(phrase phraseKey, whole bool)) {
+	for phrase, whole := range n.phrases {
+		fn(phrase, whole)
+	}
+	for _, child := range n.children {
+		child.each(fn)
+	}
+}
+
+// Fold normalizes text like CJKAnalyzer does and collapses whitespace, so
+// that text can be compared outside an index as well
+func Fold(text string) string {
+	var b strings.Builder
+	space := false
+	for _, c := range normalize(text) {
+		if c.r == ' ' || c.r == '\t' || c.r == '\n' || c.r == '\r' {
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteRune(' ')
+			space = false
+		}
+		b.WriteRune(c.r)
+	}
+	return b.String()
+}
+
+// wordSuffixes returns folded and every suffix of it starting at a later word
+func wordSuffixes(folded string) []string {
+	suffixes := []string{folded}
+	for i := 0; i < len(folded); i++ {
+		if folded[i] == ' ' {
+			suffixes = append(suffixes, folded[i+1:])
+		}
+	}
+	return suffixes
+}
@@ -0,0 +1,72 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	s := NewSuggester(map[string]float64{"title": 3, "ingredient": 2})
+	s.Set(1, []Phrase{{"Chicken Curry", "title"}, {"chicken thigh", "ingredient"}, {"Curry powder", "ingredient"}})
+	s.Set(2, []Phrase{{"Roast Chicken", "title"}, {"Chicken thigh", "ingredient"}})
+	s.Set(3, []Phrase{{"肉じゃが", "title"}, {"ジャガイモ", "ingredient"}})
+
+	type (
+		in struct {
+			prefix string
+		}
+		out struct {
+			texts []string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {
+			in{"chi"},
+			out{[]string{"chicken thigh", "Chicken Curry", "Roast Chicken"}},
+		},
+		"case-02": {
+			in{"cur"},
+			out{[]string{"Chicken Curry", "Curry powder"}},
+		},
+		// typos are only forgiven in longer prefixes
+		"case-03": {
+			in{"chx"},
+			out{[]string{}},
+		},
+		"case-04": {
+			in{"cihcken"},
+			out{[]string{"chicken thigh", "Chicken Curry", "Roast Chicken"}},
+		},
+		"case-05": {
+			in{"ｼﾞｬｶﾞ"},
+			out{[]string{"ジャガイモ"}},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			texts := []string{}
+			for _, suggestion := range s.Suggest(in.prefix, 10) {
+				texts = append(texts, suggestion.Text)
+			}
+			if !reflect.DeepEqual(texts, out.texts) {
+				t.Errorf("actual texts %v, expected texts %v", texts, out.texts)
+			}
+		})
+	}
+
+	s.Delete(1)
+	s.Delete(2)
+	if suggestions := s.Suggest("chi", 10); len(suggestions) != 0 {
+		t.Errorf("actual suggestions after delete %v", suggestions)
+	}
+	if len(s.root.children) != 2 {
+		t.Errorf("actual trie children after delete %d, expected 2", len(s.root.children))
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/motomux/smart-cooking-server/resource"
+)
+
+// pageCursor is the position after which the next page of a listing starts.
+// Clients only ever see it encoded, so its fields may change freely.
+type pageCursor struct {
+	ID uint `json:"id"`
+	// Sort is the sort order of the listing, and the fields below are the
+	// sort key of the last recipe, as far as the order depends on them
+	Sort     string  `json:"sort,omitempty"`
+	Rating   float64 `json:"rating,omitempty"`
+	CookTime int     `json:"cook_time,omitempty"`
+}
+
+func encodeCursor(c pageCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, resource.Invalid("cursor", "is malformed")
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, resource.Invalid("cursor", "is malformed")
+	}
+	return c, nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/parser"
+	"github.com/motomux/smart-cooking-server/resource"
+)
+
+// MaxParseLines is the most lines parsed in one request
+const MaxParseLines = 500
+
+// IngredientsSvcInterface is an interface to test IngredientsSvc
+type IngredientsSvcInterface interface {
+	Parse(text string) ([]parser.Parsed, error)
+}
+
+// IngredientsSvc turns ingredient lists written as text into ingredients
+type IngredientsSvc struct{}
+
+// NewIngredientsSvc initiates IngredientsSvc
+func NewIngredientsSvc() *IngredientsSvc {
+	return &IngredientsSvc{}
+}
+
+// Parse parses every line of text
+func (u *IngredientsSvc) Parse(text string) ([]parser.Parsed, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, resource.Invalid("text", "is required")
+	}
+	if n := strings.Count(text, "\n") + 1; n > MaxParseLines {
+		return nil, resource.Invalid("text", fmt.Sprintf("must have at most %d lines", MaxParseLines))
+	}
+	parsed := parser.ParseList(text)
+	if parsed == nil {
+		parsed = []parser.Parsed{}
+	}
+	return parsed, nil
+}
@@ -0,0 +1,480 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/ical"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// MaxPlanMeals is the most meals one meal plan holds
+const MaxPlanMeals = 500
+
+// dateFormat is how the dates of meals are written
+const dateFormat = "2006-01-02"
+
+// mealTimes are when the meals of each slot are eaten, as the time of day
+var mealTimes = map[string]time.Duration{
+	"breakfast": 8 * time.Hour,
+	"lunch":     12*time.Hour + 30*time.Minute,
+	"dinner":    19 * time.Hour,
+}
+
+// mealDuration is how long a meal lasts in the calendar
+const mealDuration = time.Hour
+
+// calendarProdID identifies the calendars of meal plans
+const calendarProdID = "-//smart-cooking-server//meal plans//EN"
+
+// MealPlanRequest asks for a new meal plan
+type MealPlanRequest struct {
+	Name string `json:"name"`
+	// TimeZone defaults to UTC
+	TimeZone string `json:"time_zone"`
+}
+
+// MealAssignment is the recipe to cook for a meal
+type MealAssignment struct {
+	RecipeID uint `json:"recipe_id"`
+	Servings int  `json:"servings"`
+}
+
+// MealPlanPage is one page of the meal plans. Next is the cursor of the
+// following page, empty on the last page.
+type MealPlanPage struct {
+	Plans []resource.MealPlan
+	Next  string
+}
+
+// MealWeek is the meals planned for the seven days from Monday of a week
+type MealWeek struct {
+	PlanID uint      `json:"plan_id"`
+	Start  string    `json:"start"`
+	End    string    `json:"end"`
+	Days   []MealDay `json:"days"`
+}
+
+// MealDay is the meals planned for a day, in slot order
+type MealDay struct {
+	Date  string        `json:"date"`
+	Meals []PlannedMeal `json:"meals"`
+}
+
+// PlannedMeal is a meal of a week with the title of its recipe, which is
+// empty when the recipe was deleted since
+type PlannedMeal struct {
+	Slot     string `json:"slot"`
+	RecipeID uint   `json:"recipe_id"`
+	Servings int    `json:"servings"`
+	Title    string `json:"title"`
+}
+
+// MealPlansSvcInterface is an interface to test MealPlansSvc
+type MealPlansSvcInterface interface {
+	GetOne(planID int) (*resource.MealPlan, error)
+	List(cursor string, limit int) (*MealPlanPage, error)
+	Create(req *MealPlanRequest) (*resource.MealPlan, error)
+	Delete(planID int) error
+	Assign(planID int, date, slot string, a *MealAssignment) (*resource.MealPlan, error)
+	Unassign(planID int, date, slot string) (*resource.MealPlan, error)
+	Week(planID int, date string) (*MealWeek, error)
+	Calendar(planID int, baseURL string) (*ical.Calendar, error)
+	ShoppingList(planID int, from, to string) (*resource.ShoppingList, error)
+}
+
+// MealPlansSvc plans recipes for the meals of days
+type MealPlansSvc struct {
+	Rsc      resource.MealPlansRscInterface
+	Recipes  resource.RecipesRscInterface
+	Shopping ShoppingListsSvcInterface
+}
+
+// NewMealPlansSvc initiates MealPlansSvc
+func NewMealPlansSvc(db store.Store) *MealPlansSvc {
+	return &MealPlansSvc{
+		Rsc:      resource.NewMealPlansRsc(db),
+		Recipes:  resource.NewRecipesRsc(db),
+		Shopping: NewShoppingListsSvc(db),
+	}
+}
+
+// GetOne gets the meal plan with planID
+func (u *MealPlansSvc) GetOne(planID int) (*resource.MealPlan, error) {
+	return u.Rsc.GetOne(planID)
+}
+
+// List gets the page of meal plans which follows cursor
+func (u *MealPlansSvc) List(cursor string, limit int) (*MealPlanPage, error) {
+	if limit < 1 || limit > MaxPageLimit {
+		return nil, resource.Invalid("limit", fmt.Sprintf("must be between 1 and %d", MaxPageLimit))
+	}
+	c, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	plans, more, err := u.Rsc.GetPage(c.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &MealPlanPage{Plans: plans}
+	if more {
+		page.Next = encodeCursor(pageCursor{ID: plans[len(plans)-1].ID})
+	}
+	return page, nil
+}
+
+// Create makes an empty meal plan
+func (u *MealPlansSvc) Create(req *MealPlanRequest) (*resource.MealPlan, error) {
+	zone := strings.TrimSpace(req.TimeZone)
+	if zone == "" {
+		zone = "UTC"
+	}
+	if _, err := time.LoadLocation(zone); err != nil {
+		return nil, resource.Invalid("time_zone", fmt.Sprintf("unknown time zone %q", zone))
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Meal plan"
+	}
+
+	return u.Rsc.Insert(&resource.MealPlan{
+		Name:      name,
+		TimeZone:  zone,
+		Meals:     []resource.Meal{},
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// Delete deletes the meal plan with planID
+func (u *MealPlansSvc) Delete(planID int) error {
+	return u.Rsc.Delete(planID)
+}
+
+// Assign plans the recipe of a for the meal of slot on date, replacing the
+// recipe planned for it before. Meals planned in between by someone else
+// are kept: the plan is read again and the change retried, up to
+// maxWriteRetries times.
+func (u *MealPlansSvc) Assign(planID int, date, slot string, a *MealAssignment) (*resource.MealPlan, error) {
+	if err := validateMeal(date, slot); err != nil {
+		return nil, err
+	}
+	if a.Servings < 0 || a.Servings > MaxServings {
+		return nil, resource.Invalid("servings", fmt.Sprintf("must be between 0 and %d", MaxServings))
+	}
+	if _, err := u.Recipes.GetOne(int(a.RecipeID)); err != nil {
+		if errors.Is(err, resource.ErrNotFound) {
+			return nil, resource.Invalid("recipe_id", fmt.Sprintf("recipe %d doesn't exist", a.RecipeID))
+		}
+		return nil, err
+	}
+
+	meal := resource.Meal{Date: date, Slot: slot, RecipeID: a.RecipeID, Servings: a.Servings}
+	return u.updateMeals(planID, func(meals []resource.Meal) ([]resource.Meal, error) {
+		if i := findMeal(meals, date, slot); i >= 0 {
+			meals[i] = meal
+			return meals, nil
+		}
+		if len(meals) >= MaxPlanMeals {
+			return nil, resource.Invalid("date", fmt.Sprintf("a meal plan holds at most %d meals", MaxPlanMeals))
+		}
+		meals = append(meals, meal)
+		sortMeals(meals)
+		return meals, nil
+	})
+}
+
+// Unassign takes the meal of slot on date off the plan, retrying like
+// Assign
+func (u *MealPlansSvc) Unassign(planID int, date, slot string) (*resource.MealPlan, error) {
+	if err := validateMeal(date, slot); err != nil {
+		return nil, err
+	}
+	return u.updateMeals(planID, func(meals []resource.Meal) ([]resource.Meal, error) {
+		i := findMeal(meals, date, slot)
+		if i < 0 {
+			return nil, resource.NotFound("no %s is planned on %s in meal plan %d", slot, date, planID)
+		}
+		return append(meals[:i], meals[i+1:]...), nil
+	})
+}
+
+// updateMeals changes the meals of the plan with planID by edit, which is
+// given a copy of them, reading the plan again when its meals were changed
+// in between
+func (u *MealPlansSvc) updateMeals(planID int, edit func(meals []resource.Meal) ([]resource.Meal, error)) (*resource.MealPlan, error) {
+	for i := 0; ; i++ {
+		plan, err := u.Rsc.GetOne(planID)
+		if err != nil {
+			return nil, err
+		}
+		meals, err := edit(append([]resource.Meal(nil), plan.Meals...))
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := u.Rsc.UpdateMeals(planID, meals, plan.Meals)
+		if errors.Is(err, resource.ErrConflict) && i < maxWriteRetries {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// Week gets the meals planned for the week of date, today in the time zone
+// of the plan when date is empty
+func (u *MealPlansSvc) Week(planID int, date string) (*MealWeek, error) {
+	plan, err := u.Rsc.GetOne(planID)
+	if err != nil {
+		return nil, err
+	}
+	var day time.Time
+	if date == "" {
+		day = time.Now().In(planLocation(plan))
+	} else if day, err = time.Parse(dateFormat, date); err != nil {
+		return nil, resource.Invalid("date", "must be formatted as YYYY-MM-DD")
+	}
+
+	week := planWeek(plan, day)
+	var meals []resource.Meal
+	for _, d := range week.Days {
+		for _, m := range d.Meals {
+			meals = append(meals, resource.Meal{RecipeID: m.RecipeID})
+		}
+	}
+	recipes, err := u.recipes(meals)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range week.Days {
+		for i := range d.Meals {
+			if recipe := recipes[d.Meals[i].RecipeID]; recipe != nil {
+				d.Meals[i].Title = recipe.Title
+			}
+		}
+	}
+	return week, nil
+}
+
+// Calendar makes a calendar with an event for every meal of the plan whose
+// recipe still exists. Recipe links are made absolute with baseURL.
+func (u *MealPlansSvc) Calendar(planID int, baseURL string) (*ical.Calendar, error) {
+	plan, err := u.Rsc.GetOne(planID)
+	if err != nil {
+		return nil, err
+	}
+	recipes, err := u.recipes(plan.Meals)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ical.Calendar{
+		ProdID: calendarProdID,
+		Name:   plan.Name,
+		Events: mealEvents(plan, recipes, baseURL),
+	}, nil
+}
+
+// ShoppingList makes a shopping list for the meals planned from from to to,
+// both included. Either end left empty is open. Meals whose recipe was
+// deleted since are left out, and the meals of a recipe planned more than
+// once are shopped for as one recipe with their servings summed up. It
+// fails when the range holds more recipes or servings than one shopping
+// list is made for.
+func (u *MealPlansSvc) ShoppingList(planID int, from, to string) (*resource.ShoppingList, error) {
+	for field, date := range map[string]string{"from": from, "to": to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(dateFormat, date); err != nil {
+			return nil, resource.Invalid(field, "must be formatted as YYYY-MM-DD")
+		}
+	}
+	plan, err := u.Rsc.GetOne(planID)
+	if err != nil {
+		return nil, err
+	}
+	var meals []resource.Meal
+	for _, m := range plan.Meals {
+		if (from == "" || m.Date >= from) && (to == "" || m.Date <= to) {
+			meals = append(meals, m)
+		}
+	}
+	recipes, err := u.recipes(meals)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ShoppingListRequest{Name: plan.Name}
+	first, last := "", ""
+	summed := map[uint]int{}
+	for _, m := range meals {
+		recipe := recipes[m.RecipeID]
+		if recipe == nil {
+			continue
+		}
+		if first == "" {
+			first = m.Date
+		}
+		last = m.Date
+		if recipe.Servings == 0 {
+			// servings can't be summed up for a recipe which doesn't
+			// say how many it serves
+			req.Recipes = append(req.Recipes, resource.ShoppingRecipe{RecipeID: m.RecipeID, Servings: m.Servings})
+			continue
+		}
+		servings := m.Servings
+		if servings == 0 {
+			servings = recipe.Servings
+		}
+		if i, ok := summed[m.RecipeID]; ok {
+			req.Recipes[i].Servings += servings
+			continue
+		}
+		summed[m.RecipeID] = len(req.Recipes)
+		req.Recipes = append(req.Recipes, resource.ShoppingRecipe{RecipeID: m.RecipeID, Servings: servings})
+	}
+	if len(req.Recipes) == 0 {
+		return nil, resource.Invalid("meals", "no meals are planned in the range")
+	}
+	span := first
+	if first != last {
+		span = fmt.Sprintf("%s to %s", first, last)
+	}
+	// the request is checked here, so that a range too large for one list
+	// isn't reported as a request the client never made
+	if len(req.Recipes) > MaxShoppingRecipes {
+		return nil, resource.Invalid("meals", fmt.Sprintf("the meals planned %s use %d recipes, more than the %d of one shopping list; shop for fewer days", span, len(req.Recipes), MaxShoppingRecipes))
+	}
+	for _, r := range req.Recipes {
+		if r.Servings > MaxServings {
+			return nil, resource.Invalid("meals", fmt.Sprintf("the meals planned %s serve %d of recipe %d, more than the %d of one shopping list; shop for fewer days", span, r.Servings, r.RecipeID, MaxServings))
+		}
+	}
+	req.Name = fmt.Sprintf("%s %s", plan.Name, span)
+	return u.Shopping.Create(req)
+}
+
+// recipes gets the recipes of meals by ID, leaving out the deleted ones
+func (u *MealPlansSvc) recipes(meals []resource.Meal) (map[uint]*resource.Recipe, error) {
+	recipes := map[uint]*resource.Recipe{}
+	for _, m := range meals {
+		if _, ok := recipes[m.RecipeID]; ok {
+			continue
+		}
+		recipe, err := u.Recipes.GetOne(int(m.RecipeID))
+		if err != nil && !errors.Is(err, resource.ErrNotFound) {
+			return nil, err
+		}
+		recipes[m.RecipeID] = recipe
+	}
+	return recipes, nil
+}
+
+func validateMeal(date, slot string) error {
+	if _, err := time.Parse(dateFormat, date); err != nil {
+		return resource.Invalid("date", "must be formatted as YYYY-MM-DD")
+	}
+	if _, ok := mealTimes[slot]; !ok {
+		return resource.Invalid("slot", fmt.Sprintf("must be one of %s", strings.Join(resource.MealSlots, ", ")))
+	}
+	return nil
+}
+
+func findMeal(meals []resource.Meal, date, slot string) int {
+	for i, m := range meals {
+		if m.Date == date && m.Slot == slot {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortMeals sorts meals by date, and the meals of a day by when they are
+// eaten
+func sortMeals(meals []resource.Meal) {
+	sort.SliceStable(meals, func(i, j int) bool {
+		if meals[i].Date != meals[j].Date {
+			return meals[i].Date < meals[j].Date
+		}
+		return mealTimes[meals[i].Slot] < mealTimes[meals[j].Slot]
+	})
+}
+
+// planLocation is the time zone of plan, UTC if it is no longer known
+func planLocation(plan *resource.MealPlan) *time.Location {
+	loc, err := time.LoadLocation(plan.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// planWeek picks the meals of plan in the week from Monday of day's week
+func planWeek(plan *resource.MealPlan, day time.Time) *MealWeek {
+	offset := (int(day.Weekday()) + 6) % 7
+	monday := time.Date(day.Year(), day.Month(), day.Day()-offset, 0, 0, 0, 0, time.UTC)
+
+	week := &MealWeek{PlanID: plan.ID}
+	for i := 0; i < 7; i++ {
+		date := monday.AddDate(0, 0, i).Format(dateFormat)
+		d := MealDay{Date: date, Meals: []PlannedMeal{}}
+		for _, m := range plan.Meals {
+			if m.Date == date {
+				d.Meals = append(d.Meals, PlannedMeal{Slot: m.Slot, RecipeID: m.RecipeID, Servings: m.Servings})
+			}
+		}
+		week.Days = append(week.Days, d)
+	}
+	week.Start, week.End = week.Days[0].Date, week.Days[6].Date
+	return week
+}
+
+// mealEvents makes an event at the time of each meal of plan, reminding
+// when to start preparing the recipe so that it is ready in time
+func mealEvents(plan *resource.MealPlan, recipes map[uint]*resource.Recipe, baseURL string) []ical.Event {
+	loc := planLocation(plan)
+	events := []ical.Event{}
+	for _, m := range plan.Meals {
+		recipe := recipes[m.RecipeID]
+		day, err := time.Parse(dateFormat, m.Date)
+		if recipe == nil || err != nil {
+			continue
+		}
+		// the time of day is set on the date rather than added to its
+		// midnight, which is off by an hour on the days clocks change
+		at := mealTimes[m.Slot]
+		start := time.Date(day.Year(), day.Month(), day.Day(), int(at/time.Hour), int(at%time.Hour/time.Minute), 0, 0, loc)
+		prep := time.Duration(recipe.PrepTime+recipe.CookTime) * time.Second
+		url := fmt.Sprintf("%s/recipes/%d", strings.TrimRight(baseURL, "/"), recipe.ID)
+
+		var desc []string
+		if prep > 0 {
+			desc = append(desc, fmt.Sprintf("Start preparing at %s", start.Add(-prep).Format("15:04")))
+		}
+		servings := m.Servings
+		if servings == 0 {
+			servings = recipe.Servings
+		}
+		if servings > 0 {
+			desc = append(desc, fmt.Sprintf("Serves %d", servings))
+		}
+		desc = append(desc, url)
+
+		events = append(events, ical.Event{
+			UID:         fmt.Sprintf("meal-plan-%d-%s-%s@smart-cooking-server", plan.ID, m.Date, m.Slot),
+			Summary:     fmt.Sprintf("%s: %s", strings.Title(m.Slot), recipe.Title),
+			Description: strings.Join(desc, "\n"),
+			URL:         url,
+			Start:       start,
+			End:         start.Add(mealDuration),
+			Alarm:       prep,
+		})
+	}
+	return events
+}
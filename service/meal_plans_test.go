@@ -0,0 +1,252 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/ical"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+func TestPlanWeek(t *testing.T) {
+	plan := &resource.MealPlan{ID: 1, Meals: []resource.Meal{
+		{Date: "2026-10-18", Slot: "dinner", RecipeID: 1},
+		{Date: "2026-10-19", Slot: "breakfast", RecipeID: 2},
+		{Date: "2026-10-19", Slot: "dinner", RecipeID: 3, Servings: 2},
+		{Date: "2026-10-25", Slot: "lunch", RecipeID: 4},
+		{Date: "2026-10-26", Slot: "lunch", RecipeID: 5},
+	}}
+
+	type (
+		in struct {
+			day time.Time
+		}
+		out struct {
+			start, end string
+			meals      map[string][]PlannedMeal
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		// a Wednesday is in the week from the Monday before
+		"case-01": {
+			in{time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC)},
+			out{"2026-10-19", "2026-10-25", map[string][]PlannedMeal{
+				"2026-10-19": {{Slot: "breakfast", RecipeID: 2}, {Slot: "dinner", RecipeID: 3, Servings: 2}},
+				"2026-10-25": {{Slot: "lunch", RecipeID: 4}},
+			}},
+		},
+		// a Sunday ends its week
+		"case-02": {
+			in{time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)},
+			out{"2026-10-12", "2026-10-18", map[string][]PlannedMeal{
+				"2026-10-18": {{Slot: "dinner", RecipeID: 1}},
+			}},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			week := planWeek(plan, test.in.day)
+			if week.Start != test.out.start || week.End != test.out.end {
+				t.Errorf("week is %s to %s, want %s to %s", week.Start, week.End, test.out.start, test.out.end)
+			}
+			if len(week.Days) != 7 {
+				t.Fatalf("week has %d days", len(week.Days))
+			}
+			for _, d := range week.Days {
+				want := test.out.meals[d.Date]
+				if want == nil {
+					want = []PlannedMeal{}
+				}
+				if !reflect.DeepEqual(d.Meals, want) {
+					t.Errorf("%s: %+v, want %+v", d.Date, d.Meals, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMealEvents(t *testing.T) {
+	plan := &resource.MealPlan{ID: 7, TimeZone: "Asia/Tokyo", Meals: []resource.Meal{
+		{Date: "2026-10-19", Slot: "breakfast", RecipeID: 1},
+		{Date: "2026-10-19", Slot: "dinner", RecipeID: 2, Servings: 2},
+		{Date: "2026-10-20", Slot: "lunch", RecipeID: 3},
+	}}
+	recipes := map[uint]*resource.Recipe{
+		1: {ID: 1, Title: "Toast"},
+		2: {ID: 2, Title: "Curry", PrepTime: 15 * 60, CookTime: 30 * 60, Servings: 4},
+		// recipe 3 was deleted
+		3: nil,
+	}
+	jst := time.FixedZone("JST", 9*60*60)
+
+	events := mealEvents(plan, recipes, "https://example.com/")
+	want := []ical.Event{
+		{
+			UID:         "meal-plan-7-2026-10-19-breakfast@smart-cooking-server",
+			Summary:     "Breakfast: Toast",
+			Description: "https://example.com/recipes/1",
+			URL:         "https://example.com/recipes/1",
+			Start:       time.Date(2026, 10, 19, 8, 0, 0, 0, jst),
+			End:         time.Date(2026, 10, 19, 9, 0, 0, 0, jst),
+		},
+		{
+			UID:         "meal-plan-7-2026-10-19-dinner@smart-cooking-server",
+			Summary:     "Dinner: Curry",
+			Description: "Start preparing at 18:15\nServes 2\nhttps://example.com/recipes/2",
+			URL:         "https://example.com/recipes/2",
+			Start:       time.Date(2026, 10, 19, 19, 0, 0, 0, jst),
+			End:         time.Date(2026, 10, 19, 20, 0, 0, 0, jst),
+			Alarm:       45 * time.Minute,
+		},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i := range want {
+		got := events[i]
+		if !got.Start.Equal(want[i].Start) || !got.End.Equal(want[i].End) {
+			t.Errorf("event %d is %s to %s, want %s to %s", i, got.Start, got.End, want[i].Start, want[i].End)
+		}
+		got.Start, got.End = want[i].Start, want[i].End
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("event %d: %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestMealEventsClockChange(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// clocks go back an hour early on 2026-11-01
+	plan := &resource.MealPlan{ID: 7, TimeZone: "America/New_York", Meals: []resource.Meal{
+		{Date: "2026-11-01", Slot: "breakfast", RecipeID: 1},
+	}}
+	recipes := map[uint]*resource.Recipe{1: {ID: 1, Title: "Toast"}}
+
+	events := mealEvents(plan, recipes, "https://example.com")
+	if want := time.Date(2026, 11, 1, 8, 0, 0, 0, loc); len(events) != 1 || !events[0].Start.Equal(want) {
+		t.Errorf("got %+v, want an event at %s", events, want)
+	}
+}
+
+func TestMealPlanShoppingList(t *testing.T) {
+	db := store.NewMemory()
+	if err := resource.CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	u := NewMealPlansSvc(db)
+	recipes := resource.NewRecipesRsc(db)
+	for _, recipe := range []resource.Recipe{
+		{Title: "Curry", Servings: 4, Ingredients: []resource.Ingredient{{Name: "rice", Quantity: resource.NewQuantity(2, 1), Unit: "cup"}}},
+		{Title: "Toast", Servings: 1, Ingredients: []resource.Ingredient{{Name: "bread", Quantity: resource.NewQuantity(1, 1), Unit: "slice"}}},
+	} {
+		if _, err := recipes.Insert(&recipe); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan, err := u.Create(&MealPlanRequest{Name: "Week"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []struct {
+		date, slot string
+		a          MealAssignment
+	}{
+		{"2026-10-19", "dinner", MealAssignment{RecipeID: 1}},
+		{"2026-10-20", "breakfast", MealAssignment{RecipeID: 2}},
+		{"2026-10-20", "dinner", MealAssignment{RecipeID: 1, Servings: 2}},
+	} {
+		if _, err := u.Assign(int(plan.ID), m.date, m.slot, &m.a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := recipes.SoftDelete(2, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := u.ShoppingList(int(plan.ID), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []resource.ShoppingRecipe{{RecipeID: 1, Servings: 6}}; !reflect.DeepEqual(list.Recipes, want) {
+		t.Errorf("recipes %+v, want %+v", list.Recipes, want)
+	}
+	if want := resource.NewQuantity(3, 1); len(list.Items) != 1 || list.Items[0].Quantity != want {
+		t.Errorf("items %+v, want %v cups of rice", list.Items, want)
+	}
+}
+
+func TestMealPlanShoppingListTooLarge(t *testing.T) {
+	db := store.NewMemory()
+	if err := resource.CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	u := NewMealPlansSvc(db)
+	recipes := resource.NewRecipesRsc(db)
+	for i := 0; i <= MaxShoppingRecipes; i++ {
+		recipe := resource.Recipe{Title: "Curry", Servings: 4, Ingredients: []resource.Ingredient{{Name: "rice", Quantity: resource.NewQuantity(2, 1), Unit: "cup"}}}
+		if _, err := recipes.Insert(&recipe); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type (
+		in struct {
+			// meals are planned a day each from 2026-01-01
+			meals    []MealAssignment
+			from, to string
+		}
+		out struct {
+			isErr bool
+		}
+	)
+
+	var many []MealAssignment
+	for i := 1; i <= MaxShoppingRecipes+1; i++ {
+		many = append(many, MealAssignment{RecipeID: uint(i)})
+	}
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{[]MealAssignment{{RecipeID: 1, Servings: MaxServings}}, "", ""}, out{false}},
+		// servings summed up over the meals of a recipe
+		"case-02": {in{[]MealAssignment{{RecipeID: 1, Servings: MaxServings}, {RecipeID: 1, Servings: 1}}, "", ""}, out{true}},
+		"case-03": {in{many, "", ""}, out{true}},
+		// a shorter range of the same plan fits one list
+		"case-04": {in{many, "2026-01-01", "2026-01-31"}, out{false}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			plan, err := u.Create(&MealPlanRequest{Name: "Month"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i := range in.meals {
+				if _, err := u.Assign(int(plan.ID), day.AddDate(0, 0, i).Format(dateFormat), "dinner", &in.meals[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err = u.ShoppingList(int(plan.ID), in.from, in.to)
+			var rerr *resource.Error
+			if (err != nil) != out.isErr || err != nil && (!errors.As(err, &rerr) || rerr.Field != "meals") {
+				t.Errorf("actual error %v, expected error %v about the meals", err, out.isErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package service
+
+// mergePatch applies a JSON Merge Patch (RFC 7396) to target. Both are
+// values produced by encoding/json decoding into interface{}.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	merged := make(map[string]interface{}, len(t))
+	for k, v := range t {
+		merged[k] = v
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = mergePatch(merged[k], v)
+	}
+	return merged
+}
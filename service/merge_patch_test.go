@@ -0,0 +1,55 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	type (
+		in struct {
+			target, patch string
+		}
+		out struct {
+			result string
+		}
+	)
+
+	// Examples from RFC 7396 Appendix A
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{`{"a":"b"}`, `{"a":"c"}`}, out{`{"a":"c"}`}},
+		"case-02": {in{`{"a":"b"}`, `{"b":"c"}`}, out{`{"a":"b","b":"c"}`}},
+		"case-03": {in{`{"a":"b"}`, `{"a":null}`}, out{`{}`}},
+		"case-04": {in{`{"a":"b","b":"c"}`, `{"a":null}`}, out{`{"b":"c"}`}},
+		"case-05": {in{`{"a":["b"]}`, `{"a":"c"}`}, out{`{"a":"c"}`}},
+		"case-06": {in{`{"a":"c"}`, `{"a":["b"]}`}, out{`{"a":["b"]}`}},
+		"case-07": {in{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`}, out{`{"a":{"b":"d"}}`}},
+		"case-08": {in{`{"a":[{"b":"c"}]}`, `{"a":[1]}`}, out{`{"a":[1]}`}},
+		"case-09": {in{`["a","b"]`, `["c","d"]`}, out{`["c","d"]`}},
+		"case-10": {in{`{"a":"b"}`, `["c"]`}, out{`["c"]`}},
+		"case-11": {in{`{"a":"foo"}`, `null`}, out{`null`}},
+		"case-12": {in{`{"a":"foo"}`, `"bar"`}, out{`"bar"`}},
+		"case-13": {in{`{"e":null}`, `{"a":1}`}, out{`{"e":null,"a":1}`}},
+		"case-14": {in{`[1,2]`, `{"a":"b","c":null}`}, out{`{"a":"b"}`}},
+		"case-15": {in{`{}`, `{"a":{"bb":{"ccc":null}}}`}, out{`{"a":{"bb":{}}}`}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			var target, patch, expected interface{}
+			json.Unmarshal([]byte(in.target), &target)
+			json.Unmarshal([]byte(in.patch), &patch)
+			json.Unmarshal([]byte(out.result), &expected)
+
+			if result := mergePatch(target, patch); !reflect.DeepEqual(result, expected) {
+				t.Errorf("actual result %v, expected result %v", result, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// PantrySvcInterface is an interface to test PantrySvc
+type PantrySvcInterface interface {
+	List() ([]resource.PantryItem, error)
+	Create(item *resource.PantryItem) (*resource.PantryItem, error)
+	Replace(itemID int, item *resource.PantryItem) (*resource.PantryItem, error)
+	Delete(itemID int) error
+}
+
+// PantrySvc keeps track of the ingredients at home
+type PantrySvc struct {
+	Rsc resource.PantryRscInterface
+}
+
+// NewPantrySvc initiates PantrySvc
+func NewPantrySvc(db store.Store) *PantrySvc {
+	return &PantrySvc{
+		Rsc: resource.NewPantryRsc(db),
+	}
+}
+
+// List gets every pantry item, those going bad soonest first
+func (u *PantrySvc) List() ([]resource.PantryItem, error) {
+	items, err := u.Rsc.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].ExpiresOn, items[j].ExpiresOn
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+		return a < b
+	})
+	return items, nil
+}
+
+// Create puts item in the pantry
+func (u *PantrySvc) Create(item *resource.PantryItem) (*resource.PantryItem, error) {
+	if err := validatePantryItem(item); err != nil {
+		return nil, err
+	}
+	item.AddedAt = time.Now().Unix()
+	return u.Rsc.Insert(item)
+}
+
+// Replace overwrites the pantry item with itemID, keeping when it was added
+func (u *PantrySvc) Replace(itemID int, item *resource.PantryItem) (*resource.PantryItem, error) {
+	if err := validatePantryItem(item); err != nil {
+		return nil, err
+	}
+	current, err := u.Rsc.GetOne(itemID)
+	if err != nil {
+		return nil, err
+	}
+	item.ID, item.AddedAt = current.ID, current.AddedAt
+	return u.Rsc.Replace(item)
+}
+
+// Delete takes the item with itemID out of the pantry
+func (u *PantrySvc) Delete(itemID int) error {
+	return u.Rsc.Delete(itemID)
+}
+
+func validatePantryItem(item *resource.PantryItem) error {
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" {
+		return resource.Invalid("name", "is required")
+	}
+	if item.Quantity.Num < 0 {
+		return resource.Invalid("quantity", "must not be negative")
+	}
+	if !item.Quantity.InRange() {
+		return resource.Invalid("quantity", "is too large")
+	}
+	if item.ExpiresOn != "" {
+		if _, err := time.Parse(dateFormat, item.ExpiresOn); err != nil {
+			return resource.Invalid("expires_on", "must be formatted as YYYY-MM-DD")
+		}
+	}
+	return nil
+}
@@ -1,24 +1,70 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/diet"
 	"github.com/motomux/smart-cooking-server/resource"
-	tarantool "github.com/tarantool/go-tarantool"
+	"github.com/motomux/smart-cooking-server/store"
 )
 
 // RecipesSvcInterface is an interface to test RecipesSvc
 type RecipesSvcInterface interface {
 	GetOne(recipeID int) (*resource.Recipe, error)
+	Scale(recipeID int, servings int) (*resource.Recipe, error)
+	List(query *resource.RecipeQuery, cursor string, limit int) (*RecipePage, error)
+	Create(recipe *resource.Recipe) (*resource.Recipe, error)
+	Replace(recipe *resource.Recipe) (*resource.Recipe, error)
+	Patch(recipeID int, patch []byte) (*resource.Recipe, error)
+	Delete(recipeID int) error
+	GetTrash() ([]resource.Recipe, error)
+	Restore(recipeID int) (*resource.Recipe, error)
 }
 
 // RecipesSvc provides api to user end point
 type RecipesSvc struct {
-	Rsc resource.RecipesRscInterface
+	Rsc       resource.RecipesRscInterface
+	Listeners []RecipeListener
+}
+
+// RecipeListener is notified after recipes are written, to keep data
+// derived from them, like the search index, up to date. Recipes moved to the
+// trash are reported as deleted, and as saved again when restored.
+type RecipeListener interface {
+	RecipeSaved(recipe *resource.Recipe)
+	RecipeDeleted(recipeID uint)
 }
 
+// RecipePage is one page of a recipe listing. Next is the cursor of the
+// following page, empty on the last page. Facets count the whole listing,
+// not only the page, and are only counted for the first page, since
+// counting reads every recipe.
+type RecipePage struct {
+	Recipes []resource.Recipe
+	Next    string
+	Facets  *resource.RecipeFacets
+}
+
+const (
+	// DefaultPageLimit is the page size used when the client doesn't ask for one
+	DefaultPageLimit = 20
+	// MaxPageLimit is the largest page size a client can ask for
+	MaxPageLimit = 100
+)
+
 // NewRecipesSvc initiates RecipesSvc
-func NewRecipesSvc(client *tarantool.Connection) *RecipesSvc {
+func NewRecipesSvc(db store.Store, listeners ...RecipeListener) *RecipesSvc {
 	return &RecipesSvc{
-		Rsc: resource.NewRecipesRsc(client),
+		Rsc:       resource.NewRecipesRsc(db),
+		Listeners: listeners,
 	}
 }
 
@@ -26,3 +72,383 @@ func NewRecipesSvc(client *tarantool.Connection) *RecipesSvc {
 func (u *RecipesSvc) GetOne(recipesID int) (*resource.Recipe, error) {
 	return u.Rsc.GetOne(recipesID)
 }
+
+// List gets the page of recipes matching query which follows cursor. An
+// empty cursor starts from the first recipe.
+func (u *RecipesSvc) List(query *resource.RecipeQuery, cursor string, limit int) (*RecipePage, error) {
+	if limit < 1 || limit > MaxPageLimit {
+		return nil, resource.Invalid("limit", fmt.Sprintf("must be between 1 and %d", MaxPageLimit))
+	}
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	c, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if c.Sort == "" {
+		c.Sort = resource.SortID
+	}
+	if cursor != "" && c.Sort != query.Sort {
+		return nil, resource.Invalid("cursor", "belongs to another sort order")
+	}
+
+	var after *resource.Recipe
+	if cursor != "" {
+		after = &resource.Recipe{ID: c.ID, Rating: c.Rating, CookTime: c.CookTime}
+	}
+	recipes, more, err := u.Rsc.Find(query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &RecipePage{Recipes: recipes}
+	if cursor == "" {
+		if page.Facets, err = u.Rsc.Facets(query); err != nil {
+			return nil, err
+		}
+	}
+	if more {
+		last := recipes[len(recipes)-1]
+		page.Next = encodeCursor(pageCursor{ID: last.ID, Sort: query.Sort, Rating: last.Rating, CookTime: last.CookTime})
+	}
+	return page, nil
+}
+
+// Create validates recipe and inserts it into recipes resource
+func (u *RecipesSvc) Create(recipe *resource.Recipe) (*resource.Recipe, error) {
+	normalizeRecipe(recipe)
+	if err := validateRecipe(recipe); err != nil {
+		return nil, err
+	}
+	recipe.DeletedAt = 0
+	recipe.LabelOverrides = nil
+	labelRecipe(recipe)
+	return u.notifySaved(u.Rsc.Insert(recipe))
+}
+
+// Replace validates recipe and overwrites the existing recipe with its ID.
+// Label overrides made in between are kept: the recipe is read again and
+// the change retried, up to maxWriteRetries times.
+func (u *RecipesSvc) Replace(recipe *resource.Recipe) (*resource.Recipe, error) {
+	normalizeRecipe(recipe)
+	if err := validateRecipe(recipe); err != nil {
+		return nil, err
+	}
+	for i := 0; ; i++ {
+		current, err := u.Rsc.GetOne(int(recipe.ID))
+		if err != nil {
+			return nil, err
+		}
+		recipe.LabelOverrides = current.LabelOverrides
+		labelRecipe(recipe)
+		replaced, err := u.Rsc.Replace(recipe, current.LabelOverrides)
+		if errors.Is(err, resource.ErrConflict) && i < maxWriteRetries {
+			continue
+		}
+		return u.notifySaved(replaced, err)
+	}
+}
+
+// Patch applies a JSON Merge Patch document to the recipe and writes back
+// only the fields whose value changed, retrying like Replace
+func (u *RecipesSvc) Patch(recipeID int, patch []byte) (*resource.Recipe, error) {
+	var doc interface{}
+	if err := json.Unmarshal(patch, &doc); err != nil {
+		return nil, resource.Invalid("patch", err.Error())
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, resource.Invalid("patch", "must be a JSON object")
+	}
+
+	for i := 0; ; i++ {
+		patched, changed, err := u.patch(recipeID, doc)
+		if errors.Is(err, resource.ErrConflict) && i < maxWriteRetries {
+			continue
+		}
+		if err != nil || !changed {
+			return patched, err
+		}
+		return u.notifySaved(patched, nil)
+	}
+}
+
+// patch reads the recipe, applies doc and writes back the fields which
+// changed, and reports whether any did
+func (u *RecipesSvc) patch(recipeID int, doc interface{}) (*resource.Recipe, bool, error) {
+	current, err := u.Rsc.GetOne(recipeID)
+	if err != nil {
+		return nil, false, err
+	}
+	original, err := recipeToMap(current)
+	if err != nil {
+		return nil, false, err
+	}
+
+	merged, err := json.Marshal(mergePatch(original, doc))
+	if err != nil {
+		return nil, false, err
+	}
+	var updated resource.Recipe
+	if err := json.Unmarshal(merged, &updated); err != nil {
+		return nil, false, resource.Invalid("patch", err.Error())
+	}
+	if updated.ID != current.ID {
+		return nil, false, resource.Invalid("id", "is read-only")
+	}
+	updated.DeletedAt = current.DeletedAt
+	updated.LabelOverrides = current.LabelOverrides
+	normalizeRecipe(&updated)
+	if err := validateRecipe(&updated); err != nil {
+		return nil, false, err
+	}
+	labelRecipe(&updated)
+
+	result, err := recipeToMap(&updated)
+	if err != nil {
+		return nil, false, err
+	}
+	var fields []string
+	for k, v := range result {
+		if !reflect.DeepEqual(original[k], v) {
+			fields = append(fields, k)
+		}
+	}
+	if len(fields) == 0 {
+		return current, false, nil
+	}
+	sort.Strings(fields)
+
+	patched, err := u.Rsc.Update(recipeID, &updated, fields, current.LabelOverrides)
+	return patched, err == nil, err
+}
+
+// Delete moves the recipe to the trash, from where it can be restored until
+// it is purged
+func (u *RecipesSvc) Delete(recipeID int) error {
+	recipe, err := u.Rsc.SoftDelete(recipeID, time.Now())
+	if err != nil {
+		return err
+	}
+	u.notifyDeleted(recipe.ID)
+	return nil
+}
+
+// GetTrash gets recipes in the trash, most recently deleted first
+func (u *RecipesSvc) GetTrash() ([]resource.Recipe, error) {
+	recipes, err := u.Rsc.GetDeleted()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(recipes, func(i, j int) bool {
+		return recipes[i].DeletedAt > recipes[j].DeletedAt
+	})
+	return recipes, nil
+}
+
+// Restore takes the recipe back from the trash
+func (u *RecipesSvc) Restore(recipeID int) (*resource.Recipe, error) {
+	return u.notifySaved(u.Rsc.Restore(recipeID))
+}
+
+// PurgeTrash permanently deletes recipes which have been in the trash for
+// longer than retention
+func (u *RecipesSvc) PurgeTrash(retention time.Duration) (int, error) {
+	purged, err := u.Rsc.Purge(time.Now().Add(-retention))
+	for _, ID := range purged {
+		u.notifyDeleted(ID)
+	}
+	return len(purged), err
+}
+
+// PurgeTrashEvery runs PurgeTrash every interval, which must be positive. It
+// never returns, so it is meant to be run in its own goroutine.
+func (u *RecipesSvc) PurgeTrashEvery(interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		n, err := u.PurgeTrash(retention)
+		if err != nil {
+			log.Println("Failed to purge trash:", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Purged %d recipes from trash", n)
+		}
+	}
+}
+
+func (u *RecipesSvc) notifySaved(recipe *resource.Recipe, err error) (*resource.Recipe, error) {
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range u.Listeners {
+		l.RecipeSaved(recipe)
+	}
+	return recipe, nil
+}
+
+func (u *RecipesSvc) notifyDeleted(recipeID uint) {
+	for _, l := range u.Listeners {
+		l.RecipeDeleted(recipeID)
+	}
+}
+
+// recipeToMap returns the json representation of recipe as a generic map
+// so that it can be merged and compared field by field
+func recipeToMap(recipe *resource.Recipe) (map[string]interface{}, error) {
+	b, err := json.Marshal(recipe)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// normalizeRecipe folds the values recipes are filtered by, so that filters
+// match them exactly
+func normalizeRecipe(recipe *resource.Recipe) {
+	var tags []string
+	for _, tag := range recipe.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	recipe.Tags = tags
+	recipe.Cuisine = strings.ToLower(strings.TrimSpace(recipe.Cuisine))
+	recipe.Difficulty = strings.ToLower(strings.TrimSpace(recipe.Difficulty))
+}
+
+// labelRecipe derives the labels of recipe from its ingredients. Labels
+// sent by clients are ignored, authors override them through
+// RecipesLabelsSvc.
+func labelRecipe(recipe *resource.Recipe) {
+	recipe.Labels = diet.Apply(diet.Analyze(ingredientNames(recipe)).Derived, recipe.LabelOverrides)
+}
+
+func ingredientNames(recipe *resource.Recipe) []string {
+	names := make([]string, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		names[i] = ingredient.Name
+	}
+	return names
+}
+
+// normalizeQuery folds the filter values of query like normalizeRecipe
+func normalizeQuery(query *resource.RecipeQuery) {
+	for i, tag := range query.Tags {
+		query.Tags[i] = strings.ToLower(strings.TrimSpace(tag))
+	}
+	for i, label := range query.Labels {
+		query.Labels[i] = strings.ToLower(strings.TrimSpace(label))
+	}
+	query.Cuisine = strings.ToLower(strings.TrimSpace(query.Cuisine))
+	query.Difficulty = strings.ToLower(strings.TrimSpace(query.Difficulty))
+	if query.Sort == "" {
+		query.Sort = resource.SortID
+	}
+}
+
+func validateQuery(query *resource.RecipeQuery) error {
+	normalizeQuery(query)
+	if !containsString(resource.Sorts, query.Sort) {
+		return resource.Invalid("sort", fmt.Sprintf("must be one of %s", strings.Join(resource.Sorts, ", ")))
+	}
+	if query.Difficulty != "" && !containsString(resource.Difficulties, query.Difficulty) {
+		return resource.Invalid("difficulty", fmt.Sprintf("must be one of %s", strings.Join(resource.Difficulties, ", ")))
+	}
+	for _, label := range query.Labels {
+		if !diet.IsLabel(label) {
+			return resource.Invalid("label", fmt.Sprintf("must be one of %s", strings.Join(diet.Labels, ", ")))
+		}
+	}
+	if query.MaxTotalTime < 0 {
+		return resource.Invalid("max_total_time", "must not be negative")
+	}
+	return nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func validateRecipe(recipe *resource.Recipe) error {
+	if strings.TrimSpace(recipe.Title) == "" {
+		return resource.Invalid("title", "is required")
+	}
+	for i, ingredient := range recipe.Ingredients {
+		if strings.TrimSpace(ingredient.Name) == "" {
+			return resource.Invalid(fmt.Sprintf("ingredients[%d].name", i), "is required")
+		}
+		if ingredient.Quantity.Num < 0 {
+			return resource.Invalid(fmt.Sprintf("ingredients[%d].quantity", i), "must not be negative")
+		}
+		if !ingredient.Quantity.InRange() {
+			return resource.Invalid(fmt.Sprintf("ingredients[%d].quantity", i), "is too large")
+		}
+	}
+	if len(recipe.Howto) == 0 {
+		return resource.Invalid("howto", "needs at least one step")
+	}
+	for i, step := range recipe.Howto {
+		field := fmt.Sprintf("howto[%d]", i)
+		if strings.TrimSpace(step.Text) == "" {
+			return resource.Invalid(field+".text", "must not be empty")
+		}
+		if step.Duration < 0 {
+			return resource.Invalid(field+".duration", "must not be negative")
+		}
+		if t := step.Temperature; t != nil && t.Unit != "C" && t.Unit != "F" {
+			return resource.Invalid(field+".temperature.unit", `must be "C" or "F"`)
+		}
+		for _, ingredient := range step.Ingredients {
+			if ingredient < 0 || ingredient >= len(recipe.Ingredients) {
+				return resource.Invalid(field+".ingredients", fmt.Sprintf("refers to missing ingredient %d", ingredient))
+			}
+		}
+		if err := validateURL(field+".photo", step.Photo); err != nil {
+			return err
+		}
+	}
+	if recipe.Difficulty != "" && !containsString(resource.Difficulties, recipe.Difficulty) {
+		return resource.Invalid("difficulty", fmt.Sprintf("must be one of %s", strings.Join(resource.Difficulties, ", ")))
+	}
+	if recipe.PrepTime < 0 {
+		return resource.Invalid("prep_time", "must not be negative")
+	}
+	if recipe.CookTime < 0 {
+		return resource.Invalid("cook_time", "must not be negative")
+	}
+	if recipe.Servings < 0 {
+		return resource.Invalid("servings", "must not be negative")
+	}
+	if recipe.Rating < 0 || recipe.Rating > 5 {
+		return resource.Invalid("rating", "must be between 0 and 5")
+	}
+	if err := validateURL("photo", recipe.Photo); err != nil {
+		return err
+	}
+	if err := validateURL("video", recipe.Video); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateURL(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return resource.Invalid(field, "must be an absolute URL")
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/store"
+	"github.com/motomux/smart-cooking-server/units"
+)
+
+// DefaultExpiringDays is how many days ahead pantry items count as about to
+// go bad, unless asked otherwise
+const DefaultExpiringDays = 3
+
+// MaxExpiringDays is the most days ahead pantry items can be looked at
+const MaxExpiringDays = 365
+
+// expiryWeight is the most that using up pantry items about to go bad adds
+// to the score of a recipe, on top of its coverage
+const expiryWeight = 0.5
+
+// CookableQuery asks for the recipes which can be cooked from the pantry
+type CookableQuery struct {
+	Limit int
+	// MinCoverage is the least share of its ingredients a recipe needs to
+	// have in the pantry to be listed
+	MinCoverage float64
+	// ExpiringDays is how many days ahead pantry items count as about to go
+	// bad
+	ExpiringDays int
+}
+
+// CookableRecipe is a recipe with how much of it the pantry covers
+type CookableRecipe struct {
+	Recipe *resource.Recipe `json:"recipe"`
+	// Coverage is the share of the ingredients in the pantry, from 0 to 1
+	Coverage float64 `json:"coverage"`
+	// Score ranks the recipes, favoring those which use up pantry items
+	// about to go bad
+	Score   float64             `json:"score"`
+	Missing []MissingIngredient `json:"missing"`
+	// Expiring are the pantry items about to go bad the recipe uses
+	Expiring []string `json:"expiring"`
+}
+
+// MissingIngredient is an ingredient to buy before cooking a recipe. Short
+// is true when the pantry has some, but not enough.
+type MissingIngredient struct {
+	resource.Ingredient
+	Short bool `json:"short,omitempty"`
+}
+
+// RecipesCookableSvcInterface is an interface to test RecipesCookableSvc
+type RecipesCookableSvcInterface interface {
+	Cookable(q *CookableQuery) ([]CookableRecipe, error)
+}
+
+// RecipesCookableSvc finds the recipes which can be cooked from the pantry.
+// Only the recipes the search index finds the pantry items in are looked
+// at, rather than every recipe.
+type RecipesCookableSvc struct {
+	Rsc    resource.RecipesRscInterface
+	Pantry resource.PantryRscInterface
+	Index  search.Index
+}
+
+// NewRecipesCookableSvc initiates RecipesCookableSvc
+func NewRecipesCookableSvc(db store.Store, index search.Index) *RecipesCookableSvc {
+	return &RecipesCookableSvc{
+		Rsc:    resource.NewRecipesRsc(db),
+		Pantry: resource.NewPantryRsc(db),
+		Index:  index,
+	}
+}
+
+// Cookable ranks the recipes with ingredients in the pantry by how much of
+// them the pantry covers, and returns the best ones. Items past their
+// expiry date don't count.
+func (u *RecipesCookableSvc) Cookable(q *CookableQuery) ([]CookableRecipe, error) {
+	if q.Limit < 1 || q.Limit > MaxPageLimit {
+		return nil, resource.Invalid("limit", fmt.Sprintf("must be between 1 and %d", MaxPageLimit))
+	}
+	if q.MinCoverage < 0 || q.MinCoverage > 1 {
+		return nil, resource.Invalid("min_coverage", "must be between 0 and 1")
+	}
+	if q.ExpiringDays < 0 || q.ExpiringDays > MaxExpiringDays {
+		return nil, resource.Invalid("expiring_days", fmt.Sprintf("must be between 0 and %d", MaxExpiringDays))
+	}
+
+	items, err := u.Pantry.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	stock := newPantryStock(items, time.Now(), q.ExpiringDays)
+
+	cookable := []CookableRecipe{}
+	if len(stock.items) == 0 {
+		return cookable, nil
+	}
+	hits, err := u.Index.Search(strings.Join(stock.names, "\n"), -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, hit := range hits {
+		recipe, err := u.Rsc.GetOne(int(hit.ID))
+		if errors.Is(err, resource.ErrNotFound) {
+			// deleted since it was indexed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := stock.cookable(recipe); ok && c.Coverage >= q.MinCoverage {
+			cookable = append(cookable, c)
+		}
+	}
+
+	sortCookable(cookable)
+	if len(cookable) > q.Limit {
+		cookable = cookable[:q.Limit]
+	}
+	return cookable, nil
+}
+
+// pantryStock is the pantry as far as it can be cooked with on a day
+type pantryStock struct {
+	// names are the names of the items, once each
+	names []string
+	// items are the pantry items by ingredientKey of their name
+	items map[string][]resource.PantryItem
+	// urgency is how soon each item about to go bad goes bad, from above 0
+	// for the last day ahead looked at to 1 for today
+	urgency map[uint]float64
+}
+
+// newPantryStock leaves out the items past their expiry date on today, and
+// weighs the items going bad within days
+func newPantryStock(items []resource.PantryItem, today time.Time, days int) *pantryStock {
+	s := &pantryStock{items: map[string][]resource.PantryItem{}, urgency: map[uint]float64{}}
+	y, m, d := today.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	for _, item := range items {
+		if item.ExpiresOn != "" {
+			expires, err := time.Parse(dateFormat, item.ExpiresOn)
+			if err == nil {
+				left := int(expires.Sub(day).Hours() / 24)
+				if left < 0 {
+					continue
+				}
+				if left <= days {
+					s.urgency[item.ID] = float64(days-left+1) / float64(days+1)
+				}
+			}
+		}
+		key := ingredientKey(item.Name)
+		if _, ok := s.items[key]; !ok {
+			s.names = append(s.names, item.Name)
+		}
+		s.items[key] = append(s.items[key], item)
+	}
+	return s
+}
+
+// cookable works out how much of recipe the stock covers. Recipes with none
+// of their ingredients in stock aren't cookable.
+func (s *pantryStock) cookable(recipe *resource.Recipe) (CookableRecipe, bool) {
+	c := CookableRecipe{Recipe: recipe, Missing: []MissingIngredient{}, Expiring: []string{}}
+	var total, covered int
+	var urgency float64
+	used := map[uint]bool{}
+	for _, ingredient := range recipe.Ingredients {
+		if strings.TrimSpace(ingredient.Name) == "" {
+			continue
+		}
+		total++
+		items := s.find(ingredient.Name)
+		if len(items) == 0 {
+			c.Missing = append(c.Missing, MissingIngredient{Ingredient: ingredient})
+			continue
+		}
+		if !enough(ingredient, items) {
+			c.Missing = append(c.Missing, MissingIngredient{Ingredient: ingredient, Short: true})
+			continue
+		}
+		covered++
+		for _, item := range items {
+			if u, ok := s.urgency[item.ID]; ok && !used[item.ID] {
+				used[item.ID] = true
+				urgency += u
+				c.Expiring = append(c.Expiring, item.Name)
+			}
+		}
+	}
+	if covered == 0 {
+		return c, false
+	}
+
+	c.Coverage = float64(covered) / float64(total)
+	if urgency > 1 {
+		urgency = 1
+	}
+	c.Score = c.Coverage + expiryWeight*urgency
+	return c, true
+}
+
+// find finds the pantry items with the name of an ingredient. Names match
+// as a whole, up to case and plurals, so that "butter" in the pantry isn't
+// taken for "peanut butter".
+func (s *pantryStock) find(name string) []resource.PantryItem {
+	return s.items[ingredientKey(name)]
+}
+
+// enough reports whether items hold the amount of ingredient. Amounts which
+// aren't tracked, or can't be compared, like a chicken against 500 g of
+// chicken, are taken to be enough.
+func enough(ingredient resource.Ingredient, items []resource.PantryItem) bool {
+	if ingredient.Quantity.IsZero() {
+		return true
+	}
+	unit, measured := units.Lookup(ingredient.Unit)
+	measured = measured && unit.Size != nil
+
+	have := new(big.Rat)
+	for _, item := range items {
+		if item.Quantity.IsZero() {
+			return true
+		}
+		amount := toRat(item.Quantity)
+		itemUnit, ok := units.Lookup(item.Unit)
+		switch {
+		case measured && ok && itemUnit.Dimension == unit.Dimension && itemUnit.Size != nil:
+			amount, _ = units.Convert(amount, itemUnit, unit)
+		case !measured && !ok && strings.EqualFold(strings.TrimSpace(item.Unit), strings.TrimSpace(ingredient.Unit)):
+		default:
+			return true
+		}
+		have.Add(have, amount)
+	}
+	return have.Cmp(toRat(ingredient.Quantity)) >= 0
+}
+
+// sortCookable sorts recipes by score, then by coverage and the number of
+// ingredients missing
+func sortCookable(recipes []CookableRecipe) {
+	sort.SliceStable(recipes, func(i, j int) bool {
+		a, b := recipes[i], recipes[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Coverage != b.Coverage {
+			return a.Coverage > b.Coverage
+		}
+		if len(a.Missing) != len(b.Missing) {
+			return len(a.Missing) < len(b.Missing)
+		}
+		return a.Recipe.ID < b.Recipe.ID
+	})
+}
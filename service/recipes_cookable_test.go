@@ -0,0 +1,140 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+func TestCookable(t *testing.T) {
+	q := resource.NewQuantity
+	today := time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC)
+	pantry := []resource.PantryItem{
+		{ID: 1, Name: "flour", Quantity: q(1, 1), Unit: "kg"},
+		{ID: 2, Name: "eggs", Quantity: q(2, 1)},
+		{ID: 3, Name: "milk", ExpiresOn: "2026-10-18"},
+		{ID: 4, Name: "spinach", ExpiresOn: "2026-10-16"},
+		{ID: 5, Name: "butter", Quantity: q(50, 1), Unit: "g", ExpiresOn: "2026-10-30"},
+	}
+	recipe := func(ID uint, ingredients ...resource.Ingredient) *resource.Recipe {
+		return &resource.Recipe{ID: ID, Ingredients: ingredients}
+	}
+	ing := func(name string, quantity resource.Quantity, unit string) resource.Ingredient {
+		return resource.Ingredient{Name: name, Quantity: quantity, Unit: unit}
+	}
+
+	type (
+		in struct {
+			recipe *resource.Recipe
+		}
+		out struct {
+			ok       bool
+			coverage float64
+			score    float64
+			missing  []MissingIngredient
+			expiring []string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		// every ingredient is in stock, and a cup of flour is less than 1 kg
+		"case-01": {
+			in{recipe(1, ing("Flour", q(1, 1), "cup"), ing("egg", q(1, 1), ""))},
+			out{true, 1, 1, []MissingIngredient{}, []string{}},
+		},
+		// three eggs are more than the two in stock
+		"case-02": {
+			in{recipe(2, ing("flour", q(200, 1), "g"), ing("eggs", q(3, 1), ""))},
+			out{true, 0.5, 0.5, []MissingIngredient{{ing("eggs", q(3, 1), ""), true}}, []string{}},
+		},
+		// milk goes bad tomorrow, spinach has already gone bad
+		"case-03": {
+			in{recipe(3, ing("milk", q(200, 1), "ml"), ing("spinach", q(1, 1), "bunch"))},
+			out{true, 0.5, 0.5 + expiryWeight*3.0/4, []MissingIngredient{{ing("spinach", q(1, 1), "bunch"), false}}, []string{"milk"}},
+		},
+		// nothing in stock
+		"case-04": {
+			in{recipe(4, ing("rice", q(1, 1), "cup"))},
+			out{false, 0, 0, nil, nil},
+		},
+		// butter in stock isn't peanut butter
+		"case-06": {
+			in{recipe(6, ing("peanut butter", q(2, 1), "tbsp"))},
+			out{false, 0, 0, nil, nil},
+		},
+		// tablespoons of butter can't be compared with grams of it, so the
+		// butter in stock is taken to be enough
+		"case-05": {
+			in{recipe(5, ing("butter", q(5, 1), "tbsp"), ing("salt", resource.Quantity{}, ""), ing("salt", q(1, 1), "tsp"))},
+			out{true, 1.0 / 3, 1.0 / 3, []MissingIngredient{{ing("salt", resource.Quantity{}, ""), false}, {ing("salt", q(1, 1), "tsp"), false}}, []string{}},
+		},
+	}
+
+	stock := newPantryStock(pantry, today, DefaultExpiringDays)
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			c, ok := stock.cookable(in.recipe)
+			if ok != out.ok {
+				t.Fatalf("actual ok %v, expected ok %v", ok, out.ok)
+			}
+			if !ok {
+				return
+			}
+			if c.Coverage != out.coverage || c.Score != out.score {
+				t.Errorf("actual coverage %v score %v, expected coverage %v score %v", c.Coverage, c.Score, out.coverage, out.score)
+			}
+			if !reflect.DeepEqual(c.Missing, out.missing) {
+				t.Errorf("actual missing %+v, expected missing %+v", c.Missing, out.missing)
+			}
+			if !reflect.DeepEqual(c.Expiring, out.expiring) {
+				t.Errorf("actual expiring %v, expected expiring %v", c.Expiring, out.expiring)
+			}
+		})
+	}
+}
+
+func TestCookableFromIndex(t *testing.T) {
+	db := store.NewMemory()
+	if err := resource.CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	index := search.NewMemoryIndex(RecipeSearchFields...)
+	searchSvc := NewRecipesSearchSvc(db, index)
+	for _, recipe := range []resource.Recipe{
+		{Title: "Toast", Ingredients: []resource.Ingredient{{Name: "bread"}, {Name: "butter"}}},
+		{Title: "Satay", Ingredients: []resource.Ingredient{{Name: "peanut butter"}, {Name: "chicken"}}},
+		{Title: "Soup", Ingredients: []resource.Ingredient{{Name: "leek"}}},
+	} {
+		if _, err := searchSvc.Rsc.Insert(&recipe); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := searchSvc.Reindex(); err != nil {
+		t.Fatal(err)
+	}
+	u := NewRecipesCookableSvc(db, index)
+	if _, err := u.Pantry.Insert(&resource.PantryItem{Name: "Butter"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cookable, err := u.Cookable(&CookableQuery{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, c := range cookable {
+		titles = append(titles, c.Recipe.Title)
+	}
+	if expected := []string{"Toast"}; !reflect.DeepEqual(titles, expected) {
+		t.Errorf("actual cookable %v, expected cookable %v", titles, expected)
+	}
+}
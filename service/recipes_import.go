@@ -0,0 +1,84 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/parser"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipeImport is a recipe whose ingredients, and steps, may be pasted as
+// text instead of filled in field by field. Lines parsed from the text
+// follow the structured ingredients and steps.
+type RecipeImport struct {
+	resource.Recipe
+	IngredientsText string `json:"ingredients_text"`
+	HowtoText       string `json:"howto_text"`
+}
+
+// RecipesImportSvcInterface is an interface to test RecipesImportSvc
+type RecipesImportSvcInterface interface {
+	Import(recipe *RecipeImport) (*resource.Recipe, []parser.Parsed, error)
+}
+
+// RecipesImportSvc creates recipes from pasted text
+type RecipesImportSvc struct {
+	Recipes     RecipesSvcInterface
+	Ingredients IngredientsSvcInterface
+}
+
+// NewRecipesImportSvc initiates RecipesImportSvc
+func NewRecipesImportSvc(db store.Store, listeners ...RecipeListener) *RecipesImportSvc {
+	return &RecipesImportSvc{
+		Recipes:     NewRecipesSvc(db, listeners...),
+		Ingredients: NewIngredientsSvc(),
+	}
+}
+
+// Import parses the text of recipe and creates it. The parsed lines are
+// returned too, so that clients can point out the lines parsed with low
+// confidence.
+func (u *RecipesImportSvc) Import(recipe *RecipeImport) (*resource.Recipe, []parser.Parsed, error) {
+	parsed := []parser.Parsed{}
+	if strings.TrimSpace(recipe.IngredientsText) != "" {
+		var err error
+		if parsed, err = u.Ingredients.Parse(recipe.IngredientsText); err != nil {
+			return nil, nil, renameInvalid(err, "ingredients_text")
+		}
+	}
+	for _, p := range parsed {
+		recipe.Ingredients = append(recipe.Ingredients, p.Ingredient)
+	}
+	recipe.Howto = append(recipe.Howto, parseSteps(recipe.HowtoText)...)
+
+	created, err := u.Recipes.Create(&recipe.Recipe)
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, parsed, nil
+}
+
+// stepNumber matches the numbering of pasted steps, like "1.", "2)" or ①
+var stepNumber = regexp.MustCompile(`^(?:(?i:step)\s*)?(?:[0-9０-９]+\s*[.):、．）]|[①-⑳])\s*`)
+
+// parseSteps makes a step of every line of text, dropping the numbering
+func parseSteps(text string) []resource.Step {
+	var steps []resource.Step
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(stepNumber.ReplaceAllString(strings.TrimSpace(line), ""))
+		if line != "" {
+			steps = append(steps, resource.Step{Text: line})
+		}
+	}
+	return steps
+}
+
+// renameInvalid reports an ErrInvalid error as being about field
+func renameInvalid(err error, field string) error {
+	if e, ok := err.(*resource.Error); ok && e.Kind == resource.ErrInvalid {
+		return resource.Invalid(field, e.Detail)
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/resource"
+)
+
+func TestParseSteps(t *testing.T) {
+	type (
+		in struct {
+			text string
+		}
+		out struct {
+			steps []resource.Step
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"1. Preheat the oven.\n2) Mix.\n\nStep 3: Bake."}, out{[]resource.Step{{Text: "Preheat the oven."}, {Text: "Mix."}, {Text: "Bake."}}}},
+		"case-02": {in{"①玉ねぎを切る\n２．炒める"}, out{[]resource.Step{{Text: "玉ねぎを切る"}, {Text: "炒める"}}}},
+		"case-03": {in{"Bake 20 minutes at 180°C"}, out{[]resource.Step{{Text: "Bake 20 minutes at 180°C"}}}},
+		"case-04": {in{""}, out{nil}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			if steps := parseSteps(in.text); !reflect.DeepEqual(steps, out.steps) {
+				t.Errorf("actual steps %+v, expected steps %+v", steps, out.steps)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/diet"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// maxWriteRetries is how many times a change is retried when the document
+// it read was written in between
+const maxWriteRetries = 3
+
+// RecipeLabels explains the dietary labels of a recipe: the allergens found
+// in its ingredients, the ingredients nothing is known about, the labels they
+// allow, the overrides of the author and
+// the trail of changes to the overrides
+type RecipeLabels struct {
+	RecipeID  uint                       `json:"recipe_id"`
+	Labels    []string                   `json:"labels"`
+	Allergens map[diet.Allergen][]string `json:"allergens"`
+	Unknown   []string                   `json:"unknown"`
+	Derived   []string                   `json:"derived"`
+	Overrides map[string]bool            `json:"overrides"`
+	History   []resource.LabelAudit      `json:"history"`
+}
+
+// LabelOverride sets a label of a recipe on or off, or clears the override
+// when Value is nil
+type LabelOverride struct {
+	Value  *bool  `json:"value"`
+	Author string `json:"author"`
+	Reason string `json:"reason"`
+}
+
+// RecipesLabelsSvcInterface is an interface to test RecipesLabelsSvc
+type RecipesLabelsSvcInterface interface {
+	Get(recipeID int) (*RecipeLabels, error)
+	Override(recipeID int, label string, override *LabelOverride) (*RecipeLabels, error)
+}
+
+// RecipesLabelsSvc lets authors override the labels derived from the
+// ingredients of their recipes, keeping an audit trail of every change
+type RecipesLabelsSvc struct {
+	Rsc       resource.RecipesRscInterface
+	Audit     resource.LabelAuditRscInterface
+	Listeners []RecipeListener
+}
+
+// NewRecipesLabelsSvc initiates RecipesLabelsSvc
+func NewRecipesLabelsSvc(db store.Store, listeners ...RecipeListener) *RecipesLabelsSvc {
+	return &RecipesLabelsSvc{
+		Rsc:       resource.NewRecipesRsc(db),
+		Audit:     resource.NewLabelAuditRsc(db),
+		Listeners: listeners,
+	}
+}
+
+// Get explains the labels of the recipe with recipeID
+func (u *RecipesLabelsSvc) Get(recipeID int) (*RecipeLabels, error) {
+	recipe, err := u.Rsc.GetOne(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	return u.explain(recipe)
+}
+
+// Override changes the override of label on the recipe with recipeID. The
+// change is recorded in the audit trail once it is applied. Overrides made
+// in between by someone else are kept: the recipe is read again and the
+// change retried, up to maxWriteRetries times.
+func (u *RecipesLabelsSvc) Override(recipeID int, label string, override *LabelOverride) (*RecipeLabels, error) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if !diet.IsLabel(label) {
+		return nil, resource.Invalid("label", fmt.Sprintf("must be one of %s", strings.Join(diet.Labels, ", ")))
+	}
+	override.Author = strings.TrimSpace(override.Author)
+	if override.Author == "" {
+		return nil, resource.Invalid("author", "is required")
+	}
+
+	var updated *resource.Recipe
+	for i := 0; updated == nil; i++ {
+		recipe, err := u.Rsc.GetOne(recipeID)
+		if err != nil {
+			return nil, err
+		}
+		current, ok := recipe.LabelOverrides[label]
+		if override.Value == nil && !ok || override.Value != nil && ok && *override.Value == current {
+			return u.explain(recipe)
+		}
+
+		previous := recipe.LabelOverrides
+		overrides := map[string]bool{}
+		for k, v := range previous {
+			overrides[k] = v
+		}
+		if override.Value == nil {
+			delete(overrides, label)
+		} else {
+			overrides[label] = *override.Value
+		}
+		if len(overrides) == 0 {
+			overrides = nil
+		}
+		recipe.LabelOverrides = overrides
+		labelRecipe(recipe)
+
+		updated, err = u.Rsc.OverrideLabels(recipeID, recipe, previous)
+		if errors.Is(err, resource.ErrConflict) && i < maxWriteRetries {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err := u.Audit.Insert(&resource.LabelAudit{
+		RecipeID: updated.ID,
+		Label:    label,
+		Value:    override.Value,
+		Author:   override.Author,
+		Reason:   strings.TrimSpace(override.Reason),
+		At:       time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range u.Listeners {
+		l.RecipeSaved(updated)
+	}
+	return u.explain(updated)
+}
+
+// Relabel derives the labels of every recipe again, for when the allergen
+// table changed, and returns how many recipes changed
+func (u *RecipesLabelsSvc) Relabel() (int, error) {
+	var n int
+	var after uint
+	for {
+		recipes, more, err := u.Rsc.GetPage(after, MaxPageLimit)
+		if err != nil {
+			return n, err
+		}
+		for i := range recipes {
+			recipe := &recipes[i]
+			labels := recipe.Labels
+			labelRecipe(recipe)
+			if containsAll(labels, recipe.Labels) && containsAll(recipe.Labels, labels) {
+				continue
+			}
+			_, err := u.Rsc.Update(int(recipe.ID), recipe, []string{"labels"}, recipe.LabelOverrides)
+			if errors.Is(err, resource.ErrConflict) {
+				// overridden in between, which labelled it again
+				continue
+			}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		if !more {
+			return n, nil
+		}
+		after = recipes[len(recipes)-1].ID
+	}
+}
+
+func (u *RecipesLabelsSvc) explain(recipe *resource.Recipe) (*RecipeLabels, error) {
+	history, err := u.Audit.GetByRecipe(int(recipe.ID))
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []resource.LabelAudit{}
+	}
+	overrides := recipe.LabelOverrides
+	if overrides == nil {
+		overrides = map[string]bool{}
+	}
+
+	analysis := diet.Analyze(ingredientNames(recipe))
+	return &RecipeLabels{
+		RecipeID:  recipe.ID,
+		Labels:    diet.Apply(analysis.Derived, recipe.LabelOverrides),
+		Allergens: analysis.Allergens,
+		Unknown:   analysis.Unknown,
+		Derived:   analysis.Derived,
+		Overrides: overrides,
+		History:   history,
+	}, nil
+}
+
+func containsAll(values, subset []string) bool {
+	for _, s := range subset {
+		if !containsString(values, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"github.com/motomux/smart-cooking-server/nutrition"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipeNutrition is the nutrition of a recipe, in total and per serving.
+// PerServing is nil when the recipe doesn't say how many it serves.
+type RecipeNutrition struct {
+	RecipeID   uint                 `json:"recipe_id"`
+	Servings   int                  `json:"servings"`
+	Total      nutrition.Nutrients  `json:"total"`
+	PerServing *nutrition.Nutrients `json:"per_serving"`
+	// Unmatched are the ingredients which aren't counted, because they
+	// aren't in the nutrient database or their amount can't be weighed
+	Unmatched []string `json:"unmatched"`
+}
+
+// RecipesNutritionSvcInterface is an interface to test RecipesNutritionSvc
+type RecipesNutritionSvcInterface interface {
+	Get(recipeID int) (*RecipeNutrition, error)
+}
+
+// RecipesNutritionSvc computes the nutrition of recipes, and keeps the
+// cached facts up to date as a RecipeListener
+type RecipesNutritionSvc struct {
+	DB      *nutrition.DB
+	Rsc     resource.NutritionRscInterface
+	Recipes resource.RecipesRscInterface
+}
+
+// NewRecipesNutritionSvc initiates RecipesNutritionSvc
+func NewRecipesNutritionSvc(db store.Store, foods *nutrition.DB) *RecipesNutritionSvc {
+	return &RecipesNutritionSvc{
+		DB:      foods,
+		Rsc:     resource.NewNutritionRsc(db),
+		Recipes: resource.NewRecipesRsc(db),
+	}
+}
+
+// Get returns the nutrition of the recipe with recipeID. Facts are computed
+// and cached when they aren't cached yet or were computed with another
+// nutrient database.
+func (u *RecipesNutritionSvc) Get(recipeID int) (*RecipeNutrition, error) {
+	facts, err := u.Rsc.Get(recipeID)
+	if err == nil && facts.Source == u.DB.Source {
+		return perServing(facts), nil
+	}
+	if err != nil && !errors.Is(err, resource.ErrNotFound) {
+		// the cache only saves work, the facts can still be computed
+		log.Printf("Failed to read nutrition of recipe %d: %s", recipeID, err)
+	}
+
+	recipe, err := u.Recipes.GetOne(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	facts = u.compute(recipe)
+	u.put(facts)
+	return perServing(facts), nil
+}
+
+// RecipeSaved implements RecipeListener
+func (u *RecipesNutritionSvc) RecipeSaved(recipe *resource.Recipe) {
+	u.put(u.compute(recipe))
+}
+
+// RecipeDeleted implements RecipeListener
+func (u *RecipesNutritionSvc) RecipeDeleted(recipeID uint) {
+	if err := u.Rsc.Delete(int(recipeID)); err != nil {
+		log.Printf("Failed to delete nutrition of recipe %d: %s", recipeID, err)
+	}
+}
+
+func (u *RecipesNutritionSvc) put(facts *resource.NutritionFacts) {
+	if _, err := u.Rsc.Put(facts); err != nil {
+		log.Printf("Failed to cache nutrition of recipe %d: %s", facts.RecipeID, err)
+	}
+}
+
+func (u *RecipesNutritionSvc) compute(recipe *resource.Recipe) *resource.NutritionFacts {
+	items := make([]nutrition.Item, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		items[i] = nutrition.Item{Name: ingredient.Name, Unit: ingredient.Unit}
+		if ingredient.Quantity.Den != 0 {
+			items[i].Amount = toRat(ingredient.Quantity)
+		}
+	}
+
+	total, unmatched := u.DB.Compute(items)
+	return &resource.NutritionFacts{
+		RecipeID:  recipe.ID,
+		Source:    u.DB.Source,
+		Servings:  recipe.Servings,
+		Total:     total,
+		Unmatched: unmatched,
+	}
+}
+
+func perServing(facts *resource.NutritionFacts) *RecipeNutrition {
+	n := &RecipeNutrition{
+		RecipeID:  facts.RecipeID,
+		Servings:  facts.Servings,
+		Total:     facts.Total,
+		Unmatched: facts.Unmatched,
+	}
+	if n.Unmatched == nil {
+		n.Unmatched = []string{}
+	}
+	if facts.Servings > 0 {
+		serving := facts.Total.Scale(1 / float64(facts.Servings))
+		n.PerServing = &serving
+	}
+	return n
+}
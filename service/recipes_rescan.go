@@ -0,0 +1,52 @@
+package service
+
+import "sync"
+
+// rescan keeps a scan of every recipe, which reads pages of recipes that may
+// be stale by the time they are applied, from undoing what listeners applied
+// in the meantime. Recipes a listener touched since the scan began are left
+// as the listener left them.
+type rescan struct {
+	scanning sync.Mutex
+	mu       sync.Mutex
+	// touched are the recipes listeners touched since the scan began, nil
+	// when no scan is running
+	touched map[uint]bool
+}
+
+// begin starts a scan, waiting for the one running to end
+func (r *rescan) begin() {
+	r.scanning.Lock()
+	r.mu.Lock()
+	r.touched = map[uint]bool{}
+	r.mu.Unlock()
+}
+
+// end ends the scan
+func (r *rescan) end() {
+	r.mu.Lock()
+	r.touched = nil
+	r.mu.Unlock()
+	r.scanning.Unlock()
+}
+
+// listened applies what a listener was told about recipe ID
+func (r *rescan) listened(ID uint, apply func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.touched != nil {
+		r.touched[ID] = true
+	}
+	apply()
+}
+
+// scanned applies what the scan read about recipe ID, unless a listener
+// touched it since the scan began
+func (r *rescan) scanned(ID uint, apply func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.touched[ID] {
+		return nil
+	}
+	return apply()
+}
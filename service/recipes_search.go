@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipeSearchFields are the fields recipes are indexed with. Titles and
+// steps are often written in Japanese, so every field is analyzed as CJK
+// text with English words mixed in, unless configured otherwise.
+var RecipeSearchFields = []search.Field{
+	{Name: "title", Weight: 3, Analyzer: search.Analyzers["cjk"]},
+	{Name: "ingredients", Weight: 2, Analyzer: search.Analyzers["cjk"]},
+	{Name: "steps", Weight: 1, Analyzer: search.Analyzers["cjk"]},
+}
+
+// RecipesSearchSvcInterface is an interface to test RecipesSearchSvc
+type RecipesSearchSvcInterface interface {
+	Search(query string, limit int) ([]RecipeHit, error)
+}
+
+// RecipesSearchSvc searches recipes and keeps the search index up to date
+// as a RecipeListener
+type RecipesSearchSvc struct {
+	Index search.Index
+	Rsc   resource.RecipesRscInterface
+	scan  rescan
+}
+
+// RecipeHit is a recipe matching a search
+type RecipeHit struct {
+	Recipe   *resource.Recipe  `json:"recipe"`
+	Score    float64           `json:"score"`
+	Snippets map[string]string `json:"snippets"`
+}
+
+// NewRecipesSearchSvc initiates RecipesSearchSvc
+func NewRecipesSearchSvc(db store.Store, index search.Index) *RecipesSearchSvc {
+	return &RecipesSearchSvc{
+		Index: index,
+		Rsc:   resource.NewRecipesRsc(db),
+	}
+}
+
+// Search finds up to limit recipes matching query, best match first
+func (u *RecipesSearchSvc) Search(query string, limit int) ([]RecipeHit, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, resource.Invalid("q", "is required")
+	}
+	if limit < 1 || limit > MaxPageLimit {
+		return nil, resource.Invalid("limit", fmt.Sprintf("must be between 1 and %d", MaxPageLimit))
+	}
+
+	hits, err := u.Index.Search(query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]RecipeHit, 0, len(hits))
+	for _, hit := range hits {
+		recipe, err := u.Rsc.GetOne(int(hit.ID))
+		if errors.Is(err, resource.ErrNotFound) {
+			// the index lags behind a delete
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, RecipeHit{recipe, hit.Score, hit.Snippets})
+	}
+	return results, nil
+}
+
+// Reindex adds every recipe to the index and removes the documents of
+// recipes which are gone, and returns how many recipes there are. Recipes
+// saved or deleted while it runs are left as RecipeSaved and RecipeDeleted
+// indexed them.
+func (u *RecipesSearchSvc) Reindex() (int, error) {
+	u.scan.begin()
+	defer u.scan.end()
+
+	indexed := map[uint]bool{}
+	var after uint
+	for {
+		recipes, more, err := u.Rsc.GetPage(after, MaxPageLimit)
+		if err != nil {
+			return len(indexed), err
+		}
+		for i := range recipes {
+			recipe := &recipes[i]
+			err := u.scan.scanned(recipe.ID, func() error {
+				return u.Index.Index(recipeDocument(recipe))
+			})
+			if err != nil {
+				return len(indexed), err
+			}
+			indexed[recipe.ID] = true
+		}
+		if !more {
+			break
+		}
+		after = recipes[len(recipes)-1].ID
+	}
+
+	IDs, err := u.Index.IDs()
+	if err != nil {
+		return len(indexed), err
+	}
+	for _, ID := range IDs {
+		if indexed[ID] {
+			continue
+		}
+		ID := ID
+		if err := u.scan.scanned(ID, func() error { return u.Index.Delete(ID) }); err != nil {
+			return len(indexed), err
+		}
+	}
+	return len(indexed), nil
+}
+
+// ReindexEvery runs Reindex every interval, which must be positive, so that
+// the index catches up with recipes written through other servers. It never
+// returns, so it is meant to be run in its own goroutine.
+func (u *RecipesSearchSvc) ReindexEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := u.Reindex(); err != nil {
+			log.Println("Failed to reindex recipes:", err)
+		}
+	}
+}
+
+// RecipeSaved implements RecipeListener
+func (u *RecipesSearchSvc) RecipeSaved(recipe *resource.Recipe) {
+	u.scan.listened(recipe.ID, func() {
+		if err := u.Index.Index(recipeDocument(recipe)); err != nil {
+			log.Printf("Failed to index recipe %d: %s", recipe.ID, err)
+		}
+	})
+}
+
+// RecipeDeleted implements RecipeListener
+func (u *RecipesSearchSvc) RecipeDeleted(recipeID uint) {
+	u.scan.listened(recipeID, func() {
+		if err := u.Index.Delete(recipeID); err != nil {
+			log.Printf("Failed to remove recipe %d from index: %s", recipeID, err)
+		}
+	})
+}
+
+func recipeDocument(recipe *resource.Recipe) search.Document {
+	ingredients := make([]string, 0, len(recipe.Ingredients))
+	for _, ingredient := range recipe.Ingredients {
+		ingredients = append(ingredients, ingredient.Name)
+	}
+	steps := make([]string, 0, len(recipe.Howto))
+	for _, step := range recipe.Howto {
+		steps = append(steps, step.Text)
+	}
+	return search.Document{
+		ID: recipe.ID,
+		Fields: map[string]string{
+			"title":       recipe.Title,
+			"ingredients": strings.Join(ingredients, "\n"),
+			"steps":       strings.Join(steps, "\n"),
+		},
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+func TestReindex(t *testing.T) {
+	db := store.NewMemory()
+	if err := resource.CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	index := search.NewMemoryIndex(RecipeSearchFields...)
+	u := NewRecipesSearchSvc(db, index)
+	for _, title := range []string{"Pancakes", "Crepes"} {
+		if _, err := u.Rsc.Insert(&resource.Recipe{Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a recipe deleted through another server is still in the index
+	index.Index(search.Document{ID: 99, Fields: map[string]string{"title": "Waffles"}})
+
+	n, err := u.Reindex()
+	if err != nil || n != 2 {
+		t.Fatalf("reindexed %d recipes, %v", n, err)
+	}
+	IDs, _ := index.IDs()
+	sort.Slice(IDs, func(i, j int) bool { return IDs[i] < IDs[j] })
+	if expected := []uint{1, 2}; !reflect.DeepEqual(IDs, expected) {
+		t.Errorf("actual indexed %v, expected indexed %v", IDs, expected)
+	}
+}
+
+// scanHook runs after the first page of recipes is read, to write recipes
+// while a scan is running
+type scanHook struct {
+	resource.RecipesRscInterface
+	after func()
+}
+
+func (h *scanHook) GetPage(after uint, limit int) ([]resource.Recipe, bool, error) {
+	recipes, more, err := h.RecipesRscInterface.GetPage(after, limit)
+	if h.after != nil {
+		h.after()
+		h.after = nil
+	}
+	return recipes, more, err
+}
+
+func TestReindexKeepsListenedRecipes(t *testing.T) {
+	db := store.NewMemory()
+	if err := resource.CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	index := search.NewMemoryIndex(RecipeSearchFields...)
+	u := NewRecipesSearchSvc(db, index)
+	for _, title := range []string{"Pancakes", "Crepes"} {
+		if _, err := u.Rsc.Insert(&resource.Recipe{Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// pancakes are renamed, crepes deleted and scones added after the page
+	// is read
+	u.Rsc = &scanHook{u.Rsc, func() {
+		u.RecipeSaved(&resource.Recipe{ID: 1, Title: "Waffles"})
+		u.RecipeDeleted(2)
+		u.RecipeSaved(&resource.Recipe{ID: 3, Title: "Scones"})
+	}}
+
+	if _, err := u.Reindex(); err != nil {
+		t.Fatal(err)
+	}
+	IDs, _ := index.IDs()
+	sort.Slice(IDs, func(i, j int) bool { return IDs[i] < IDs[j] })
+	if expected := []uint{1, 3}; !reflect.DeepEqual(IDs, expected) {
+		t.Errorf("actual indexed %v, expected indexed %v", IDs, expected)
+	}
+	for query, expected := range map[string]int{"Waffles": 1, "Pancakes": 0} {
+		if hits, _ := index.Search(query, 10); len(hits) != expected {
+			t.Errorf("actual %d hits for %q, expected %d hits", len(hits), query, expected)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// RecipeSuggestWeights rank suggested titles above ingredients and tags
+// found in as many recipes
+var RecipeSuggestWeights = map[string]float64{
+	"title":      3,
+	"ingredient": 2,
+	"tag":        1,
+}
+
+// DefaultSuggestLimit is the number of suggestions returned when the client
+// doesn't ask for a number
+const DefaultSuggestLimit = 10
+
+// RecipesSuggestSvcInterface is an interface to test RecipesSuggestSvc
+type RecipesSuggestSvcInterface interface {
+	Suggest(prefix string, limit int) ([]search.Suggestion, error)
+}
+
+// RecipesSuggestSvc completes what users type into the search box, and keeps
+// the suggester up to date as a RecipeListener
+type RecipesSuggestSvc struct {
+	Suggester *search.Suggester
+	Rsc       resource.RecipesRscInterface
+	scan      rescan
+}
+
+// NewRecipesSuggestSvc initiates RecipesSuggestSvc
+func NewRecipesSuggestSvc(db store.Store, suggester *search.Suggester) *RecipesSuggestSvc {
+	return &RecipesSuggestSvc{
+		Suggester: suggester,
+		Rsc:       resource.NewRecipesRsc(db),
+	}
+}
+
+// Suggest completes prefix to up to limit titles, ingredient names and tags
+func (u *RecipesSuggestSvc) Suggest(prefix string, limit int) ([]search.Suggestion, error) {
+	if strings.TrimSpace(prefix) == "" {
+		return nil, resource.Invalid("prefix", "is required")
+	}
+	if limit < 1 || limit > MaxPageLimit {
+		return nil, resource.Invalid("limit", fmt.Sprintf("must be between 1 and %d", MaxPageLimit))
+	}
+	return u.Suggester.Suggest(prefix, limit), nil
+}
+
+// Rebuild adds every recipe to the suggester and removes the phrases of
+// recipes which are gone, and returns how many recipes there are. Recipes
+// saved or deleted while it runs are left as RecipeSaved and RecipeDeleted
+// set them.
+func (u *RecipesSuggestSvc) Rebuild() (int, error) {
+	u.scan.begin()
+	defer u.scan.end()
+
+	saved := map[uint]bool{}
+	var after uint
+	for {
+		recipes, more, err := u.Rsc.GetPage(after, MaxPageLimit)
+		if err != nil {
+			return len(saved), err
+		}
+		for i := range recipes {
+			recipe := &recipes[i]
+			u.scan.scanned(recipe.ID, func() error {
+				u.Suggester.Set(recipe.ID, recipePhrases(recipe))
+				return nil
+			})
+			saved[recipe.ID] = true
+		}
+		if !more {
+			break
+		}
+		after = recipes[len(recipes)-1].ID
+	}
+
+	for _, ID := range u.Suggester.IDs() {
+		if saved[ID] {
+			continue
+		}
+		ID := ID
+		u.scan.scanned(ID, func() error {
+			u.Suggester.Delete(ID)
+			return nil
+		})
+	}
+	return len(saved), nil
+}
+
+// RebuildEvery runs Rebuild every interval, which must be positive, so that
+// suggestions catch up with recipes written through other servers. It never
+// returns, so it is meant to be run in its own goroutine.
+func (u *RecipesSuggestSvc) RebuildEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := u.Rebuild(); err != nil {
+			log.Println("Failed to rebuild suggestions:", err)
+		}
+	}
+}
+
+// RecipeSaved implements RecipeListener
+func (u *RecipesSuggestSvc) RecipeSaved(recipe *resource.Recipe) {
+	u.scan.listened(recipe.ID, func() { u.Suggester.Set(recipe.ID, recipePhrases(recipe)) })
+}
+
+// RecipeDeleted implements RecipeListener
+func (u *RecipesSuggestSvc) RecipeDeleted(recipeID uint) {
+	u.scan.listened(recipeID, func() { u.Suggester.Delete(recipeID) })
+}
+
+func recipePhrases(recipe *resource.Recipe) []search.Phrase {
+	phrases := []search.Phrase{{Text: recipe.Title, Kind: "title"}}
+	for _, ingredient := range recipe.Ingredients {
+		phrases = append(phrases, search.Phrase{Text: ingredient.Name, Kind: "ingredient"})
+	}
+	for _, tag := range recipe.Tags {
+		phrases = append(phrases, search.Phrase{Text: tag, Kind: "tag"})
+	}
+	return phrases
+}
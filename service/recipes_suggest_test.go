@@ -0,0 +1,65 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+func TestRebuildSuggestions(t *testing.T) {
+	db := store.NewMemory()
+	if err := resource.CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	suggester := search.NewSuggester(RecipeSuggestWeights)
+	u := NewRecipesSuggestSvc(db, suggester)
+	if _, err := u.Rsc.Insert(&resource.Recipe{Title: "Pancakes"}); err != nil {
+		t.Fatal(err)
+	}
+	// a recipe deleted through another server still has suggestions
+	suggester.Set(99, []search.Phrase{{Text: "Waffles", Kind: "title"}})
+
+	n, err := u.Rebuild()
+	if err != nil || n != 1 {
+		t.Fatalf("rebuilt %d recipes, %v", n, err)
+	}
+	if IDs := suggester.IDs(); !reflect.DeepEqual(IDs, []uint{1}) {
+		t.Errorf("actual suggested %v, expected suggested [1]", IDs)
+	}
+}
+
+func TestRebuildKeepsListenedRecipes(t *testing.T) {
+	db := store.NewMemory()
+	if err := resource.CreateSpaces(db); err != nil {
+		t.Fatal(err)
+	}
+	suggester := search.NewSuggester(RecipeSuggestWeights)
+	u := NewRecipesSuggestSvc(db, suggester)
+	for _, title := range []string{"Pancakes", "Crepes"} {
+		if _, err := u.Rsc.Insert(&resource.Recipe{Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// pancakes are renamed, crepes deleted and scones added after the page
+	// is read
+	u.Rsc = &scanHook{u.Rsc, func() {
+		u.RecipeSaved(&resource.Recipe{ID: 1, Title: "Waffles"})
+		u.RecipeDeleted(2)
+		u.RecipeSaved(&resource.Recipe{ID: 3, Title: "Scones"})
+	}}
+
+	if _, err := u.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if IDs := suggester.IDs(); !reflect.DeepEqual(IDs, []uint{1, 3}) {
+		t.Errorf("actual suggested %v, expected suggested [1 3]", IDs)
+	}
+	for prefix, expected := range map[string]int{"waff": 1, "panc": 0} {
+		if found := suggester.Suggest(prefix, 10); len(found) != expected {
+			t.Errorf("actual %d suggestions for %q, expected %d suggestions", len(found), prefix, expected)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/units"
+)
+
+// MaxServings is the largest number of servings a recipe can be scaled to
+const MaxServings = 1000
+
+// countDens are the denominators amounts without a known unit, like eggs,
+// are rounded to
+var countDens = []int64{2, 3, 4}
+
+// Scale gets the recipe with its ingredient quantities scaled from the
+// servings of the recipe to servings
+func (u *RecipesSvc) Scale(recipeID int, servings int) (*resource.Recipe, error) {
+	if servings < 1 || servings > MaxServings {
+		return nil, resource.Invalid("servings", fmt.Sprintf("must be between 1 and %d", MaxServings))
+	}
+	recipe, err := u.Rsc.GetOne(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if recipe.Servings == 0 {
+		return nil, resource.Invalid("servings", "can't be scaled, the recipe doesn't say how many it serves")
+	}
+
+	factor := resource.NewQuantity(int64(servings), int64(recipe.Servings))
+	scaled := *recipe
+	scaled.Servings = servings
+	scaled.Ingredients = make([]resource.Ingredient, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		if scaled.Ingredients[i], err = scaleIngredient(ingredient, factor); err != nil {
+			return nil, resource.Invalid("servings", fmt.Sprintf("can't scale %s: %s", ingredient.Name, err))
+		}
+	}
+	return &scaled, nil
+}
+
+// scaleIngredient multiplies the quantity of ingredient by factor, moves it
+// to the unit it reads best in, like 3 tsp to 1 tbsp, and rounds it to a
+// fraction used in the kitchen. Temperatures don't change with servings, so
+// they are kept as they are.
+func scaleIngredient(ingredient resource.Ingredient, factor resource.Quantity) (resource.Ingredient, error) {
+	if ingredient.Quantity.IsZero() {
+		return ingredient, nil
+	}
+	unit, ok := units.Lookup(ingredient.Unit)
+	if ok && unit.Dimension == units.Temperature {
+		return ingredient, nil
+	}
+	amount := new(big.Rat).Mul(toRat(ingredient.Quantity), toRat(factor))
+
+	var err error
+	if !ok {
+		if amount, err = units.Round(amount, countDens); err != nil {
+			return ingredient, err
+		}
+		ingredient.Quantity, err = resource.QuantityOf(amount)
+		return ingredient, err
+	}
+
+	fitted, to := units.Fit(amount, unit)
+	if ingredient.Quantity, err = resource.QuantityOf(fitted); err != nil {
+		return ingredient, err
+	}
+	if to != unit {
+		ingredient.Unit = to.Name
+	}
+	return ingredient, nil
+}
+
+func toRat(q resource.Quantity) *big.Rat {
+	return big.NewRat(q.Num, q.Den)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/parser"
+	"github.com/motomux/smart-cooking-server/resource"
+)
+
+func TestScaleIngredient(t *testing.T) {
+	type (
+		in struct {
+			ingredient resource.Ingredient
+			factor     resource.Quantity
+		}
+		out struct {
+			ingredient resource.Ingredient
+			isErr      bool
+		}
+	)
+
+	q := resource.NewQuantity
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {
+			in{resource.Ingredient{Name: "salt", Quantity: q(1, 1), Unit: "tsp"}, q(3, 1)},
+			out{resource.Ingredient{Name: "salt", Quantity: q(1, 1), Unit: "tbsp"}, false},
+		},
+		"case-02": {
+			in{resource.Ingredient{Name: "flour", Quantity: q(250, 1), Unit: "g"}, q(4, 1)},
+			out{resource.Ingredient{Name: "flour", Quantity: q(1, 1), Unit: "kg"}, false},
+		},
+		// 1/2 tbsp halved reads better in teaspoons
+		"case-03": {
+			in{resource.Ingredient{Name: "oil", Quantity: q(1, 2), Unit: "tablespoons"}, q(1, 2)},
+			out{resource.Ingredient{Name: "oil", Quantity: q(3, 4), Unit: "tsp"}, false},
+		},
+		// 2/3 of a cup is kept exactly
+		"case-04": {
+			in{resource.Ingredient{Name: "milk", Quantity: q(1, 1), Unit: "cup"}, q(2, 3)},
+			out{resource.Ingredient{Name: "milk", Quantity: q(2, 3), Unit: "cup"}, false},
+		},
+		// 5/12 cup is 20% off the nearest cup fraction, so it goes down to
+		// 6 2/3 tbsp, which rounds to 6 1/2 tbsp
+		"case-05": {
+			in{resource.Ingredient{Name: "sugar", Quantity: q(5, 4), Unit: "cups"}, q(1, 3)},
+			out{resource.Ingredient{Name: "sugar", Quantity: q(13, 2), Unit: "tbsp"}, false},
+		},
+		"case-06": {
+			in{resource.Ingredient{Name: "egg", Quantity: q(3, 1)}, q(1, 2)},
+			out{resource.Ingredient{Name: "egg", Quantity: q(3, 2)}, false},
+		},
+		"case-07": {
+			in{resource.Ingredient{Name: "pepper"}, q(2, 1)},
+			out{resource.Ingredient{Name: "pepper"}, false},
+		},
+		"case-08": {
+			in{resource.Ingredient{Name: "醤油", Quantity: q(1, 1), Unit: "大さじ"}, q(1, 3)},
+			out{resource.Ingredient{Name: "醤油", Quantity: q(1, 1), Unit: "小さじ"}, false},
+		},
+		// temperatures don't change with servings
+		"case-09": {
+			in{resource.Ingredient{Name: "water", Quantity: q(40, 1), Unit: "C"}, q(2, 1)},
+			out{resource.Ingredient{Name: "water", Quantity: q(40, 1), Unit: "C"}, false},
+		},
+		"case-10": {
+			in{resource.Ingredient{Name: "egg", Quantity: q(1<<62, 1)}, q(4, 1)},
+			out{resource.Ingredient{Name: "egg", Quantity: q(1<<62, 1)}, true},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			ingredient, err := scaleIngredient(in.ingredient, in.factor)
+			if (err != nil) != out.isErr {
+				t.Fatalf("actual error %v, expected error %v", err, out.isErr)
+			}
+			if !out.isErr && !reflect.DeepEqual(ingredient, out.ingredient) {
+				t.Errorf("actual ingredient %+v, expected ingredient %+v", ingredient, out.ingredient)
+			}
+		})
+	}
+}
+
+func TestScaleParsedIngredient(t *testing.T) {
+	// "c" is a cup in ingredient lines, never Celsius
+	p := parser.Parse("1 c flour")
+	ingredient, err := scaleIngredient(p.Ingredient, resource.NewQuantity(2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := resource.Ingredient{Name: "flour", Quantity: resource.NewQuantity(2, 1), Unit: "cup"}
+	if !reflect.DeepEqual(ingredient, expected) {
+		t.Errorf("actual ingredient %+v, expected ingredient %+v", ingredient, expected)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/store"
+)
+
+// MaxShoppingRecipes is the most recipes one shopping list is made for
+const MaxShoppingRecipes = 50
+
+// ShoppingListRequest asks for a shopping list for recipes
+type ShoppingListRequest struct {
+	Name    string                    `json:"name"`
+	Recipes []resource.ShoppingRecipe `json:"recipes"`
+}
+
+// ShoppingListPage is one page of the shopping lists. Next is the cursor of
+// the following page, empty on the last page.
+type ShoppingListPage struct {
+	Lists []resource.ShoppingList
+	Next  string
+}
+
+// ShoppingListsSvcInterface is an interface to test ShoppingListsSvc
+type ShoppingListsSvcInterface interface {
+	GetOne(listID int) (*resource.ShoppingList, error)
+	List(cursor string, limit int) (*ShoppingListPage, error)
+	Create(req *ShoppingListRequest) (*resource.ShoppingList, error)
+	Delete(listID int) error
+	Check(listID, index int, checked bool) (*resource.ShoppingList, error)
+}
+
+// ShoppingListsSvc makes shopping lists out of recipes
+type ShoppingListsSvc struct {
+	Rsc     resource.ShoppingListsRscInterface
+	Recipes resource.RecipesRscInterface
+}
+
+// NewShoppingListsSvc initiates ShoppingListsSvc
+func NewShoppingListsSvc(db store.Store) *ShoppingListsSvc {
+	return &ShoppingListsSvc{
+		Rsc:     resource.NewShoppingListsRsc(db),
+		Recipes: resource.NewRecipesRsc(db),
+	}
+}
+
+// GetOne gets the shopping list with listID
+func (u *ShoppingListsSvc) GetOne(listID int) (*resource.ShoppingList, error) {
+	return u.Rsc.GetOne(listID)
+}
+
+// List gets the page of shopping lists which follows cursor
+func (u *ShoppingListsSvc) List(cursor string, limit int) (*ShoppingListPage, error) {
+	if limit < 1 || limit > MaxPageLimit {
+		return nil, resource.Invalid("limit", fmt.Sprintf("must be between 1 and %d", MaxPageLimit))
+	}
+	c, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, more, err := u.Rsc.GetPage(c.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &ShoppingListPage{Lists: lists}
+	if more {
+		page.Next = encodeCursor(pageCursor{ID: lists[len(lists)-1].ID})
+	}
+	return page, nil
+}
+
+// Create makes a shopping list with the ingredients of the recipes of req,
+// scaled to the servings asked for and summed up
+func (u *ShoppingListsSvc) Create(req *ShoppingListRequest) (*resource.ShoppingList, error) {
+	if len(req.Recipes) == 0 {
+		return nil, resource.Invalid("recipes", "is required")
+	}
+	if len(req.Recipes) > MaxShoppingRecipes {
+		return nil, resource.Invalid("recipes", fmt.Sprintf("must have at most %d recipes", MaxShoppingRecipes))
+	}
+
+	var ingredients []shoppingIngredient
+	for i, r := range req.Recipes {
+		field := fmt.Sprintf("recipes[%d].servings", i)
+		if r.Servings < 0 || r.Servings > MaxServings {
+			return nil, resource.Invalid(field, fmt.Sprintf("must be between 0 and %d", MaxServings))
+		}
+		recipe, err := u.Recipes.GetOne(int(r.RecipeID))
+		if err != nil {
+			return nil, err
+		}
+		factor := resource.NewQuantity(1, 1)
+		if r.Servings > 0 && r.Servings != recipe.Servings {
+			if recipe.Servings == 0 {
+				return nil, resource.Invalid(field, fmt.Sprintf("recipe %d doesn't say how many it serves", recipe.ID))
+			}
+			factor = resource.NewQuantity(int64(r.Servings), int64(recipe.Servings))
+		}
+		for _, ingredient := range recipe.Ingredients {
+			ingredients = append(ingredients, shoppingIngredient{ingredient, factor, recipe.ID})
+		}
+	}
+
+	items, err := mergeIngredients(ingredients)
+	if err != nil {
+		return nil, resource.Invalid("recipes", fmt.Sprintf("can't sum up the ingredients: %s", err))
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = time.Now().Format("Shopping list 2006-01-02")
+	}
+	return u.Rsc.Insert(&resource.ShoppingList{
+		Name:      name,
+		Recipes:   req.Recipes,
+		Items:     items,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// Delete deletes the shopping list with listID
+func (u *ShoppingListsSvc) Delete(listID int) error {
+	return u.Rsc.Delete(listID)
+}
+
+// Check checks the item at index of the shopping list off, or back on. An
+// item checked in between by someone else is kept: the list is read again
+// and the change retried, up to maxWriteRetries times.
+func (u *ShoppingListsSvc) Check(listID, index int, checked bool) (*resource.ShoppingList, error) {
+	for i := 0; ; i++ {
+		list, err := u.Rsc.GetOne(listID)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= len(list.Items) {
+			return nil, resource.NotFound("item %d of shopping list %d doesn't exist", index, listID)
+		}
+		if list.Items[index].Checked == checked {
+			return list, nil
+		}
+
+		items := append([]resource.ShoppingItem(nil), list.Items...)
+		items[index].Checked = checked
+		updated, err := u.Rsc.UpdateItems(listID, items, list.Items)
+		if errors.Is(err, resource.ErrConflict) && i < maxWriteRetries {
+			continue
+		}
+		return updated, err
+	}
+}
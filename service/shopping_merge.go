@@ -0,0 +1,221 @@
+package service
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/motomux/smart-cooking-server/grocery"
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/search"
+	"github.com/motomux/smart-cooking-server/units"
+)
+
+// shoppingIngredient is an ingredient of a recipe on a shopping list, and
+// the factor its quantity is scaled by
+type shoppingIngredient struct {
+	resource.Ingredient
+	factor   resource.Quantity
+	recipeID uint
+}
+
+// shoppingBucket sums up the amounts of one ingredient which add up: those
+// measured in units of one dimension, or counted in the same unit
+type shoppingBucket struct {
+	name string
+	// unit is set for measured amounts, which are summed in it
+	unit *units.Unit
+	// unitText is the unit of counted amounts
+	unitText string
+	// amount is nil until an amount is known, as for "salt to taste"
+	amount  *big.Rat
+	recipes []uint
+}
+
+// mergeIngredients sums up the ingredients of recipes into shopping items.
+// Ingredients are the same when their names analyze to the same terms, like
+// "egg" and "eggs". Amounts of the same dimension are converted before they
+// are summed, and volumes are weighed when the density of the ingredient is
+// known and it is weighed elsewhere. Items are sorted by aisle. It fails when
+// a sum is too large for a quantity.
+func mergeIngredients(ingredients []shoppingIngredient) ([]resource.ShoppingItem, error) {
+	groups := map[string][]*shoppingBucket{}
+	var keys []string
+	for _, ingredient := range ingredients {
+		key := ingredientKey(ingredient.Name)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = addToBuckets(groups[key], ingredient)
+	}
+
+	var items []resource.ShoppingItem
+	for _, key := range keys {
+		for _, b := range weighVolumes(groups[key]) {
+			item, err := b.item()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := grocery.Order(items[i].Aisle), grocery.Order(items[j].Aisle)
+		if a != b {
+			return a < b
+		}
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	return items, nil
+}
+
+func ingredientKey(name string) string {
+	var terms []string
+	for _, token := range search.Analyzers["cjk"].Analyze(name) {
+		terms = append(terms, token.Term)
+	}
+	if len(terms) == 0 {
+		return strings.ToLower(strings.TrimSpace(name))
+	}
+	return strings.Join(terms, " ")
+}
+
+func addToBuckets(buckets []*shoppingBucket, ingredient shoppingIngredient) []*shoppingBucket {
+	var amount *big.Rat
+	if !ingredient.Quantity.IsZero() {
+		amount = new(big.Rat).Mul(toRat(ingredient.Quantity), toRat(ingredient.factor))
+	}
+	unit, measured := units.Lookup(ingredient.Unit)
+	measured = measured && unit.Size != nil
+
+	var target *shoppingBucket
+	for _, b := range buckets {
+		switch {
+		case amount == nil:
+		case measured && b.unit != nil && b.unit.Dimension == unit.Dimension:
+			amount, _ = units.Convert(amount, unit, b.unit)
+		case !measured && b.unit == nil && b.amount != nil && strings.EqualFold(b.unitText, ingredient.Unit):
+		case b.amount == nil:
+			// an ingredient listed without an amount so far takes the
+			// first amount it is listed with
+			if measured {
+				b.unit, b.unitText = unit, ""
+			} else {
+				b.unit, b.unitText = nil, ingredient.Unit
+			}
+		default:
+			continue
+		}
+		target = b
+		break
+	}
+	if target == nil {
+		target = &shoppingBucket{name: strings.TrimSpace(ingredient.Name), unitText: ingredient.Unit}
+		if measured {
+			target.unit, target.unitText = unit, ""
+		}
+		buckets = append(buckets, target)
+	}
+
+	if amount != nil {
+		if target.amount == nil {
+			target.amount = new(big.Rat)
+		}
+		target.amount.Add(target.amount, amount)
+	}
+	if !containsUint(target.recipes, ingredient.recipeID) {
+		target.recipes = append(target.recipes, ingredient.recipeID)
+	}
+	return buckets
+}
+
+// weighVolumes adds a volume of an ingredient to its mass when the
+// ingredient is both weighed and measured by volume, and its density is
+// known
+func weighVolumes(buckets []*shoppingBucket) []*shoppingBucket {
+	var mass, volume *shoppingBucket
+	for _, b := range buckets {
+		if b.unit == nil || b.amount == nil {
+			continue
+		}
+		switch b.unit.Dimension {
+		case units.Mass:
+			mass = b
+		case units.Volume:
+			volume = b
+		}
+	}
+	if mass == nil || volume == nil {
+		return buckets
+	}
+	d, ok := units.DensityOf(mass.name)
+	if !ok {
+		return buckets
+	}
+
+	ml, _ := units.Convert(volume.amount, volume.unit, units.Millilitre)
+	grams := ml.Mul(ml, d.GramsPerMillilitre)
+	amount, _ := units.Convert(grams, units.Gram, mass.unit)
+	mass.amount.Add(mass.amount, amount)
+	for _, ID := range volume.recipes {
+		if !containsUint(mass.recipes, ID) {
+			mass.recipes = append(mass.recipes, ID)
+		}
+	}
+
+	merged := buckets[:0]
+	for _, b := range buckets {
+		if b != volume {
+			merged = append(merged, b)
+		}
+	}
+	return merged
+}
+
+func (b *shoppingBucket) item() (resource.ShoppingItem, error) {
+	item := resource.ShoppingItem{
+		Name:    b.name,
+		Unit:    b.unitText,
+		Aisle:   grocery.Aisle(b.name),
+		Recipes: b.recipes,
+	}
+	var err error
+	switch {
+	case b.amount == nil:
+		if b.unit != nil {
+			item.Unit = b.unit.Name
+		}
+	case b.unit != nil:
+		fitted, to := units.Fit(b.amount, b.unit)
+		item.Unit = to.Name
+		item.Quantity, err = resource.QuantityOf(fitted)
+	default:
+		// counted amounts are only ever rounded up, a third of an egg
+		// short is an egg short
+		item.Quantity, err = resource.QuantityOf(roundUp(b.amount))
+	}
+	return item, err
+}
+
+// roundUp rounds amount up to a half, or to a whole once it is above 2
+func roundUp(amount *big.Rat) *big.Rat {
+	den := int64(2)
+	if amount.Cmp(big.NewRat(2, 1)) > 0 {
+		den = 1
+	}
+	scaled := new(big.Rat).Mul(amount, big.NewRat(den, 1))
+	num := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+	if !scaled.IsInt() {
+		num.Add(num, big.NewInt(1))
+	}
+	return new(big.Rat).SetFrac(num, big.NewInt(den))
+}
+
+func containsUint(values []uint, v uint) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
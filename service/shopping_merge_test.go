@@ -0,0 +1,79 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/motomux/smart-cooking-server/resource"
+)
+
+func TestMergeIngredients(t *testing.T) {
+	q := resource.NewQuantity
+	one := q(1, 1)
+	ing := func(name string, quantity resource.Quantity, unit string, factor resource.Quantity, recipeID uint) shoppingIngredient {
+		return shoppingIngredient{resource.Ingredient{Name: name, Quantity: quantity, Unit: unit}, factor, recipeID}
+	}
+
+	type (
+		in struct {
+			ingredients []shoppingIngredient
+		}
+		out struct {
+			items []resource.ShoppingItem
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		// teaspoons and tablespoons add up, and move to a larger unit
+		"case-01": {
+			in{[]shoppingIngredient{ing("milk", q(1, 2), "cup", one, 1), ing("Milk", q(4, 1), "tbsp", one, 2)}},
+			out{[]resource.ShoppingItem{{Name: "milk", Quantity: q(3, 4), Unit: "cup", Aisle: "dairy & eggs", Recipes: []uint{1, 2}}}},
+		},
+		// counted amounts add up when written in the same unit, and round up
+		"case-02": {
+			in{[]shoppingIngredient{ing("eggs", q(3, 1), "", q(1, 2), 1), ing("egg", q(1, 1), "", one, 2)}},
+			out{[]resource.ShoppingItem{{Name: "eggs", Quantity: q(3, 1), Aisle: "dairy & eggs", Recipes: []uint{1, 2}}}},
+		},
+		// an amount to taste joins the amount given elsewhere
+		"case-03": {
+			in{[]shoppingIngredient{ing("salt", resource.Quantity{}, "", one, 1), ing("salt", q(1, 1), "tsp", one, 2)}},
+			out{[]resource.ShoppingItem{{Name: "salt", Quantity: q(1, 1), Unit: "tsp", Aisle: "spices & seasonings", Recipes: []uint{1, 2}}}},
+		},
+		// flour is weighed, so a cup of it joins the grams
+		"case-04": {
+			in{[]shoppingIngredient{ing("all-purpose flour", q(200, 1), "g", one, 1), ing("all-purpose flour", q(1, 1), "cup", one, 2)}},
+			out{[]resource.ShoppingItem{{Name: "all-purpose flour", Quantity: q(325, 1), Unit: "g", Aisle: "baking", Recipes: []uint{1, 2}}}},
+		},
+		// amounts which don't add up stay apart, and items are sorted by aisle
+		"case-05": {
+			in{[]shoppingIngredient{ing("garlic", q(2, 1), "cloves", one, 1), ing("chicken", q(1, 1), "kg", one, 1), ing("garlic", q(1, 1), "head", one, 2)}},
+			out{[]resource.ShoppingItem{
+				{Name: "garlic", Quantity: q(2, 1), Unit: "cloves", Aisle: "produce", Recipes: []uint{1}},
+				{Name: "garlic", Quantity: q(1, 1), Unit: "head", Aisle: "produce", Recipes: []uint{2}},
+				{Name: "chicken", Quantity: q(1, 1), Unit: "kg", Aisle: "meat & seafood", Recipes: []uint{1}},
+			}},
+		},
+		// a unit listed without an amount gives way to the counted amount
+		"case-06": {
+			in{[]shoppingIngredient{ing("flour", resource.Quantity{}, "cup", one, 1), ing("flour", q(2, 1), "", one, 2)}},
+			out{[]resource.ShoppingItem{{Name: "flour", Quantity: q(2, 1), Aisle: "baking", Recipes: []uint{1, 2}}}},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			items, err := mergeIngredients(in.ingredients)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(items, out.items) {
+				t.Errorf("actual items %+v, expected items %+v", items, out.items)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"github.com/motomux/smart-cooking-server/resource"
+	"github.com/motomux/smart-cooking-server/units"
+)
+
+// ConvertRecipe returns recipe with its ingredient quantities and its
+// temperatures, including those written in the text of steps, in system
+func ConvertRecipe(recipe *resource.Recipe, system units.System) *resource.Recipe {
+	converted := *recipe
+
+	converted.Ingredients = make([]resource.Ingredient, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		converted.Ingredients[i] = convertIngredient(ingredient, system)
+	}
+
+	converted.Howto = make([]resource.Step, len(recipe.Howto))
+	for i, step := range recipe.Howto {
+		step.Text = units.ConvertTemperatures(step.Text, system)
+		if t := step.Temperature; t != nil {
+			if unit, ok := units.Lookup(t.Unit); ok {
+				value, to := units.ConvertTemperature(t.Value, unit, system)
+				step.Temperature = &resource.Temperature{Value: value, Unit: to.Name}
+			}
+		}
+		converted.Howto[i] = step
+	}
+	return &converted
+}
+
+func convertIngredient(ingredient resource.Ingredient, system units.System) resource.Ingredient {
+	if ingredient.Quantity.IsZero() {
+		return ingredient
+	}
+	unit, ok := units.Lookup(ingredient.Unit)
+	if !ok {
+		return ingredient
+	}
+
+	amount, to := units.ConvertIngredient(toRat(ingredient.Quantity), unit, system, ingredient.Name)
+	if to == unit {
+		return ingredient
+	}
+	// an amount too large for a quantity is kept in its unit
+	if quantity, err := resource.QuantityOf(amount); err == nil {
+		ingredient.Quantity, ingredient.Unit = quantity, to.Name
+	}
+	return ingredient
+}
@@ -0,0 +1,497 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tarantool "github.com/tarantool/go-tarantool"
+)
+
+// States of the connection to a node
+const (
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateDisconnected = "disconnected"
+)
+
+// Roles of nodes, as they are given to NewCluster
+const (
+	RoleMaster  = "master"
+	RoleReplica = "replica"
+)
+
+// NodeStatus is the state of the connection to a node of a Cluster
+type NodeStatus struct {
+	Addr string `json:"addr"`
+	Role string `json:"role"`
+	// State is whether the node is connected
+	State string `json:"state"`
+	// Master reports whether the node takes writes: a master which isn't
+	// read-only
+	Master bool `json:"master"`
+	// Reads reports whether the node serves reads: a read-only replica
+	// which follows its master within MaxLag
+	Reads bool `json:"reads"`
+	// Since is when the node went into State
+	Since time.Time `json:"since"`
+	// Error is why the node was last disconnected, or why a connected node
+	// doesn't serve its role
+	Error string `json:"error,omitempty"`
+}
+
+// ClusterOpts configures a Cluster
+type ClusterOpts struct {
+	// Timeout is how long a request waits for its response
+	Timeout time.Duration
+	User    string
+	Pass    string
+	// MinBackoff and MaxBackoff bound the delay before connecting to a node
+	// again, which doubles with every failure and is jittered
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// CheckInterval is how often nodes are asked whether they take writes
+	// and how their replication goes, which also tells whether they are
+	// still connected
+	CheckInterval time.Duration
+	// MaxLag is how far a replica may lag behind its master before reads
+	// stop going to it, no limit when zero
+	MaxLag time.Duration
+}
+
+// luaHealth returns whether a node refuses writes, how many masters it
+// replicates from, the status of the first of them which isn't followed
+// and the largest lag in milliseconds. Tarantool 1.6 has no box.info.ro and
+// reports a single upstream, which is off without replication_source.
+const luaHealth = `
+local ro = box.info.ro
+if ro == nil then ro = box.cfg.read_only == true end
+local replication = box.info.replication or {}
+if replication.status ~= nil then replication = {{upstream = replication}} end
+local upstreams, stopped, lag = 0, '', 0
+for _, r in pairs(replication) do
+	local u = r.upstream
+	if u ~= nil and u.status ~= 'off' then
+		upstreams = upstreams + 1
+		if u.status ~= 'follow' and stopped == '' then stopped = tostring(u.status) end
+		if u.lag ~= nil and u.lag > lag then lag = u.lag end
+	end
+end
+return {ro = ro, upstreams = upstreams, stopped = stopped, lag = math.floor(lag * 1000)}
+`
+
+// health is what luaHealth returns
+type health struct {
+	ReadOnly  bool   `msgpack:"ro"`
+	Upstreams int    `msgpack:"upstreams"`
+	Stopped   string `msgpack:"stopped"`
+	LagMs     int64  `msgpack:"lag"`
+}
+
+// errNoMaster is a client error, so that resources report it as
+// unavailable
+var errNoMaster = tarantool.ClientError{Code: tarantool.ErrConnectionNotReady, Msg: "no master is connected"}
+
+// nodeConn is a connection to a node
+type nodeConn interface {
+	Store
+	Verifier
+	evaler
+	Close() error
+}
+
+// Cluster stores tuples in tarantool nodes, masters and their replicas. It
+// connects to every node, again and again for as long as it is open, and
+// asks the nodes whether they take writes, so that writes follow the
+// master when another master is promoted. Only masters take writes. Reads
+// made through Replicas go to the read-only replicas which follow their
+// master.
+type Cluster struct {
+	opts  ClusterOpts
+	nodes []*node
+	// next picks the replica of the next read
+	next uint32
+	done chan struct{}
+	once sync.Once
+
+	// rnd jitters the delays before connecting again
+	rndMu sync.Mutex
+	rnd   *rand.Rand
+
+	// dial connects to a node, and sleep waits between attempts and checks
+	dial  func(addr string) (nodeConn, error)
+	sleep func(d time.Duration) bool
+}
+
+type node struct {
+	addr string
+	// master is whether the node was given as a master rather than as a
+	// replica
+	master bool
+
+	mu     sync.RWMutex
+	status NodeStatus
+	// db is nil unless the node is connected
+	db nodeConn
+}
+
+// NewCluster initiates Cluster and starts connecting to masters and
+// replicas. Masters are preferred for writes in the order they are given,
+// as long as they take them.
+func NewCluster(masters, replicas []string, opts ClusterOpts) *Cluster {
+	c := newCluster(masters, replicas, opts)
+	c.dial = func(addr string) (nodeConn, error) {
+		conn, err := tarantool.Connect(addr, tarantool.Opts{Timeout: opts.Timeout, User: opts.User, Pass: opts.Pass})
+		if err != nil {
+			return nil, err
+		}
+		return NewTarantool(conn), nil
+	}
+	c.start()
+	return c
+}
+
+func newCluster(masters, replicas []string, opts ClusterOpts) *Cluster {
+	c := &Cluster{opts: opts, done: make(chan struct{}), rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	c.sleep = c.wait
+	now := time.Now()
+	for _, addr := range masters {
+		c.nodes = append(c.nodes, newNode(addr, true, now))
+	}
+	for _, addr := range replicas {
+		c.nodes = append(c.nodes, newNode(addr, false, now))
+	}
+	return c
+}
+
+func newNode(addr string, master bool, now time.Time) *node {
+	role := RoleReplica
+	if master {
+		role = RoleMaster
+	}
+	return &node{addr: addr, master: master, status: NodeStatus{Addr: addr, Role: role, State: StateConnecting, Since: now}}
+}
+
+func (c *Cluster) start() {
+	for _, n := range c.nodes {
+		go c.run(n)
+	}
+}
+
+// Close disconnects from every node
+func (c *Cluster) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+// Status returns the state of the connection to every node
+func (c *Cluster) Status() []NodeStatus {
+	statuses := make([]NodeStatus, len(c.nodes))
+	for i, n := range c.nodes {
+		n.mu.RLock()
+		statuses[i] = n.status
+		n.mu.RUnlock()
+	}
+	return statuses
+}
+
+// WaitMaster blocks until a master is connected or the cluster is closed
+func (c *Cluster) WaitMaster() error {
+	for {
+		if _, err := c.master(); err == nil {
+			return nil
+		}
+		if !c.wait(50 * time.Millisecond) {
+			return errNoMaster
+		}
+	}
+}
+
+// Replicas returns a store which reads from a replica which serves reads,
+// or from the master when none does, and writes to the master. Its reads may lag
+// behind writes, so it doesn't suit reads which are written back.
+func (c *Cluster) Replicas() Store {
+	return replicaReads{c}
+}
+
+// Select implements Store with the master
+func (c *Cluster) Select(space, index string, offset, limit uint32, iterator Iterator, key []interface{}, result interface{}) error {
+	db, err := c.master()
+	if err != nil {
+		return err
+	}
+	return db.Select(space, index, offset, limit, iterator, key, result)
+}
+
+// Insert implements Store
+func (c *Cluster) Insert(space string, tuple, result interface{}) error {
+	db, err := c.master()
+	if err != nil {
+		return err
+	}
+	return db.Insert(space, tuple, result)
+}
+
+// Replace implements Store
+func (c *Cluster) Replace(space string, tuple, result interface{}) error {
+	db, err := c.master()
+	if err != nil {
+		return err
+	}
+	return db.Replace(space, tuple, result)
+}
+
+// Update implements Store
+func (c *Cluster) Update(space, index string, key, ops []interface{}, result interface{}) error {
+	db, err := c.master()
+	if err != nil {
+		return err
+	}
+	return db.Update(space, index, key, ops, result)
+}
+
+// Delete implements Store
+func (c *Cluster) Delete(space, index string, key []interface{}, result interface{}) error {
+	db, err := c.master()
+	if err != nil {
+		return err
+	}
+	return db.Delete(space, index, key, result)
+}
+
+// NextValue implements Store
+func (c *Cluster) NextValue(sequence string) (uint, error) {
+	db, err := c.master()
+	if err != nil {
+		return 0, err
+	}
+	return db.NextValue(sequence)
+}
+
+// CreateSpace implements Store
+func (c *Cluster) CreateSpace(def *Space) error {
+	db, err := c.master()
+	if err != nil {
+		return err
+	}
+	return db.CreateSpace(def)
+}
+
+// HasIndex implements Store with the schema of the master
+func (c *Cluster) HasIndex(space, index string) bool {
+	db, err := c.master()
+	return err == nil && db.HasIndex(space, index)
+}
+
+// Eval evaluates Lua on the master, like Tarantool.Eval
+func (c *Cluster) Eval(expr string, args []interface{}, result interface{}) error {
+	db, err := c.master()
+	if err != nil {
+		return err
+	}
+	return db.Eval(expr, args, result)
+}
+
+// Verify implements Verifier with the schema of the master
+func (c *Cluster) Verify(defs []*Space) error {
+	db, err := c.master()
+	if err != nil {
+		return err
+	}
+	return db.Verify(defs)
+}
+
+// master returns the first connected master which takes writes
+func (c *Cluster) master() (nodeConn, error) {
+	for _, n := range c.nodes {
+		n.mu.RLock()
+		db, master := n.db, n.status.Master
+		n.mu.RUnlock()
+		if db != nil && master {
+			return db, nil
+		}
+	}
+	return nil, errNoMaster
+}
+
+// replica returns a connected replica which serves reads, taking turns, or
+// the master when none does
+func (c *Cluster) replica() (Store, error) {
+	var replicas []Store
+	for _, n := range c.nodes {
+		n.mu.RLock()
+		if n.db != nil && n.status.Reads {
+			replicas = append(replicas, n.db)
+		}
+		n.mu.RUnlock()
+	}
+	if len(replicas) == 0 {
+		return c.master()
+	}
+	i := atomic.AddUint32(&c.next, 1)
+	return replicas[int(i)%len(replicas)], nil
+}
+
+// run keeps n connected until the cluster is closed
+func (c *Cluster) run(n *node) {
+	backoff := c.opts.MinBackoff
+	for {
+		conn, err := c.dial(n.addr)
+		var h health
+		if err == nil {
+			if h, err = checkHealth(conn); err != nil {
+				conn.Close()
+			}
+		}
+		if err != nil {
+			delay := c.jitter(backoff)
+			log.Printf("Failed to connect to tarantool at %s, retrying in %s: %s", n.addr, delay, err)
+			n.disconnected(err)
+			if !c.sleep(delay) {
+				return
+			}
+			if backoff *= 2; backoff > c.opts.MaxBackoff {
+				backoff = c.opts.MaxBackoff
+			}
+			continue
+		}
+
+		log.Printf("Connected to tarantool at %s", n.addr)
+		backoff = c.opts.MinBackoff
+		n.connected(conn)
+		n.checked(h, c.opts.MaxLag)
+		for c.sleep(c.opts.CheckInterval) {
+			if h, err = checkHealth(conn); err != nil {
+				break
+			}
+			n.checked(h, c.opts.MaxLag)
+		}
+		if err == nil {
+			// the cluster was closed
+			conn.Close()
+			n.disconnected(nil)
+			return
+		}
+		// closing waits for the client when it is dialing again, which can
+		// take as long as the system lets a dial take
+		go conn.Close()
+		log.Printf("Lost connection to tarantool at %s: %s", n.addr, err)
+		n.disconnected(err)
+	}
+}
+
+// wait waits for d, and reports whether the cluster is still open
+func (c *Cluster) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// jitter returns a random delay between half of d and d, so that servers
+// which lost a node together don't reconnect together
+func (c *Cluster) jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	c.rndMu.Lock()
+	defer c.rndMu.Unlock()
+	return d/2 + time.Duration(c.rnd.Int63n(int64(d/2)+1))
+}
+
+func checkHealth(conn nodeConn) (health, error) {
+	var hs []health
+	if err := conn.Eval(luaHealth, []interface{}{}, &hs); err != nil {
+		return health{}, err
+	}
+	if len(hs) == 0 {
+		return health{}, errors.New("tarantool returned no health")
+	}
+	return hs[0], nil
+}
+
+func (n *node) connected(conn nodeConn) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.db = conn
+	n.status.State, n.status.Since, n.status.Error = StateConnected, time.Now(), ""
+}
+
+// checked sets the role n serves from its health. A master serves writes
+// unless it is read-only, and a replica serves reads when it is read-only
+// and follows its master within maxLag.
+func (n *node) checked(h health, maxLag time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var problem string
+	if n.master {
+		if h.ReadOnly {
+			problem = "read-only"
+		}
+		switch master := problem == ""; {
+		case master && !n.status.Master:
+			log.Printf("Tarantool at %s takes writes now", n.addr)
+		case !master && n.status.Master:
+			log.Printf("Tarantool at %s no longer takes writes", n.addr)
+		}
+		n.status.Master = problem == ""
+	} else {
+		lag := time.Duration(h.LagMs) * time.Millisecond
+		switch {
+		case !h.ReadOnly:
+			problem = "writable replica"
+		case h.Upstreams == 0:
+			problem = "replicates from no master"
+		case h.Stopped != "":
+			problem = "replication is " + h.Stopped
+		case maxLag > 0 && lag > maxLag:
+			problem = fmt.Sprintf("replication lags %s behind", lag)
+		}
+		switch reads := problem == ""; {
+		case !h.ReadOnly && n.status.Error != problem:
+			log.Printf("WARNING: tarantool replica at %s takes writes, which may split the data from its master; reads no longer go to it", n.addr)
+		case reads && !n.status.Reads:
+			log.Printf("Tarantool at %s serves reads now", n.addr)
+		case !reads && n.status.Reads:
+			log.Printf("Tarantool at %s no longer serves reads: %s", n.addr, problem)
+		}
+		n.status.Reads = problem == ""
+	}
+	n.status.Error = problem
+}
+
+func (n *node) disconnected(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.db = nil
+	if n.status.State != StateDisconnected {
+		n.status.Since = time.Now()
+	}
+	n.status.State, n.status.Master, n.status.Reads = StateDisconnected, false, false
+	n.status.Error = ""
+	if err != nil {
+		n.status.Error = err.Error()
+	}
+}
+
+// replicaReads sends selects to replicas and everything else to the master
+type replicaReads struct {
+	*Cluster
+}
+
+// Select implements Store with a replica
+func (r replicaReads) Select(space, index string, offset, limit uint32, iterator Iterator, key []interface{}, result interface{}) error {
+	db, err := r.replica()
+	if err != nil {
+		return err
+	}
+	return db.Select(space, index, offset, limit, iterator, key, result)
+}
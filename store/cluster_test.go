@@ -0,0 +1,258 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConn is a connection to a node over Memory, whose health tests set.
+// It fails to dial and to check while err is set, like a node which is
+// down.
+type fakeConn struct {
+	*Memory
+
+	mu     sync.Mutex
+	health health
+	err    error
+}
+
+func (f *fakeConn) set(h health, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.health, f.err = h, err
+}
+
+func (f *fakeConn) dial(addr string) (nodeConn, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f, nil
+}
+
+func (f *fakeConn) Eval(expr string, args []interface{}, result interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	*result.(*[]health) = []health{f.health}
+	return nil
+}
+
+func (f *fakeConn) Verify(defs []*Space) error {
+	return nil
+}
+
+func (f *fakeConn) Close() error {
+	return nil
+}
+
+// waitFor polls cond until it holds, failing after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestClusterRouting(t *testing.T) {
+	// healths by what nodes report, "" being disconnected
+	healths := map[string]health{
+		"rw":      {Upstreams: 1},
+		"ro":      {ReadOnly: true, Upstreams: 1},
+		"lag":     {ReadOnly: true, Upstreams: 1, LagMs: 5000},
+		"stopped": {ReadOnly: true, Upstreams: 1, Stopped: "disconnected"},
+		"alone":   {ReadOnly: true},
+	}
+
+	type (
+		in struct {
+			masters  []string
+			replicas []string
+		}
+		out struct {
+			// write is the node written to, -1 when writes fail
+			write int
+			// reads are the nodes read from, none when reads fail
+			reads []int
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{[]string{"rw"}, []string{"ro", "ro"}}, out{0, []int{1, 2}}},
+		"case-02": {in{[]string{"rw"}, []string{"", "ro"}}, out{0, []int{2}}},
+		// a master promoted after the first one was demoted takes the writes
+		"case-03": {in{[]string{"ro", "rw"}, []string{"ro"}}, out{1, []int{2}}},
+		// reads go to the master while no replica serves them
+		"case-04": {in{[]string{"rw"}, []string{"", ""}}, out{0, []int{0}}},
+		"case-05": {in{[]string{""}, []string{"ro"}}, out{-1, []int{1}}},
+		// a writable replica takes neither writes nor reads
+		"case-06": {in{[]string{"rw"}, []string{"rw", "ro"}}, out{0, []int{2}}},
+		"case-07": {in{[]string{"ro"}, []string{"rw"}}, out{-1, nil}},
+		// replicas which lag, stopped replicating or never did serve no reads
+		"case-08": {in{[]string{"rw"}, []string{"lag", "stopped", "alone"}}, out{0, []int{0}}},
+		"case-09": {in{[]string{"rw"}, []string{"lag", "ro"}}, out{0, []int{2}}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			var masters, replicas []string
+			for i := range in.masters {
+				masters = append(masters, fmt.Sprintf("db-%d:3301", i))
+			}
+			for i := range in.replicas {
+				replicas = append(replicas, fmt.Sprintf("db-%d:3301", len(in.masters)+i))
+			}
+			c := newCluster(masters, replicas, ClusterOpts{MaxLag: time.Second})
+			var mems []*Memory
+			for i, state := range append(append([]string(nil), in.masters...), in.replicas...) {
+				m := newItems(t)
+				if err := m.Insert("items", []interface{}{100, "node", i}, nil); err != nil {
+					t.Fatal(err)
+				}
+				if state != "" {
+					c.nodes[i].connected(&fakeConn{Memory: m})
+					c.nodes[i].checked(healths[state], c.opts.MaxLag)
+				}
+				mems = append(mems, m)
+			}
+
+			write := -1
+			if err := c.Insert("items", []interface{}{200, "written", 0}, nil); err == nil {
+				for i, m := range mems {
+					var rows [][]interface{}
+					m.Select("items", "primary", 0, 1, IterEq, []interface{}{200}, &rows)
+					if len(rows) > 0 {
+						write = i
+					}
+				}
+			}
+			if write != out.write {
+				t.Errorf("actual write %d, expected write %d", write, out.write)
+			}
+
+			read := map[int]bool{}
+			for i := 0; i < 2*len(mems); i++ {
+				var rows [][]interface{}
+				if err := c.Replicas().Select("items", "primary", 0, 1, IterEq, []interface{}{100}, &rows); err != nil {
+					break
+				}
+				n, _ := toInt(rows[0][2])
+				read[n] = true
+			}
+			var reads []int
+			for n := range read {
+				reads = append(reads, n)
+			}
+			sort.Ints(reads)
+			if !reflect.DeepEqual(reads, out.reads) {
+				t.Errorf("actual reads %v, expected reads %v", reads, out.reads)
+			}
+		})
+	}
+}
+
+func TestClusterReconnect(t *testing.T) {
+	opts := ClusterOpts{MinBackoff: 100 * time.Millisecond, MaxBackoff: 400 * time.Millisecond, CheckInterval: time.Millisecond}
+	conn := &fakeConn{Memory: newItems(t)}
+	conn.set(health{}, errors.New("connection refused"))
+
+	c := newCluster([]string{"db-0:3301"}, nil, opts)
+	c.dial = conn.dial
+	// delays before connecting again are recorded rather than waited for
+	var mu sync.Mutex
+	var delays []time.Duration
+	c.sleep = func(d time.Duration) bool {
+		if d != opts.CheckInterval {
+			mu.Lock()
+			delays = append(delays, d)
+			mu.Unlock()
+		}
+		return c.wait(time.Millisecond)
+	}
+	recorded := func() []time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]time.Duration(nil), delays...)
+	}
+	status := func() NodeStatus {
+		return c.Status()[0]
+	}
+	c.start()
+	defer c.Close()
+
+	// the backoff doubles from MinBackoff up to MaxBackoff, jittered down
+	// to half
+	checkBackoff := func() {
+		t.Helper()
+		for i, d := range recorded() {
+			max := opts.MaxBackoff
+			if i < 2 {
+				max = opts.MinBackoff << uint(i)
+			}
+			if d < max/2 || d > max {
+				t.Errorf("delay %d is %s, expected between %s and %s", i, d, max/2, max)
+			}
+		}
+	}
+
+	waitFor(t, "retries", func() bool { return len(recorded()) >= 5 })
+	if s := status(); s.State != StateDisconnected || s.Error != "connection refused" {
+		t.Errorf("actual status %+v while the node is down", s)
+	}
+	checkBackoff()
+
+	conn.set(health{}, nil)
+	waitFor(t, "the master", func() bool { return status().Master })
+	if err := c.Insert("items", []interface{}{201, "written", 0}, nil); err != nil {
+		t.Errorf("actual error %v writing to the master", err)
+	}
+
+	// a master which turns read-only is demoted, and promoted again when it
+	// takes writes
+	conn.set(health{ReadOnly: true}, nil)
+	waitFor(t, "the demotion", func() bool { return !status().Master })
+	if err := c.Insert("items", []interface{}{202, "written", 0}, nil); err != errNoMaster {
+		t.Errorf("actual error %v writing to a read-only master, expected %v", err, errNoMaster)
+	}
+	conn.set(health{}, nil)
+	waitFor(t, "the promotion", func() bool { return status().Master })
+
+	// a lost connection is dialed again, starting over from MinBackoff
+	mu.Lock()
+	delays = nil
+	mu.Unlock()
+	conn.set(health{}, errors.New("connection reset"))
+	waitFor(t, "retries", func() bool { return len(recorded()) >= 3 })
+	checkBackoff()
+	conn.set(health{}, nil)
+	waitFor(t, "the reconnection", func() bool { return status().Master })
+	if s := status(); s.State != StateConnected || s.Role != RoleMaster || s.Error != "" {
+		t.Errorf("actual status %+v after reconnecting", s)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	c := newCluster(nil, nil, ClusterOpts{})
+	for _, d := range []time.Duration{0, 1, 2, 100, time.Second} {
+		for i := 0; i < 100; i++ {
+			if j := c.jitter(d); j < d/2 || j > d {
+				t.Fatalf("jitter of %d is %d", d, j)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"fmt"
+)
+
+// atomicStore is a store which applies several operations atomically, like
+// Memory and File
+type atomicStore interface {
+	Atomic(fn func(tx Store) error) error
+}
+
+// evaler is a store which evaluates Lua on tarantool, like Tarantool and
+// Cluster
+type evaler interface {
+	Eval(expr string, args []interface{}, result interface{}) error
+}
+
+// luaEqual compares values like equalValues. Memtx doesn't yield between
+// the get and the write which follow it, so nothing is written in between.
+const luaEqual = `
+local function empty(v)
+    return v == nil or type(v) == 'table' and next(v) == nil
+end
+local function equal(a, b)
+    if empty(a) and empty(b) then return true end
+    if type(a) ~= 'table' or type(b) ~= 'table' then return a == b end
+    for k, v in pairs(a) do
+        if not equal(v, b[k]) then return false end
+    end
+    for k, v in pairs(b) do
+        if not equal(v, a[k]) then return false end
+    end
+    return true
+end
+`
+
+const luaUpdateIf = luaEqual + `
+local space, index, key, field, value, ops = ...
+local t = box.space[space].index[index]:get(key)
+if t == nil or not equal(t[field], value) then return end
+return box.space[space].index[index]:update(key, ops)
+`
+
+const luaReplaceIf = luaEqual + `
+local space, index, key, field, value, tuple = ...
+local t = box.space[space].index[index]:get(key)
+if t == nil or not equal(t[field], value) then return end
+return box.space[space]:replace(tuple)
+`
+
+const luaDeleteIf = luaEqual + `
+local space, index, key, field, value = ...
+local t = box.space[space].index[index]:get(key)
+if t == nil or not equal(t[field], value) then return end
+return box.space[space].index[index]:delete(key)
+`
+
+// UpdateIf applies update operations to the tuple with key of a unique
+// index, like Update, only while its field is equal to value, so that a
+// tuple read, changed and written back doesn't overwrite a write made in
+// between. Arrays and maps are equal when their contents are, nil is equal
+// to an empty array or map, and a field the tuple lacks is nil. Nothing is
+// read when there is no such tuple or the field isn't equal to value.
+func UpdateIf(db Store, space, index string, key []interface{}, field int, value interface{}, ops []interface{}, result interface{}) error {
+	switch db := db.(type) {
+	case atomicStore:
+		return db.Atomic(func(tx Store) error {
+			ok, err := fieldEquals(tx, space, index, key, field, value)
+			if err != nil || !ok {
+				return err
+			}
+			return tx.Update(space, index, key, ops, result)
+		})
+	case evaler:
+		// fields are numbered from 1 in Lua
+		luaOps := make([]interface{}, len(ops))
+		for i, o := range ops {
+			op, ok := o.([]interface{})
+			if !ok || len(op) < 2 {
+				return fmt.Errorf("update operation %v isn't an array {op, field, ...}", o)
+			}
+			n, ok := op[1].(int)
+			if !ok {
+				return fmt.Errorf("update operation %v doesn't have an int field", o)
+			}
+			luaOp := append([]interface{}(nil), op...)
+			luaOp[1] = n + 1
+			luaOps[i] = luaOp
+		}
+		return db.Eval(luaUpdateIf, []interface{}{space, index, key, field + 1, value, luaOps}, result)
+	}
+	return fmt.Errorf("%T doesn't support conditional writes", db)
+}
+
+// ReplaceIf overwrites the tuple with key of a unique index with tuple, like
+// Replace, only while its field is equal to value, with the rules of
+// UpdateIf. Unlike Replace, it never inserts a tuple. Nothing is read when
+// there is no such tuple or the field isn't equal to value.
+func ReplaceIf(db Store, space, index string, key []interface{}, field int, value interface{}, tuple, result interface{}) error {
+	switch db := db.(type) {
+	case atomicStore:
+		return db.Atomic(func(tx Store) error {
+			ok, err := fieldEquals(tx, space, index, key, field, value)
+			if err != nil || !ok {
+				return err
+			}
+			return tx.Replace(space, tuple, result)
+		})
+	case evaler:
+		return db.Eval(luaReplaceIf, []interface{}{space, index, key, field + 1, value, tuple}, result)
+	}
+	return fmt.Errorf("%T doesn't support conditional writes", db)
+}
+
+// DeleteIf deletes the tuple with key of a unique index, like Delete, only
+// while its field is equal to value, with the rules of UpdateIf. Nothing is
+// read when there is no such tuple or the field isn't equal to value.
+func DeleteIf(db Store, space, index string, key []interface{}, field int, value interface{}, result interface{}) error {
+	switch db := db.(type) {
+	case atomicStore:
+		return db.Atomic(func(tx Store) error {
+			ok, err := fieldEquals(tx, space, index, key, field, value)
+			if err != nil || !ok {
+				return err
+			}
+			return tx.Delete(space, index, key, result)
+		})
+	case evaler:
+		return db.Eval(luaDeleteIf, []interface{}{space, index, key, field + 1, value}, result)
+	}
+	return fmt.Errorf("%T doesn't support conditional writes", db)
+}
+
+func fieldEquals(db Store, space, index string, key []interface{}, field int, value interface{}) (bool, error) {
+	var rows []tuple
+	if err := db.Select(space, index, 0, 1, IterEq, key, &rows); err != nil || len(rows) == 0 {
+		return false, err
+	}
+	var v interface{}
+	if field < len(rows[0]) {
+		v = rows[0][field]
+	}
+	expected, err := decodeTuple([]interface{}{value})
+	if err != nil {
+		return false, err
+	}
+	return equalValues(v, expected[0]), nil
+}
+
+// equalValues reports whether a and b are equal, arrays and maps by their
+// contents, nil being equal to an empty array or map
+func equalValues(a, b interface{}) bool {
+	if isEmpty(a) && isEmpty(b) {
+		return true
+	}
+	switch a := a.(type) {
+	case []interface{}:
+		bs, ok := b.([]interface{})
+		if !ok || len(a) != len(bs) {
+			return false
+		}
+		for i := range a {
+			if !equalValues(a[i], bs[i]) {
+				return false
+			}
+		}
+		return true
+	case map[interface{}]interface{}:
+		bm, ok := b.(map[interface{}]interface{})
+		if !ok || len(a) != len(bm) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := bm[k]
+			if !ok || !equalValues(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	switch b.(type) {
+	case []interface{}, map[interface{}]interface{}:
+		return false
+	}
+	return compareValues(a, b) == 0
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	case map[interface{}]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
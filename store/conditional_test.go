@@ -0,0 +1,118 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestUpdateIf(t *testing.T) {
+	type (
+		in struct {
+			tuple []interface{}
+			field int
+			value interface{}
+		}
+		out struct {
+			updated bool
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{[]interface{}{1, "a", 1}, 2, 1}, out{true}},
+		"case-02": {in{[]interface{}{1, "a", 1}, 2, 2}, out{false}},
+		"case-03": {in{[]interface{}{1, "a", 1, []interface{}{"x", 1}}, 3, []interface{}{"x", 1}}, out{true}},
+		"case-04": {in{[]interface{}{1, "a", 1, []interface{}{"x", 1}}, 3, []interface{}{"x", 2}}, out{false}},
+		"case-05": {in{[]interface{}{1, "a", 1, map[string]bool{"x": true, "y": false}}, 3, map[string]bool{"y": false, "x": true}}, out{true}},
+		"case-06": {in{[]interface{}{1, "a", 1, map[string]bool{"x": true}}, 3, map[string]bool{"x": false}}, out{false}},
+		// a missing field is nil, which is equal to an empty array
+		"case-07": {in{[]interface{}{1, "a", 1}, 3, nil}, out{true}},
+		"case-08": {in{[]interface{}{1, "a", 1, []interface{}{}}, 3, nil}, out{true}},
+		"case-09": {in{[]interface{}{1, "a", 1, []interface{}{}}, 3, 0}, out{false}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			m := newItems(t)
+			if err := m.Replace("items", in.tuple, nil); err != nil {
+				t.Fatal(err)
+			}
+			var rows [][]interface{}
+			ops := []interface{}{[]interface{}{"=", 1, "updated"}}
+			if err := UpdateIf(m, "items", "primary", []interface{}{1}, in.field, in.value, ops, &rows); err != nil {
+				t.Fatal(err)
+			}
+			if updated := len(rows) > 0; updated != out.updated {
+				t.Errorf("actual updated %v, expected updated %v", updated, out.updated)
+			}
+			rows = nil
+			if err := m.Select("items", "primary", 0, 1, IterEq, []interface{}{1}, &rows); err != nil {
+				t.Fatal(err)
+			}
+			if updated := rows[0][1] == "updated"; updated != out.updated {
+				t.Errorf("actual stored %v, expected updated %v", rows[0], out.updated)
+			}
+		})
+	}
+}
+
+func TestReplaceIf(t *testing.T) {
+	m := newItems(t)
+	if err := m.Replace("items", []interface{}{1, "a", 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows [][]interface{}
+	if err := ReplaceIf(m, "items", "primary", []interface{}{1}, 2, 2, []interface{}{1, "b", 2}, &rows); err != nil || len(rows) != 0 {
+		t.Fatalf("replaced a tuple whose field differs: %v, %v", rows, err)
+	}
+	if err := ReplaceIf(m, "items", "primary", []interface{}{1}, 2, 1, []interface{}{1, "b", 2}, &rows); err != nil || len(rows) != 1 {
+		t.Fatalf("didn't replace a tuple whose field is equal: %v, %v", rows, err)
+	}
+	// a tuple which doesn't exist isn't inserted
+	rows = nil
+	if err := ReplaceIf(m, "items", "primary", []interface{}{9}, 2, nil, []interface{}{9, "c", 1}, &rows); err != nil || len(rows) != 0 {
+		t.Fatalf("inserted a tuple: %v, %v", rows, err)
+	}
+	if err := m.Select("items", "primary", 0, 1, IterEq, []interface{}{9}, &rows); err != nil || len(rows) != 0 {
+		t.Errorf("actual rows %v, %v, expected none", rows, err)
+	}
+}
+
+func TestDeleteIf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f, err := OpenFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createItems(t, f)
+
+	var rows [][]interface{}
+	if err := DeleteIf(f, "items", "primary", []interface{}{1}, 1, "b", &rows); err != nil || len(rows) != 0 {
+		t.Fatalf("deleted a tuple whose field differs: %v, %v", rows, err)
+	}
+	if err := DeleteIf(f, "items", "primary", []interface{}{1}, 1, "a", &rows); err != nil || len(rows) != 1 {
+		t.Fatalf("didn't delete a tuple whose field is equal: %v, %v", rows, err)
+	}
+
+	// the delete is logged
+	f.Close()
+	f, err = OpenFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows = nil
+	if err := f.Select("items", "primary", 0, 1, IterEq, []interface{}{1}, &rows); err != nil || len(rows) != 0 {
+		t.Errorf("delete wasn't logged: %v, %v", rows, err)
+	}
+}
@@ -0,0 +1,588 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+// DefaultCompactSize is the size of the log past which File compacts it
+const DefaultCompactSize = 16 << 20
+
+// Files in the directory of File are named by their generation, in hex,
+// but for the lock file
+const (
+	snapshotExt = ".snap"
+	logExt      = ".wal"
+	lockName    = "LOCK"
+)
+
+var errClosed = errors.New("store is closed")
+
+// File stores tuples in memory like Memory, and keeps them in a directory
+// so that they outlive the process. Every write is appended to a log and
+// synced before it returns, and the log is compacted into a snapshot once it
+// grows past CompactSize. A write torn by a crash is dropped when the store
+// is opened again, while a log damaged anywhere else fails to open. When a
+// write can't be logged, the store refuses writes from then on, and it has
+// to be opened again. Only one process can open a directory at a time.
+type File struct {
+	// CompactSize is the size of the log, in bytes, past which it is
+	// compacted into a snapshot
+	CompactSize int64
+
+	mu   sync.Mutex
+	mem  *Memory
+	dir  string
+	lock *os.File
+	// gen is the generation of the log. The log starts from the snapshot
+	// of the same generation, or from the logs of the generations before
+	// while that snapshot is being written.
+	gen  uint64
+	log  *os.File
+	size int64
+	// compacting is true while a snapshot is written in the background
+	compacting  bool
+	compactions sync.WaitGroup
+	// err fails writes once the log may be behind memory
+	err error
+}
+
+// OpenFile opens the store kept in dir, creating dir when it doesn't exist
+func OpenFile(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{CompactSize: DefaultCompactSize, mem: NewMemory(), dir: dir, lock: lock}
+	if err := f.open(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// open loads the latest snapshot and replays the logs from its generation
+func (f *File) open() error {
+	snaps, err := f.generations(snapshotExt)
+	if err != nil {
+		return err
+	}
+	var base uint64
+	if len(snaps) > 0 {
+		base = snaps[len(snaps)-1]
+		if err := f.loadSnapshot(base); err != nil {
+			return err
+		}
+	}
+	logs, err := f.generations(logExt)
+	if err != nil {
+		return err
+	}
+	f.gen = base
+	for i, gen := range logs {
+		if gen < base {
+			continue
+		}
+		// only the log written last can have been torn by a crash
+		if err := f.replay(gen, i == len(logs)-1); err != nil {
+			return err
+		}
+		f.gen = gen
+	}
+	if f.log, f.size, err = openLog(f.path(f.gen, logExt)); err != nil {
+		return err
+	}
+	f.removeStale(base)
+	return nil
+}
+
+// Close closes the log, once a snapshot being written is done, and lets
+// other processes open the store. Writes fail once the store is closed.
+func (f *File) Close() error {
+	f.compactions.Wait()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err == errClosed {
+		return nil
+	}
+	f.err = errClosed
+	err := f.log.Close()
+	if lerr := f.lock.Close(); err == nil {
+		err = lerr
+	}
+	return err
+}
+
+// Atomic calls fn with a store whose writes are applied and logged all
+// together when fn returns nil, and dropped otherwise, like Memory.Atomic
+func (f *File) Atomic(fn func(tx Store) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	err := f.mem.Atomic(func(tx Store) error {
+		r := newRecorder(tx)
+		if err := fn(r); err != nil {
+			return err
+		}
+		return f.append(r)
+	})
+	if err != nil {
+		return err
+	}
+	f.compactIfLarge()
+	return nil
+}
+
+// Select implements Store
+func (f *File) Select(space, index string, offset, limit uint32, iterator Iterator, key []interface{}, result interface{}) error {
+	return f.mem.Select(space, index, offset, limit, iterator, key, result)
+}
+
+// Insert implements Store
+func (f *File) Insert(space string, tuple, result interface{}) error {
+	return f.write(func(tx Store) error {
+		return tx.Insert(space, tuple, result)
+	})
+}
+
+// Replace implements Store
+func (f *File) Replace(space string, tuple, result interface{}) error {
+	return f.write(func(tx Store) error {
+		return tx.Replace(space, tuple, result)
+	})
+}
+
+// Update implements Store
+func (f *File) Update(space, index string, key, ops []interface{}, result interface{}) error {
+	return f.write(func(tx Store) error {
+		return tx.Update(space, index, key, ops, result)
+	})
+}
+
+// Delete implements Store
+func (f *File) Delete(space, index string, key []interface{}, result interface{}) error {
+	return f.write(func(tx Store) error {
+		return tx.Delete(space, index, key, result)
+	})
+}
+
+// NextValue implements Store
+func (f *File) NextValue(sequence string) (uint, error) {
+	var v uint
+	err := f.write(func(tx Store) error {
+		var err error
+		v, err = tx.NextValue(sequence)
+		return err
+	})
+	return v, err
+}
+
+// CreateSpace implements Store. Unlike the other writes it may change
+// several things, so it is applied atomically.
+func (f *File) CreateSpace(def *Space) error {
+	return f.Atomic(func(tx Store) error {
+		return tx.CreateSpace(def)
+	})
+}
+
+// HasIndex implements Store
+func (f *File) HasIndex(space, index string) bool {
+	return f.mem.HasIndex(space, index)
+}
+
+// write applies fn to memory and logs its writes. Single writes to Memory
+// change nothing when they fail, so they need no transaction.
+func (f *File) write(fn func(tx Store) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	r := newRecorder(f.mem)
+	if err := fn(r); err != nil {
+		return err
+	}
+	if err := f.append(r); err != nil {
+		return err
+	}
+	f.compactIfLarge()
+	return nil
+}
+
+// append writes the entries r recorded to the log as one record and syncs
+// it. Once that fails the log may hold part of the record, or be behind
+// memory, so the store stops taking writes.
+func (f *File) append(r *recorder) error {
+	if r.err != nil {
+		f.err = r.err
+		return r.err
+	}
+	if r.buf.Len() == 0 {
+		return nil
+	}
+	record := frame(r.buf.Bytes())
+	if _, err := f.log.Write(record); err != nil {
+		f.err = err
+		return err
+	}
+	if err := f.log.Sync(); err != nil {
+		f.err = err
+		return err
+	}
+	f.size += int64(len(record))
+	return nil
+}
+
+func (f *File) compactIfLarge() {
+	if f.size <= f.CompactSize || f.compacting {
+		return
+	}
+	if err := f.compact(); err != nil {
+		log.Printf("Failed to compact the log in %s: %s", f.dir, err)
+	}
+}
+
+// compact starts an empty log of the next generation and writes a snapshot
+// of memory as it is at its start in the background, so that writes go on
+// meanwhile. Until the snapshot is in place the store opens from the
+// previous snapshot and replays the logs of both generations.
+func (f *File) compact() error {
+	gen := f.gen + 1
+	next, _, err := openLog(f.path(gen, logExt))
+	if err != nil {
+		return err
+	}
+	snap := f.mem.snapshot()
+
+	f.log.Close()
+	f.log, f.size = next, 0
+	f.gen = gen
+	f.compacting = true
+	f.compactions.Add(1)
+	go func() {
+		defer f.compactions.Done()
+		err := f.writeSnapshot(gen, snap)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.compacting = false
+		if err != nil {
+			log.Printf("Failed to compact the log in %s: %s", f.dir, err)
+			return
+		}
+		f.removeStale(gen)
+	}()
+	return nil
+}
+
+func (f *File) writeSnapshot(gen uint64, snap *snapshot) error {
+	payload, err := msgpack.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return writeFileSync(f.path(gen, snapshotExt), frame(payload))
+}
+
+func (f *File) loadSnapshot(gen uint64) error {
+	path := f.path(gen, snapshotExt)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	payload, err := readRecord(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		return fmt.Errorf("reading %s: %s", path, err)
+	}
+	var snap snapshot
+	if err := msgpack.Unmarshal(payload, &snap); err != nil {
+		return fmt.Errorf("reading %s: %s", path, err)
+	}
+	if err := f.mem.restore(&snap); err != nil {
+		return fmt.Errorf("reading %s: %s", path, err)
+	}
+	return nil
+}
+
+// replay applies the log of gen to memory. A torn record can only be the
+// last one of the last log, since writes stop once a record fails, so that
+// log is cut before it. A damaged record anywhere else fails the replay, as
+// the records after it can't be applied without it.
+func (f *File) replay(gen uint64, last bool) error {
+	path := f.path(gen, logExt)
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(file)
+	var offset int64
+	for {
+		payload, err := readRecord(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err == errCorrupt && offset+int64(recordHeaderSize+len(payload)) == info.Size() {
+			// a crash may leave the whole length of the last record
+			// written but not all of its payload
+			err = errTorn
+		}
+		if err == errTorn && last {
+			log.Printf("Dropping a torn write at offset %d of %s", offset, path)
+			return os.Truncate(path, offset)
+		}
+		if err == errTorn || err == errCorrupt {
+			return fmt.Errorf("replaying %s at offset %d: %s", path, offset, err)
+		}
+		if err != nil {
+			return err
+		}
+
+		dec := msgpack.NewDecoder(bytes.NewReader(payload))
+		for {
+			var e entry
+			if err := dec.Decode(&e); err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("replaying %s at offset %d: %s", path, offset, err)
+			}
+			if err := e.apply(f.mem); err != nil {
+				return fmt.Errorf("replaying %s at offset %d: %s", path, offset, err)
+			}
+		}
+		offset += int64(recordHeaderSize + len(payload))
+	}
+}
+
+// generations lists the generations of the files with ext in the
+// directory, in ascending order
+func (f *File) generations(ext string) ([]uint64, error) {
+	paths, err := filepath.Glob(filepath.Join(f.dir, "*"+ext))
+	if err != nil {
+		return nil, err
+	}
+	var gens []uint64
+	for _, path := range paths {
+		gen, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), ext), 16, 64)
+		if err == nil {
+			gens = append(gens, gen)
+		}
+	}
+	sort.Slice(gens, func(i, j int) bool { return gens[i] < gens[j] })
+	return gens, nil
+}
+
+// removeStale removes the snapshots but the one of base, the logs before
+// it, and temporary files left by a crash
+func (f *File) removeStale(base uint64) {
+	for _, ext := range []string{snapshotExt, logExt} {
+		gens, _ := f.generations(ext)
+		for _, gen := range gens {
+			if gen < base || ext == snapshotExt && gen != base {
+				os.Remove(f.path(gen, ext))
+			}
+		}
+		tmps, _ := filepath.Glob(filepath.Join(f.dir, "*"+ext+".tmp"))
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}
+}
+
+func (f *File) path(gen uint64, ext string) string {
+	return filepath.Join(f.dir, fmt.Sprintf("%016x%s", gen, ext))
+}
+
+// openLog opens the log at path for appending, creating it when it doesn't
+// exist, and returns its size
+func openLog(path string) (*os.File, int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err == nil {
+		err = syncDir(filepath.Dir(path))
+	}
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// Operations of log entries
+const (
+	opInsert  = "insert"
+	opReplace = "replace"
+	opUpdate  = "update"
+	opDelete  = "delete"
+	opNext    = "next"
+	opSpace   = "space"
+)
+
+// entry is a write in the log
+type entry struct {
+	Op       string        `msgpack:"op"`
+	Space    string        `msgpack:"space,omitempty"`
+	Index    string        `msgpack:"index,omitempty"`
+	Key      []interface{} `msgpack:"key,omitempty"`
+	Tuple    interface{}   `msgpack:"tuple,omitempty"`
+	Ops      []interface{} `msgpack:"ops,omitempty"`
+	Sequence string        `msgpack:"sequence,omitempty"`
+	Def      *Space        `msgpack:"def,omitempty"`
+}
+
+func (e *entry) apply(db Store) error {
+	switch e.Op {
+	case opInsert:
+		return db.Insert(e.Space, e.Tuple, nil)
+	case opReplace:
+		return db.Replace(e.Space, e.Tuple, nil)
+	case opUpdate:
+		return db.Update(e.Space, e.Index, e.Key, e.Ops, nil)
+	case opDelete:
+		return db.Delete(e.Space, e.Index, e.Key, nil)
+	case opNext:
+		_, err := db.NextValue(e.Sequence)
+		return err
+	case opSpace:
+		return db.CreateSpace(e.Def)
+	}
+	return fmt.Errorf("unknown operation %q", e.Op)
+}
+
+// recorder encodes the writes made through it which succeed. They are
+// encoded right away, as the values written may change once they are.
+type recorder struct {
+	Store
+	buf bytes.Buffer
+	enc *msgpack.Encoder
+	err error
+}
+
+func newRecorder(db Store) *recorder {
+	r := &recorder{Store: db}
+	r.enc = msgpack.NewEncoder(&r.buf)
+	return r
+}
+
+func (r *recorder) record(e entry, err error) error {
+	if err != nil {
+		return err
+	}
+	if r.err == nil {
+		r.err = r.enc.Encode(&e)
+	}
+	return nil
+}
+
+func (r *recorder) Insert(space string, tuple, result interface{}) error {
+	return r.record(entry{Op: opInsert, Space: space, Tuple: tuple}, r.Store.Insert(space, tuple, result))
+}
+
+func (r *recorder) Replace(space string, tuple, result interface{}) error {
+	return r.record(entry{Op: opReplace, Space: space, Tuple: tuple}, r.Store.Replace(space, tuple, result))
+}
+
+func (r *recorder) Update(space, index string, key, ops []interface{}, result interface{}) error {
+	return r.record(entry{Op: opUpdate, Space: space, Index: index, Key: key, Ops: ops}, r.Store.Update(space, index, key, ops, result))
+}
+
+func (r *recorder) Delete(space, index string, key []interface{}, result interface{}) error {
+	return r.record(entry{Op: opDelete, Space: space, Index: index, Key: key}, r.Store.Delete(space, index, key, result))
+}
+
+func (r *recorder) NextValue(sequence string) (uint, error) {
+	v, err := r.Store.NextValue(sequence)
+	return v, r.record(entry{Op: opNext, Sequence: sequence}, err)
+}
+
+func (r *recorder) CreateSpace(def *Space) error {
+	return r.record(entry{Op: opSpace, Def: def}, r.Store.CreateSpace(def))
+}
+
+// snapshot is every space of a store with its tuples
+type snapshot struct {
+	Sequences map[string]uint
+	Spaces    []snapshotSpace
+}
+
+type snapshotSpace struct {
+	Def    Space
+	Tuples []tuple
+}
+
+func (m *Memory) snapshot() *snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snap := &snapshot{Sequences: map[string]uint{}}
+	for name, v := range m.sequences {
+		snap.Sequences[name] = v
+	}
+	var names []string
+	for name := range m.spaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := m.spaces[name]
+		space := snapshotSpace{Def: Space{Name: name}}
+		for _, idx := range s.indexes {
+			space.Def.Indexes = append(space.Def.Indexes, Index{Name: idx.name, Parts: idx.parts})
+		}
+		if len(s.indexes) > 0 {
+			// tuples are never changed in place, but the slice of them
+			// is as tuples come and go
+			space.Tuples = append([]tuple(nil), s.indexes[0].tuples...)
+		}
+		snap.Spaces = append(snap.Spaces, space)
+	}
+	return snap
+}
+
+func (m *Memory) restore(snap *snapshot) error {
+	for i := range snap.Spaces {
+		space := &snap.Spaces[i]
+		if err := m.CreateSpace(&space.Def); err != nil {
+			return err
+		}
+		for _, t := range space.Tuples {
+			if err := m.Insert(space.Def.Name, t, nil); err != nil {
+				return err
+			}
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, v := range snap.Sequences {
+		m.sequences[name] = v
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+// +build !windows
+
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockDir takes an exclusive lock on dir, which is held until the returned
+// file is closed, so that two processes never append to the same log
+func lockDir(dir string) (*os.File, error) {
+	path := filepath.Join(dir, lockName)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, fmt.Errorf("store in %s is in use by another process", dir)
+		}
+		return nil, fmt.Errorf("locking %s: %s", path, err)
+	}
+	return file, nil
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// flags of LockFileEx, and the error of a lock held by another process
+const (
+	lockfileFailImmediately               = 0x1
+	lockfileExclusiveLock                 = 0x2
+	errorLockViolation      syscall.Errno = 33
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// lockDir takes an exclusive lock on dir with LockFileEx, which is held
+// until the returned file is closed, so that two processes never append to
+// the same log
+func lockDir(dir string) (*os.File, error) {
+	path := filepath.Join(dir, lockName)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	var overlapped syscall.Overlapped
+	r, _, err := procLockFileEx.Call(file.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r == 0 {
+		file.Close()
+		if err == errorLockViolation {
+			return nil, fmt.Errorf("store in %s is in use by another process", dir)
+		}
+		return nil, fmt.Errorf("locking %s: %s", path, err)
+	}
+	return file, nil
+}
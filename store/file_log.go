@@ -0,0 +1,100 @@
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Records are framed by the length of their payload and its CRC-32C, both
+// little endian, so that a record torn by a crash is told from a whole one
+const recordHeaderSize = 8
+
+// maxRecordSize bounds the length read from a header, which is garbage
+// when the header is torn
+const maxRecordSize = 1 << 30
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+	// errTorn is read at a record which was not written completely
+	errTorn = errors.New("torn record")
+	// errCorrupt is read at a record whose header or payload is damaged
+	errCorrupt = errors.New("corrupt record")
+)
+
+// frame prefixes payload with its record header
+func frame(payload []byte) []byte {
+	b := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(b[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(b[4:8], crc32.Checksum(payload, crcTable))
+	return append(b, payload...)
+}
+
+// readRecord reads the payload of the next record. It returns io.EOF at the
+// end of r, errTorn when r ends within the record, and errCorrupt with the
+// payload when its checksum doesn't match, or without it when the length is
+// out of bounds.
+func readRecord(r *bufio.Reader) ([]byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errTorn
+		}
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size > maxRecordSize {
+		return nil, errCorrupt
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errTorn
+		}
+		return nil, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		return payload, errCorrupt
+	}
+	return payload, nil
+}
+
+// writeFileSync writes data to a temporary file, syncs it and renames it to
+// path, so that path holds either its old content or all of data
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs the entries of dir, making files created or renamed in it
+// durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
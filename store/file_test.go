@@ -0,0 +1,219 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func dumpItems(t *testing.T, db Store) string {
+	var rows [][]interface{}
+	if err := db.Select("items", "primary", 0, 100, IterAll, []interface{}{}, &rows); err != nil {
+		t.Fatal(err)
+	}
+	var groups [][]interface{}
+	if err := db.Select("items", "group", 0, 100, IterAll, []interface{}{}, &groups); err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprint(rows, groups)
+}
+
+func TestFileReopen(t *testing.T) {
+	type (
+		in struct {
+			compactSize int64
+			// tail is appended to the log after the store is closed
+			tail []byte
+		}
+		out struct {
+			compacted bool
+		}
+	)
+
+	badCRC := frame([]byte{0xc0})
+	badCRC[4]++
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{DefaultCompactSize, nil}, out{false}},
+		// every write compacts the log into a snapshot
+		"case-02": {in{0, nil}, out{true}},
+		// a write torn within its header
+		"case-03": {in{DefaultCompactSize, []byte{42, 0, 0}}, out{false}},
+		// a write torn within its payload
+		"case-04": {in{DefaultCompactSize, frame([]byte{1, 2, 3, 4})[:10]}, out{false}},
+		"case-05": {in{DefaultCompactSize, badCRC}, out{false}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			dir, err := ioutil.TempDir("", "store")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			f, err := OpenFile(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.CompactSize = in.compactSize
+			createItems(t, f)
+			if err := f.Replace("items", []interface{}{1, "d", 9.5}, nil); err != nil {
+				t.Fatal(err)
+			}
+			ops := []interface{}{[]interface{}{"=", 1, "e"}, []interface{}{"+", 2, 1}}
+			if err := f.Update("items", "primary", []interface{}{2}, ops, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Delete("items", "primary", []interface{}{3}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.NextValue("items_id"); err != nil {
+				t.Fatal(err)
+			}
+			err = f.Atomic(func(tx Store) error {
+				if err := tx.Delete("items", "primary", []interface{}{4}, nil); err != nil {
+					return err
+				}
+				return tx.Insert("items", []interface{}{5, "x", 0}, nil)
+			})
+			if err == nil {
+				t.Fatal("atomic writes with a failed insert succeeded")
+			}
+			expected := dumpItems(t, f)
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			logs, _ := filepath.Glob(filepath.Join(dir, "*"+logExt))
+			if len(logs) != 1 {
+				t.Fatalf("actual logs %v, expected one log", logs)
+			}
+			if in.tail != nil {
+				file, err := os.OpenFile(logs[0], os.O_WRONLY|os.O_APPEND, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				file.Write(in.tail)
+				file.Close()
+			}
+
+			f, err = OpenFile(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if actual := dumpItems(t, f); actual != expected {
+				t.Errorf("actual items %s, expected items %s", actual, expected)
+			}
+			if compacted := f.gen > 0; compacted != out.compacted {
+				t.Errorf("actual compacted %v, expected compacted %v", compacted, out.compacted)
+			}
+			if v, err := f.NextValue("items_id"); err != nil || v != 2 {
+				t.Errorf("next value %d, %v", v, err)
+			}
+			if err := f.Insert("items", []interface{}{6, "f", 1}, nil); err != nil {
+				t.Errorf("insert after reopen: %v", err)
+			}
+		})
+	}
+}
+
+func TestFileCorruptLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createItems(t, f)
+	f.Close()
+
+	// a damaged record followed by a whole one isn't a torn write
+	logs, _ := filepath.Glob(filepath.Join(dir, "*"+logExt))
+	b, err := ioutil.ReadFile(logs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[recordHeaderSize]++
+	if err := ioutil.WriteFile(logs[0], b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := OpenFile(dir); err == nil {
+		f.Close()
+		t.Fatal("opened a log damaged in the middle")
+	}
+	if info, err := os.Stat(logs[0]); err != nil || info.Size() != int64(len(b)) {
+		t.Errorf("the damaged log was changed: %v, %v", info, err)
+	}
+}
+
+func TestFileLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other, err := OpenFile(dir); err == nil {
+		other.Close()
+		t.Fatal("opened a store which is open already")
+	}
+	f.Close()
+	if f, err = OpenFile(dir); err != nil {
+		t.Fatalf("can't open a closed store: %v", err)
+	}
+	f.Close()
+}
+
+func TestFileCompactInBackground(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := OpenFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createItems(t, f)
+	f.CompactSize = 0
+	// writes go on while the snapshot is written, and compact no more
+	// until it is in place
+	f.mu.Lock()
+	f.size = 1
+	f.compactIfLarge()
+	f.mu.Unlock()
+	for ID := 10; ID < 20; ID++ {
+		if err := f.Insert("items", []interface{}{ID, "n", 0}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := dumpItems(t, f)
+	f.Close()
+
+	f, err = OpenFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if actual := dumpItems(t, f); actual != expected {
+		t.Errorf("actual items %s, expected items %s", actual, expected)
+	}
+}
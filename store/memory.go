@@ -0,0 +1,502 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+
+	tarantool "github.com/tarantool/go-tarantool"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+// Memory stores tuples in memory, behaving like tarantool down to its error
+// codes. Every operation is atomic; Atomic makes several operations atomic
+// together.
+type Memory struct {
+	mu        sync.RWMutex
+	spaces    map[string]*memSpace
+	sequences map[string]uint
+
+	// inTx is set on the stores Atomic passes to its callers, which write
+	// in place and keep undo to revert their writes, newest last
+	inTx bool
+	undo []func()
+}
+
+// tuple is a decoded msgpack array. Tuples are never modified once stored,
+// updates store a modified copy.
+type tuple []interface{}
+
+type memSpace struct {
+	name string
+	// indexes[0] is the primary index
+	indexes []*memIndex
+}
+
+// NewMemory initiates Memory with no spaces
+func NewMemory() *Memory {
+	return &Memory{
+		spaces:    map[string]*memSpace{},
+		sequences: map[string]uint{},
+	}
+}
+
+// Atomic calls fn with a store whose writes are applied all together when
+// fn returns nil, and dropped otherwise, sequences included. Other callers
+// wait until fn returns, so fn must only use the store it is given. Writes
+// are made in place and undone when fn fails, so that a transaction costs
+// as much as its writes, whatever the size of the store.
+func (m *Memory) Atomic(fn func(tx Store) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &Memory{spaces: m.spaces, sequences: m.sequences, inTx: true}
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	// a transaction within a transaction is undone with it
+	m.onUndo(tx.undo...)
+	return nil
+}
+
+// onUndo keeps fns to revert writes when m is within a transaction
+func (m *Memory) onUndo(fns ...func()) {
+	if m.inTx {
+		m.undo = append(m.undo, fns...)
+	}
+}
+
+// Select implements Store
+func (m *Memory) Select(space, index string, offset, limit uint32, iterator Iterator, key []interface{}, result interface{}) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	idx, err := m.index(space, index)
+	if err != nil {
+		return err
+	}
+	k, err := decodeTuple(key)
+	if err != nil {
+		return err
+	}
+	tuples, err := idx.find(iterator, k)
+	if err != nil {
+		return err
+	}
+	if uint32(len(tuples)) <= offset {
+		tuples = nil
+	} else {
+		tuples = tuples[offset:]
+	}
+	if uint32(len(tuples)) > limit {
+		tuples = tuples[:limit]
+	}
+	return encodeResult(tuples, result)
+}
+
+// Insert implements Store
+func (m *Memory) Insert(space string, t, result interface{}) error {
+	return m.put(space, t, false, result)
+}
+
+// Replace implements Store
+func (m *Memory) Replace(space string, t, result interface{}) error {
+	return m.put(space, t, true, result)
+}
+
+func (m *Memory) put(space string, v interface{}, replace bool, result interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.space(space)
+	if err != nil {
+		return err
+	}
+	t, err := decodeTuple(v)
+	if err != nil {
+		return err
+	}
+	old, err := s.put(t, replace)
+	if err != nil {
+		return err
+	}
+	m.onUndo(func() { s.restore(t, old) })
+	return encodeResult([]tuple{t}, result)
+}
+
+// Update implements Store. It supports the "=", "+", "-", "!" and "#"
+// operations.
+func (m *Memory) Update(space, index string, key, ops []interface{}, result interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.space(space)
+	if err != nil {
+		return err
+	}
+	old, err := s.get(index, key)
+	if err != nil || old == nil {
+		return err
+	}
+	decoded, err := decodeTuple(ops)
+	if err != nil {
+		return err
+	}
+	t, err := applyOps(old, decoded)
+	if err != nil {
+		return err
+	}
+	if compareKeys(s.indexes[0].key(t), s.indexes[0].key(old)) != 0 {
+		return tarantool.Error{
+			Code: tarantool.ErrCantUpdatePrimaryKey,
+			Msg:  fmt.Sprintf("Attempt to modify a tuple field which is part of index '%s' in space '%s'", s.indexes[0].name, s.name),
+		}
+	}
+	if _, err := s.put(t, true); err != nil {
+		return err
+	}
+	m.onUndo(func() { s.restore(t, old) })
+	return encodeResult([]tuple{t}, result)
+}
+
+// Delete implements Store
+func (m *Memory) Delete(space, index string, key []interface{}, result interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.space(space)
+	if err != nil {
+		return err
+	}
+	t, err := s.get(index, key)
+	if err != nil || t == nil {
+		return err
+	}
+	s.remove(t)
+	m.onUndo(func() { s.restore(nil, t) })
+	return encodeResult([]tuple{t}, result)
+}
+
+// NextValue implements Store. Sequences start at 1.
+func (m *Memory) NextValue(sequence string) (uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok := m.sequences[sequence]
+	if !ok {
+		return 0, tarantool.Error{
+			Code: tarantool.ErrProcLua,
+			Msg:  fmt.Sprintf("sequence '%s' does not exist", sequence),
+		}
+	}
+	m.sequences[sequence] = v + 1
+	m.onUndo(func() { m.sequences[sequence] = v })
+	return v + 1, nil
+}
+
+// CreateSpace implements Store. Indexes created on a space with tuples are
+// built from them, and fail like tarantool when the tuples lack their
+// fields or have duplicate keys.
+func (m *Memory) CreateSpace(def *Space) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sequences[def.Sequence]; def.Sequence != "" && !ok {
+		m.sequences[def.Sequence] = 0
+		m.onUndo(func() { delete(m.sequences, def.Sequence) })
+	}
+	s, ok := m.spaces[def.Name]
+	if !ok {
+		s = &memSpace{name: def.Name}
+		m.spaces[def.Name] = s
+		m.onUndo(func() { delete(m.spaces, def.Name) })
+	}
+	n := len(s.indexes)
+	m.onUndo(func() { s.indexes = s.indexes[:n] })
+	for _, index := range def.Indexes {
+		if s.index(index.Name) != nil {
+			continue
+		}
+		idx := &memIndex{name: index.Name, parts: index.Parts}
+		if len(s.indexes) > 0 {
+			for _, t := range s.indexes[0].tuples {
+				if err := idx.check(s.name, t); err != nil {
+					return err
+				}
+				if err := idx.insert(t); err != nil {
+					return idx.duplicate(s.name)
+				}
+			}
+		}
+		s.indexes = append(s.indexes, idx)
+	}
+	return nil
+}
+
+// HasIndex implements Store
+func (m *Memory) HasIndex(space, index string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.spaces[space]
+	return ok && s.index(index) != nil
+}
+
+func (m *Memory) space(name string) (*memSpace, error) {
+	s, ok := m.spaces[name]
+	if !ok || len(s.indexes) == 0 {
+		return nil, tarantool.Error{
+			Code: tarantool.ErrNoSuchSpace,
+			Msg:  fmt.Sprintf("Space '%s' does not exist", name),
+		}
+	}
+	return s, nil
+}
+
+func (m *Memory) index(space, index string) (*memIndex, error) {
+	s, err := m.space(space)
+	if err != nil {
+		return nil, err
+	}
+	idx := s.index(index)
+	if idx == nil {
+		return nil, tarantool.Error{
+			Code: tarantool.ErrNoSuchIndex,
+			Msg:  fmt.Sprintf("No index '%s' is defined in space '%s'", index, space),
+		}
+	}
+	return idx, nil
+}
+
+func (s *memSpace) index(name string) *memIndex {
+	for _, idx := range s.indexes {
+		if idx.name == name {
+			return idx
+		}
+	}
+	return nil
+}
+
+// get finds the tuple with the full key of a unique index, nil if there is
+// none
+func (s *memSpace) get(index string, key []interface{}) (tuple, error) {
+	idx := s.index(index)
+	if idx == nil {
+		return nil, tarantool.Error{
+			Code: tarantool.ErrNoSuchIndex,
+			Msg:  fmt.Sprintf("No index '%s' is defined in space '%s'", index, s.name),
+		}
+	}
+	k, err := decodeTuple(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(k) != len(idx.parts) {
+		return nil, tarantool.Error{
+			Code: tarantool.ErrExactMatch,
+			Msg:  fmt.Sprintf("Invalid key part count in an exact match (expected %d, got %d)", len(idx.parts), len(k)),
+		}
+	}
+	return idx.lookup(k), nil
+}
+
+// put stores t in every index, and returns the tuple it replaced. It checks
+// every index before changing any, so that a failed put leaves the space as
+// it was.
+func (s *memSpace) put(t tuple, replace bool) (tuple, error) {
+	for _, idx := range s.indexes {
+		if err := idx.check(s.name, t); err != nil {
+			return nil, err
+		}
+	}
+	old := s.indexes[0].lookup(s.indexes[0].key(t))
+	if old != nil && !replace {
+		return nil, s.indexes[0].duplicate(s.name)
+	}
+	for _, idx := range s.indexes[1:] {
+		if found := idx.lookup(idx.key(t)); found != nil && !sameTuple(found, old) {
+			return nil, idx.duplicate(s.name)
+		}
+	}
+
+	if old != nil {
+		s.remove(old)
+	}
+	for _, idx := range s.indexes {
+		idx.insert(t)
+	}
+	return old, nil
+}
+
+// restore undoes a write which stored t in place of old, either of which
+// is nil when there was none
+func (s *memSpace) restore(t, old tuple) {
+	if t != nil {
+		s.remove(t)
+	}
+	if old != nil {
+		for _, idx := range s.indexes {
+			idx.insert(old)
+		}
+	}
+}
+
+func (s *memSpace) remove(t tuple) {
+	for _, idx := range s.indexes {
+		idx.remove(t)
+	}
+}
+
+// sameTuple reports whether a and b are the same stored tuple
+func sameTuple(a, b tuple) bool {
+	return len(a) > 0 && len(b) > 0 && &a[0] == &b[0]
+}
+
+// decodeTuple normalizes v to the values msgpack decodes, as tarantool
+// would receive it
+func decodeTuple(v interface{}) (tuple, error) {
+	b, err := msgpack.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := msgpack.Unmarshal(b, &decoded); err != nil {
+		return nil, err
+	}
+	t, ok := decoded.([]interface{})
+	if !ok && decoded != nil {
+		return nil, tarantool.Error{Code: tarantool.ErrTupleNotArray, Msg: "Tuple/Key must be MsgPack array"}
+	}
+	return tuple(t), nil
+}
+
+func encodeResult(tuples []tuple, result interface{}) error {
+	if result == nil {
+		return nil
+	}
+	if tuples == nil {
+		tuples = []tuple{}
+	}
+	b, err := msgpack.Marshal(tuples)
+	if err != nil {
+		return err
+	}
+	return msgpack.Unmarshal(b, result)
+}
+
+// applyOps applies update operations to a copy of t
+func applyOps(t tuple, ops tuple) (tuple, error) {
+	updated := append(tuple(nil), t...)
+	for _, o := range ops {
+		op, ok := o.([]interface{})
+		if !ok || len(op) < 3 {
+			return nil, tarantool.Error{Code: tarantool.ErrIllegalParams, Msg: "Illegal parameters, update operation must be an array {op,..}"}
+		}
+		name, _ := op[0].(string)
+		field, ok := toInt(op[1])
+		if !ok || field < 0 {
+			return nil, tarantool.Error{Code: tarantool.ErrIllegalParams, Msg: "Illegal parameters, field id must be a number"}
+		}
+		noField := tarantool.Error{Code: tarantool.ErrNoSuchField, Msg: fmt.Sprintf("Field %d was not found in the tuple", field)}
+
+		switch name {
+		case "=":
+			switch {
+			case field < len(updated):
+				updated[field] = op[2]
+			case field == len(updated):
+				updated = append(updated, op[2])
+			default:
+				return nil, noField
+			}
+		case "!":
+			if field > len(updated) {
+				return nil, noField
+			}
+			updated = append(updated[:field], append(tuple{op[2]}, updated[field:]...)...)
+		case "#":
+			n, ok := toInt(op[2])
+			if field >= len(updated) {
+				return nil, noField
+			}
+			if !ok || n < 1 {
+				return nil, tarantool.Error{Code: tarantool.ErrUpdateField, Msg: fmt.Sprintf("Field %d UPDATE error: cannot delete 0 fields", field)}
+			}
+			if field+n > len(updated) {
+				n = len(updated) - field
+			}
+			updated = append(updated[:field], updated[field+n:]...)
+		case "+", "-":
+			if field >= len(updated) {
+				return nil, noField
+			}
+			v, err := arith(name, updated[field], op[2])
+			if err != nil {
+				return nil, tarantool.Error{Code: tarantool.ErrArgType, Msg: fmt.Sprintf("Argument type in operation '%s' on field %d does not match field type: expected a number", name, field)}
+			}
+			updated[field] = v
+		default:
+			return nil, tarantool.Error{Code: tarantool.ErrUnknownUpdateOp, Msg: "Unknown UPDATE operation"}
+		}
+	}
+	return updated, nil
+}
+
+// arith adds or subtracts numbers, keeping integers integers
+func arith(op string, a, b interface{}) (interface{}, error) {
+	ai, aInt := toInt64(a)
+	bi, bInt := toInt64(b)
+	if aInt && bInt {
+		if op == "-" {
+			bi = -bi
+		}
+		return ai + bi, nil
+	}
+	af, aok := toFloat(a)
+	bf, bok := toFloat(b)
+	if !aok || !bok {
+		return nil, fmt.Errorf("not a number")
+	}
+	if op == "-" {
+		bf = -bf
+	}
+	return af + bf, nil
+}
+
+func toInt(v interface{}) (int, bool) {
+	n, ok := toInt64(v)
+	return int(n), ok
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= 1<<63-1
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+
+	tarantool "github.com/tarantool/go-tarantool"
+)
+
+// memIndex is a unique tree index, its tuples sorted by key
+type memIndex struct {
+	name   string
+	parts  []Part
+	tuples []tuple
+}
+
+func (idx *memIndex) key(t tuple) tuple {
+	key := make(tuple, len(idx.parts))
+	for i, part := range idx.parts {
+		if part.Field < len(t) {
+			key[i] = t[part.Field]
+		}
+	}
+	return key
+}
+
+// check reports whether t has the fields of the key, of their types
+func (idx *memIndex) check(space string, t tuple) error {
+	for i, part := range idx.parts {
+		if part.Field >= len(t) {
+			return tarantool.Error{
+				Code: tarantool.ErrIndexFieldCount,
+				Msg:  fmt.Sprintf("Tuple field count %d is less than required by a defined index (expected %d)", len(t), part.Field+1),
+			}
+		}
+		if !hasType(t[part.Field], part.Type) {
+			return tarantool.Error{
+				Code: tarantool.ErrFieldType,
+				Msg:  fmt.Sprintf("Tuple field %d type does not match one required by operation: expected %s", i+1, part.Type),
+			}
+		}
+	}
+	return nil
+}
+
+func (idx *memIndex) duplicate(space string) error {
+	return tarantool.Error{
+		Code: tarantool.ErrTupleFound,
+		Msg:  fmt.Sprintf("Duplicate key exists in unique index '%s' in space '%s'", idx.name, space),
+	}
+}
+
+// search returns the position of the first tuple whose key, cut to the
+// length of key, isn't less than key, or isn't less nor equal when after is
+// true
+func (idx *memIndex) search(key tuple, after bool) int {
+	return sort.Search(len(idx.tuples), func(i int) bool {
+		c := compareKeys(idx.key(idx.tuples[i])[:len(key)], key)
+		if after {
+			return c > 0
+		}
+		return c >= 0
+	})
+}
+
+func (idx *memIndex) lookup(key tuple) tuple {
+	i := idx.search(key, false)
+	if i < len(idx.tuples) && compareKeys(idx.key(idx.tuples[i]), key) == 0 {
+		return idx.tuples[i]
+	}
+	return nil
+}
+
+// insert adds t, failing when its key is taken
+func (idx *memIndex) insert(t tuple) error {
+	key := idx.key(t)
+	i := idx.search(key, false)
+	if i < len(idx.tuples) && compareKeys(idx.key(idx.tuples[i]), key) == 0 {
+		return fmt.Errorf("duplicate key")
+	}
+	idx.tuples = append(idx.tuples, nil)
+	copy(idx.tuples[i+1:], idx.tuples[i:])
+	idx.tuples[i] = t
+	return nil
+}
+
+func (idx *memIndex) remove(t tuple) {
+	i := idx.search(idx.key(t), false)
+	if i < len(idx.tuples) && sameTuple(idx.tuples[i], t) {
+		idx.tuples = append(idx.tuples[:i], idx.tuples[i+1:]...)
+	}
+}
+
+// find returns the tuples iterator reads from key, in the order it reads
+// them. Keys may be shorter than the key of the index, to match its
+// first parts.
+func (idx *memIndex) find(iterator Iterator, key tuple) ([]tuple, error) {
+	if len(key) > len(idx.parts) {
+		return nil, tarantool.Error{
+			Code: tarantool.ErrKeyPartCount,
+			Msg:  fmt.Sprintf("Invalid key part count (expected [0..%d], got %d)", len(idx.parts), len(key)),
+		}
+	}
+
+	all := idx.tuples
+	if len(key) == 0 {
+		switch iterator {
+		case IterReq, IterLt, IterLe:
+			return reversed(all), nil
+		}
+		return append([]tuple(nil), all...), nil
+	}
+
+	lo, hi := idx.search(key, false), idx.search(key, true)
+	switch iterator {
+	case IterEq:
+		return append([]tuple(nil), all[lo:hi]...), nil
+	case IterReq:
+		return reversed(all[lo:hi]), nil
+	case IterAll, IterGe:
+		return append([]tuple(nil), all[lo:]...), nil
+	case IterGt:
+		return append([]tuple(nil), all[hi:]...), nil
+	case IterLe:
+		return reversed(all[:hi]), nil
+	case IterLt:
+		return reversed(all[:lo]), nil
+	}
+	return nil, tarantool.Error{
+		Code: tarantool.ErrUnsupported,
+		Msg:  fmt.Sprintf("TREE does not support iterator %d", iterator),
+	}
+}
+
+func reversed(tuples []tuple) []tuple {
+	r := make([]tuple, len(tuples))
+	for i, t := range tuples {
+		r[len(tuples)-1-i] = t
+	}
+	return r
+}
+
+func hasType(v interface{}, typ string) bool {
+	switch typ {
+	case "unsigned":
+		n, ok := toInt64(v)
+		_, unsigned := v.(uint64)
+		return unsigned || (ok && n >= 0)
+	case "integer":
+		_, ok := toInt64(v)
+		_, unsigned := v.(uint64)
+		return ok || unsigned
+	case "number":
+		_, ok := toFloat(v)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "scalar":
+		switch v.(type) {
+		case bool, string, []byte:
+			return true
+		}
+		_, ok := toFloat(v)
+		return ok
+	}
+	return true
+}
+
+// compareKeys compares keys part by part, a key being less than the longer
+// keys it starts
+func compareKeys(a, b tuple) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// compareValues orders values like tarantool's scalar type: nil, booleans,
+// numbers, strings, then anything else
+func compareValues(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch ra {
+	case 1:
+		ab, bb := a.(bool), b.(bool)
+		switch {
+		case ab == bb:
+			return 0
+		case !ab:
+			return -1
+		}
+		return 1
+	case 2:
+		return compareNumbers(a, b)
+	case 3:
+		as, bs := a.(string), b.(string)
+		switch {
+		case as < bs:
+			return -1
+		case as > bs:
+			return 1
+		}
+	}
+	return 0
+}
+
+func rank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case string:
+		return 3
+	}
+	if _, ok := toFloat(v); ok {
+		return 2
+	}
+	return 4
+}
+
+// compareNumbers compares integers exactly, whatever their signedness, and
+// floats as floats
+func compareNumbers(a, b interface{}) int {
+	ai, aInt := toInt64(a)
+	bi, bInt := toInt64(b)
+	if aInt && bInt {
+		return cmp3(ai < bi, ai > bi)
+	}
+	// unsigned integers which don't fit an int64 are above every int64
+	au, aBig := a.(uint64)
+	bu, bBig := b.(uint64)
+	switch {
+	case aBig && bBig:
+		return cmp3(au < bu, au > bu)
+	case aBig && bInt:
+		return 1
+	case aInt && bBig:
+		return -1
+	}
+	af, _ := toFloat(a)
+	bf, _ := toFloat(b)
+	return cmp3(af < bf, af > bf)
+}
+
+func cmp3(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
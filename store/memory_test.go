@@ -0,0 +1,225 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	tarantool "github.com/tarantool/go-tarantool"
+)
+
+type item struct {
+	ID    uint
+	Group string
+	Score float64
+}
+
+func newItems(t *testing.T) *Memory {
+	m := NewMemory()
+	createItems(t, m)
+	return m
+}
+
+func createItems(t *testing.T, db Store) {
+	err := db.CreateSpace(&Space{
+		Name:     "items",
+		Sequence: "items_id",
+		Indexes: []Index{
+			{"primary", []Part{{0, "unsigned"}}},
+			{"group", []Part{{1, "string"}, {0, "unsigned"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range []item{{1, "a", 1}, {2, "b", 2.5}, {3, "a", 3}, {4, "b", 0}, {5, "c", 1}} {
+		if err := db.Insert("items", []interface{}{it.ID, it.Group, it.Score}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func ids(rows [][]interface{}) []uint64 {
+	got := []uint64{}
+	for _, row := range rows {
+		n, _ := toFloat(row[0])
+		got = append(got, uint64(n))
+	}
+	return got
+}
+
+func TestMemorySelect(t *testing.T) {
+	m := newItems(t)
+
+	type (
+		in struct {
+			index         string
+			offset, limit uint32
+			iterator      Iterator
+			key           []interface{}
+		}
+		out struct {
+			ids []uint64
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"primary", 0, 10, IterEq, []interface{}{3}}, out{[]uint64{3}}},
+		"case-02": {in{"primary", 0, 2, IterGt, []interface{}{1}}, out{[]uint64{2, 3}}},
+		"case-03": {in{"primary", 1, 10, IterLe, []interface{}{4}}, out{[]uint64{3, 2, 1}}},
+		"case-04": {in{"primary", 0, 10, IterLt, []interface{}{}}, out{[]uint64{5, 4, 3, 2, 1}}},
+		// a key shorter than the index matches its first parts
+		"case-05": {in{"group", 0, 10, IterEq, []interface{}{"b"}}, out{[]uint64{2, 4}}},
+		"case-06": {in{"group", 0, 10, IterReq, []interface{}{"a"}}, out{[]uint64{3, 1}}},
+		"case-07": {in{"group", 0, 10, IterGt, []interface{}{"a", 1}}, out{[]uint64{3, 2, 4, 5}}},
+		"case-08": {in{"group", 0, 10, IterLt, []interface{}{"b"}}, out{[]uint64{3, 1}}},
+		"case-09": {in{"group", 0, 10, IterGe, []interface{}{"bb"}}, out{[]uint64{5}}},
+		"case-10": {in{"primary", 10, 10, IterAll, []interface{}{}}, out{[]uint64{}}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			var rows [][]interface{}
+			if err := m.Select("items", in.index, in.offset, in.limit, in.iterator, in.key, &rows); err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(rows); !reflect.DeepEqual(got, out.ids) {
+				t.Errorf("actual ids %v, expected ids %v", got, out.ids)
+			}
+		})
+	}
+}
+
+func TestMemoryWrite(t *testing.T) {
+	m := newItems(t)
+
+	err := m.Insert("items", []interface{}{1, "z", 0}, nil)
+	if e, ok := err.(tarantool.Error); !ok || e.Code != tarantool.ErrTupleFound {
+		t.Errorf("insert of a taken key: %v", err)
+	}
+	if err := m.Insert("items", []interface{}{6}, nil); err == nil {
+		t.Error("insert of a tuple lacking indexed fields succeeded")
+	}
+
+	var got [][]interface{}
+	if err := m.Replace("items", []interface{}{1, "d", 9.5}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[[1 d 9.5]]" {
+		t.Errorf("replaced %+v", got)
+	}
+	var rows [][]interface{}
+	if err := m.Select("items", "group", 0, 10, IterEq, []interface{}{"a"}, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(rows); !reflect.DeepEqual(got, []uint64{3}) {
+		t.Errorf("group index still has %v after replace", got)
+	}
+
+	got = nil
+	ops := []interface{}{[]interface{}{"=", 1, "e"}, []interface{}{"+", 2, 1}}
+	if err := m.Update("items", "primary", []interface{}{2}, ops, &got); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[[2 e 3.5]]" {
+		t.Errorf("updated %+v", got)
+	}
+	err = m.Update("items", "primary", []interface{}{2}, []interface{}{[]interface{}{"=", 5, "x"}}, nil)
+	if e, ok := err.(tarantool.Error); !ok || e.Code != tarantool.ErrNoSuchField {
+		t.Errorf("update of a missing field: %v", err)
+	}
+
+	got = nil
+	if err := m.Delete("items", "primary", []interface{}{9}, &got); err != nil || len(got) != 0 {
+		t.Errorf("delete of a missing tuple: %+v, %v", got, err)
+	}
+	if v, err := m.NextValue("items_id"); err != nil || v != 1 {
+		t.Errorf("next value %d, %v", v, err)
+	}
+}
+
+func TestMemoryAtomic(t *testing.T) {
+	m := newItems(t)
+
+	err := m.Atomic(func(tx Store) error {
+		if err := tx.Delete("items", "primary", []interface{}{1}, nil); err != nil {
+			return err
+		}
+		return tx.Insert("items", []interface{}{2, "x", 0}, nil)
+	})
+	if err == nil {
+		t.Fatal("atomic writes with a failed insert succeeded")
+	}
+	var rows [][]interface{}
+	if err := m.Select("items", "primary", 0, 10, IterEq, []interface{}{1}, &rows); err != nil || len(rows) != 1 {
+		t.Errorf("delete of a failed transaction took effect: %v, %v", rows, err)
+	}
+
+	err = m.Atomic(func(tx Store) error {
+		return tx.Delete("items", "primary", []interface{}{1}, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows = nil
+	if err := m.Select("items", "primary", 0, 10, IterEq, []interface{}{1}, &rows); err != nil || len(rows) != 0 {
+		t.Errorf("delete of a transaction didn't take effect: %v, %v", rows, err)
+	}
+}
+
+func TestMemoryAtomicUndo(t *testing.T) {
+	m := newItems(t)
+	before := dumpItems(t, m)
+
+	errFailed := errors.New("failed")
+	err := m.Atomic(func(tx Store) error {
+		if err := tx.Replace("items", []interface{}{1, "z", 9}, nil); err != nil {
+			return err
+		}
+		if err := tx.Update("items", "primary", []interface{}{2}, []interface{}{[]interface{}{"=", 1, "y"}}, nil); err != nil {
+			return err
+		}
+		if err := tx.Insert("items", []interface{}{6, "c", 1}, nil); err != nil {
+			return err
+		}
+		if err := tx.Delete("items", "primary", []interface{}{3}, nil); err != nil {
+			return err
+		}
+		if _, err := tx.NextValue("items_id"); err != nil {
+			return err
+		}
+		// a transaction within the transaction is undone with it
+		err := tx.(*Memory).Atomic(func(tx Store) error {
+			return tx.CreateSpace(&Space{Name: "other", Sequence: "other_id", Indexes: []Index{{"primary", []Part{{0, "unsigned"}}}}})
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.CreateSpace(&Space{Name: "items", Indexes: []Index{{"score", []Part{{2, "number"}, {0, "unsigned"}}}}}); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if err != errFailed {
+		t.Fatalf("actual error %v, expected %v", err, errFailed)
+	}
+
+	if after := dumpItems(t, m); after != before {
+		t.Errorf("actual items %s after a failed transaction, expected %s", after, before)
+	}
+	if m.HasIndex("items", "score") || m.HasIndex("other", "primary") {
+		t.Error("spaces and indexes of a failed transaction were kept")
+	}
+	if n, err := m.NextValue("items_id"); err != nil || n != 1 {
+		t.Errorf("actual next value %d, %v, expected 1", n, err)
+	}
+	if _, err := m.NextValue("other_id"); err == nil {
+		t.Error("sequence of a failed transaction was kept")
+	}
+}
@@ -0,0 +1,72 @@
+// Package store abstracts the storage resources keep their tuples in, so
+// that they can be stored in tarantool, in files for small deployments
+// without a database server, or, for tests and local development, in
+// memory.
+package store
+
+// Store is a tuple storage with spaces, tree indexes and sequences, modeled
+// after tarantool. Tuples are written as values which encode to msgpack
+// arrays, and read into result, a pointer to a slice of values which decode
+// from them; a nil result discards the tuples. Fields are numbered from 0.
+type Store interface {
+	// Select reads up to limit tuples of space by index, skipping offset
+	// tuples first, in the order iterator walks from key
+	Select(space, index string, offset, limit uint32, iterator Iterator, key []interface{}, result interface{}) error
+	// Insert adds tuple to space, failing when its key is taken
+	Insert(space string, tuple, result interface{}) error
+	// Replace adds tuple to space, in place of the tuple with the same
+	// primary key if there is one
+	Replace(space string, tuple, result interface{}) error
+	// Update applies tarantool update operations, like {"=", 1, "title"},
+	// to the tuple with key. Nothing is read when there is no such tuple.
+	Update(space, index string, key, ops []interface{}, result interface{}) error
+	// Delete removes the tuple with key. Nothing is read when there is no
+	// such tuple.
+	Delete(space, index string, key []interface{}, result interface{}) error
+	// NextValue increments sequence and returns its value
+	NextValue(sequence string) (uint, error)
+	// CreateSpace creates the space, its sequence and its indexes which
+	// don't exist yet
+	CreateSpace(def *Space) error
+	// HasIndex reports whether space has index
+	HasIndex(space, index string) bool
+}
+
+// Iterator is the way Select walks an index, with the values of tarantool
+type Iterator uint32
+
+// Iterators of Select
+const (
+	// IterEq reads the tuples whose key starts with the key asked for
+	IterEq Iterator = iota
+	// IterReq reads the tuples IterEq does in reverse
+	IterReq
+	// IterAll reads every tuple
+	IterAll
+	IterLt
+	IterLe
+	IterGe
+	IterGt
+)
+
+// Space describes a space
+type Space struct {
+	Name string
+	// Sequence is the name of the sequence of its IDs, if it has one
+	Sequence string
+	// Indexes are unique tree indexes, the first one is the primary index
+	Indexes []Index
+}
+
+// Index describes an index of a space
+type Index struct {
+	Name  string
+	Parts []Part
+}
+
+// Part is a field of the key of an index. Type is a tarantool field type:
+// "unsigned", "integer", "number", "string" or "scalar".
+type Part struct {
+	Field int
+	Type  string
+}
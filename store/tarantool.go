@@ -0,0 +1,128 @@
+package store
+
+import (
+	"fmt"
+
+	tarantool "github.com/tarantool/go-tarantool"
+)
+
+// Tarantool stores tuples in a tarantool server
+type Tarantool struct {
+	conn *tarantool.Connection
+}
+
+// NewTarantool initiates Tarantool
+func NewTarantool(conn *tarantool.Connection) *Tarantool {
+	return &Tarantool{conn: conn}
+}
+
+// Close closes the connection
+func (s *Tarantool) Close() error {
+	return s.conn.Close()
+}
+
+// Select implements Store
+func (s *Tarantool) Select(space, index string, offset, limit uint32, iterator Iterator, key []interface{}, result interface{}) error {
+	if result == nil {
+		_, err := s.conn.Select(space, index, offset, limit, uint32(iterator), key)
+		return err
+	}
+	return s.conn.SelectTyped(space, index, offset, limit, uint32(iterator), key, result)
+}
+
+// Insert implements Store
+func (s *Tarantool) Insert(space string, tuple, result interface{}) error {
+	if result == nil {
+		_, err := s.conn.Insert(space, tuple)
+		return err
+	}
+	return s.conn.InsertTyped(space, tuple, result)
+}
+
+// Replace implements Store
+func (s *Tarantool) Replace(space string, tuple, result interface{}) error {
+	if result == nil {
+		_, err := s.conn.Replace(space, tuple)
+		return err
+	}
+	return s.conn.ReplaceTyped(space, tuple, result)
+}
+
+// Update implements Store
+func (s *Tarantool) Update(space, index string, key, ops []interface{}, result interface{}) error {
+	if result == nil {
+		_, err := s.conn.Update(space, index, key, ops)
+		return err
+	}
+	return s.conn.UpdateTyped(space, index, key, ops, result)
+}
+
+// Delete implements Store
+func (s *Tarantool) Delete(space, index string, key []interface{}, result interface{}) error {
+	if result == nil {
+		_, err := s.conn.Delete(space, index, key)
+		return err
+	}
+	return s.conn.DeleteTyped(space, index, key, result)
+}
+
+// NextValue implements Store
+func (s *Tarantool) NextValue(sequence string) (uint, error) {
+	var values []uint
+	err := s.conn.EvalTyped("return box.sequence[...]:next()", []interface{}{sequence}, &values)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, fmt.Errorf("sequence %s returned no value", sequence)
+	}
+	return values[0], nil
+}
+
+// CreateSpace implements Store. The indexes are only known to HasIndex once
+// the connection is established again.
+func (s *Tarantool) CreateSpace(def *Space) error {
+	indexes := make([]interface{}, 0, len(def.Indexes))
+	for _, index := range def.Indexes {
+		// tarantool numbers fields from 1
+		parts := make([]interface{}, 0, 2*len(index.Parts))
+		for _, part := range index.Parts {
+			parts = append(parts, part.Field+1, part.Type)
+		}
+		indexes = append(indexes, []interface{}{index.Name, parts})
+	}
+
+	_, err := s.conn.Eval(
+		"local name, sequence, indexes = ...; "+
+			"if sequence ~= '' then box.schema.sequence.create(sequence, {if_not_exists = true}) end; "+
+			"local space = box.schema.space.create(name, {if_not_exists = true}); "+
+			"for _, index in ipairs(indexes) do "+
+			"space:create_index(index[1], {parts = index[2], if_not_exists = true}) end",
+		[]interface{}{def.Name, def.Sequence, indexes},
+	)
+	return err
+}
+
+// HasIndex implements Store with the schema loaded when the connection was
+// established
+func (s *Tarantool) HasIndex(space, index string) bool {
+	if s.conn.Schema == nil {
+		return false
+	}
+	sp, ok := s.conn.Schema.Spaces[space]
+	if !ok {
+		return false
+	}
+	_, ok = sp.Indexes[index]
+	return ok
+}
+
+// Eval evaluates Lua on the server, reading the values it returns into
+// result; a nil result discards them
+func (s *Tarantool) Eval(expr string, args []interface{}, result interface{}) error {
+	if result == nil {
+		_, err := s.conn.Eval(expr, args)
+		return err
+	}
+	return s.conn.EvalTyped(expr, args, result)
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	tarantool "github.com/tarantool/go-tarantool"
+)
+
+// Verifier is a store whose spaces are created outside the server, which
+// can check them against the definitions the server relies on
+type Verifier interface {
+	Verify(defs []*Space) error
+}
+
+// Verify implements Verifier with the schema loaded when the connection was
+// established. Sequences are not part of it, so they are not verified.
+func (s *Tarantool) Verify(defs []*Space) error {
+	return verifySchema(defs, s.conn.Schema)
+}
+
+// verifySchema reports every space and index of defs which is missing from
+// schema or differs from its definition. Fields are numbered from 1 in the
+// report, as tarantool does.
+func verifySchema(defs []*Space, schema *tarantool.Schema) error {
+	if schema == nil {
+		return fmt.Errorf("schema wasn't loaded")
+	}
+
+	var problems []string
+	for _, def := range defs {
+		space, ok := schema.Spaces[def.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("space %s doesn't exist", def.Name))
+			continue
+		}
+		for _, index := range def.Indexes {
+			problems = append(problems, verifyIndex(space, index)...)
+		}
+		problems = append(problems, verifyFormat(space, def)...)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("schema doesn't match:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
+
+func verifyIndex(space *tarantool.Space, def Index) []string {
+	index, ok := space.Indexes[def.Name]
+	if !ok {
+		return []string{fmt.Sprintf("space %s: index %s doesn't exist", space.Name, def.Name)}
+	}
+
+	var problems []string
+	if strings.ToLower(index.Type) != "tree" || !index.Unique {
+		unique := "unique"
+		if !index.Unique {
+			unique = "non-unique"
+		}
+		problems = append(problems, fmt.Sprintf("space %s: index %s is a %s %s index, expected a unique tree index",
+			space.Name, def.Name, unique, strings.ToLower(index.Type)))
+	}
+
+	expected := make([]string, len(def.Parts))
+	for i, part := range def.Parts {
+		expected[i] = fmt.Sprintf("%d %s", part.Field+1, part.Type)
+	}
+	actual := make([]string, len(index.Fields))
+	for i, field := range index.Fields {
+		actual[i] = fmt.Sprintf("%d %s", field.Id+1, fieldType(field.Type))
+	}
+	if strings.Join(actual, ", ") != strings.Join(expected, ", ") {
+		problems = append(problems, fmt.Sprintf("space %s: index %s has parts [%s], expected [%s]",
+			space.Name, def.Name, strings.Join(actual, ", "), strings.Join(expected, ", ")))
+	}
+	return problems
+}
+
+// verifyFormat checks the types the format of space gives the indexed
+// fields, when it has one
+func verifyFormat(space *tarantool.Space, def *Space) []string {
+	var problems []string
+	checked := map[int]bool{}
+	for _, index := range def.Indexes {
+		for _, part := range index.Parts {
+			field, ok := space.FieldsById[uint32(part.Field)]
+			if !ok || checked[part.Field] {
+				continue
+			}
+			checked[part.Field] = true
+			switch t := fieldType(field.Type); t {
+			case "", "any", "scalar", part.Type:
+			default:
+				problems = append(problems, fmt.Sprintf("space %s: field %d is formatted as %s, expected %s",
+					space.Name, part.Field+1, t, part.Type))
+			}
+		}
+	}
+	return problems
+}
+
+// fieldType is the name of a field type of tarantool 1.7 and later, for
+// the names older versions use too
+func fieldType(t string) string {
+	t = strings.ToLower(t)
+	switch t {
+	case "num":
+		return "unsigned"
+	case "str":
+		return "string"
+	case "int":
+		return "integer"
+	}
+	return t
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	tarantool "github.com/tarantool/go-tarantool"
+)
+
+func TestVerifySchema(t *testing.T) {
+	defs := []*Space{{
+		Name: "items",
+		Indexes: []Index{
+			{"primary", []Part{{0, "unsigned"}}},
+			{"group", []Part{{1, "string"}, {0, "unsigned"}}},
+		},
+	}}
+	index := func(name, typ string, unique bool, fields ...interface{}) *tarantool.Index {
+		idx := &tarantool.Index{Name: name, Type: typ, Unique: unique}
+		for i := 0; i < len(fields); i += 2 {
+			idx.Fields = append(idx.Fields, &tarantool.IndexField{Id: uint32(fields[i].(int)), Type: fields[i+1].(string)})
+		}
+		return idx
+	}
+	schema := func(format map[uint32]*tarantool.Field, indexes ...*tarantool.Index) *tarantool.Schema {
+		space := &tarantool.Space{Name: "items", FieldsById: format, Indexes: map[string]*tarantool.Index{}}
+		for _, idx := range indexes {
+			space.Indexes[idx.Name] = idx
+		}
+		return &tarantool.Schema{Spaces: map[string]*tarantool.Space{"items": space}}
+	}
+	primary := index("primary", "TREE", true, 0, "unsigned")
+	group := index("group", "TREE", true, 1, "string", 0, "unsigned")
+
+	type (
+		in struct {
+			schema *tarantool.Schema
+		}
+		out struct {
+			// problems are the lines of the error, none when it is nil
+			problems []string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{schema(nil, primary, group)}, out{nil}},
+		"case-02": {in{&tarantool.Schema{Spaces: map[string]*tarantool.Space{}}}, out{[]string{"space items doesn't exist"}}},
+		"case-03": {in{schema(nil, primary)}, out{[]string{"space items: index group doesn't exist"}}},
+		"case-04": {
+			in{schema(nil, index("primary", "HASH", true, 0, "unsigned"), index("group", "tree", true, 2, "string", 0, "unsigned"))},
+			out{[]string{
+				"space items: index primary is a unique hash index, expected a unique tree index",
+				"space items: index group has parts [3 string, 1 unsigned], expected [2 string, 1 unsigned]",
+			}},
+		},
+		// field types of tarantool 1.6
+		"case-05": {in{schema(nil, index("primary", "TREE", true, 0, "NUM"), index("group", "TREE", true, 1, "STR", 0, "NUM"))}, out{nil}},
+		"case-06": {
+			in{schema(map[uint32]*tarantool.Field{0: {Type: "unsigned"}, 1: {Type: "integer"}}, primary, group)},
+			out{[]string{"space items: field 2 is formatted as integer, expected string"}},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			err := verifySchema(defs, in.schema)
+			var problems []string
+			if err != nil {
+				problems = strings.Split(err.Error(), "\n\t")[1:]
+			}
+			if strings.Join(problems, "\n") != strings.Join(out.problems, "\n") {
+				t.Errorf("actual problems %q, expected problems %q", problems, out.problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package units
+
+import (
+	"math/big"
+
+	"github.com/motomux/smart-cooking-server/search"
+)
+
+// Density is how much a cup of an ingredient weighs, for converting between
+// volume and mass
+type Density struct {
+	// Names are words or phrases an ingredient name contains as a whole to
+	// have the density, like "flour" in "all-purpose flour"
+	Names              []string
+	GramsPerMillilitre *big.Rat
+	// Weighed is whether cooks using metric weigh the ingredient rather
+	// than measure its volume
+	Weighed bool
+}
+
+// densities of common staples, measured spooned and levelled. Longer names
+// win over the ones they contain, and otherwise the first match wins.
+var densities = []Density{
+	{[]string{"brown sugar", "三温糖"}, gramsPerCup("220"), true},
+	{[]string{"powdered sugar", "icing sugar", "confectioners", "粉糖"}, gramsPerCup("120"), true},
+	{[]string{"sugar", "砂糖", "グラニュー糖"}, gramsPerCup("200"), true},
+	{[]string{"bread flour", "強力粉"}, gramsPerCup("127"), true},
+	{[]string{"whole wheat flour", "全粒粉"}, gramsPerCup("120"), true},
+	{[]string{"flour", "薄力粉", "小麦粉"}, gramsPerCup("125"), true},
+	{[]string{"cornstarch", "片栗粉"}, gramsPerCup("128"), true},
+	{[]string{"cocoa", "ココア"}, gramsPerCup("85"), true},
+	{[]string{"oats", "オートミール"}, gramsPerCup("90"), true},
+	{[]string{"rice", "米"}, gramsPerCup("200"), true},
+	{[]string{"butter", "バター"}, gramsPerCup("227"), true},
+	{[]string{"salt", "塩"}, gramsPerCup("292"), true},
+	{[]string{"honey", "はちみつ", "蜂蜜"}, gramsPerCup("340"), false},
+	{[]string{"milk", "牛乳"}, gramsPerCup("242"), false},
+	{[]string{"oil", "油"}, gramsPerCup("218"), false},
+	{[]string{"water", "水"}, gramsPerCup("236.5882365"), false},
+}
+
+// compounds are ingredients whose names contain a name of densities but
+// which have another density, like "rice vinegar" or "sugar snap peas".
+// They have no known density.
+var compounds = []string{
+	"rice vinegar", "rice wine", "rice noodle", "rice paper", "sugar snap", "snap pea", "butter bean",
+	"peanut butter", "cocoa butter", "water chestnut", "flour tortilla", "salt cod",
+	"米酢", "米粉", "水菜", "醤油",
+}
+
+var (
+	keywords search.Keywords
+	// densityOf maps names to their density, nil for compounds
+	densityOf = map[string]*Density{}
+)
+
+func init() {
+	for i := range densities {
+		keywords.Add(densities[i].Names...)
+		for _, n := range densities[i].Names {
+			densityOf[n] = &densities[i]
+		}
+	}
+	keywords.Add(compounds...)
+}
+
+func gramsPerCup(grams string) *big.Rat {
+	return new(big.Rat).Quo(rat(grams), Cup.Size)
+}
+
+// DensityOf finds the density of the ingredient named name
+func DensityOf(name string) (Density, bool) {
+	for _, n := range keywords.Find(name) {
+		if d := densityOf[n]; d != nil {
+			return *d, true
+		}
+	}
+	return Density{}, false
+}
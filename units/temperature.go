@@ -0,0 +1,57 @@
+package units
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// temperaturePattern matches temperatures in text, like "180°C", "350 °F",
+// "180℃" or "350 degrees Fahrenheit". A degree sign or word is required,
+// since "2 C" is as likely two cups.
+var temperaturePattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(?:(?:°|º|degrees?\s*)\s*(C|F|Celsius|Fahrenheit)\b|(℃|℉))`)
+
+// ConvertTemperature converts a temperature in unit, Celsius or Fahrenheit,
+// into system. Oven temperatures are rounded to the steps ovens are set in,
+// other temperatures to 5 degrees.
+func ConvertTemperature(value float64, unit *Unit, system System) (float64, *Unit) {
+	switch {
+	case unit == Celsius && system == USCustomary:
+		f := value*9/5 + 32
+		if f >= 300 {
+			return roundTo(f, 25), Fahrenheit
+		}
+		return roundTo(f, 5), Fahrenheit
+	case unit == Fahrenheit && system == Metric:
+		c := (value - 32) * 5 / 9
+		if c >= 150 {
+			return roundTo(c, 10), Celsius
+		}
+		return roundTo(c, 5), Celsius
+	}
+	return value, unit
+}
+
+func roundTo(v, step float64) float64 {
+	return math.Round(v/step) * step
+}
+
+// ConvertTemperatures rewrites the temperatures in text into system
+func ConvertTemperatures(text string, system System) string {
+	return temperaturePattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := temperaturePattern.FindStringSubmatch(match)
+		value, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return match
+		}
+		unit, ok := Lookup(m[2] + m[3])
+		if !ok {
+			return match
+		}
+		converted, to := ConvertTemperature(value, unit, system)
+		if to == unit {
+			return match
+		}
+		return strconv.FormatFloat(converted, 'f', -1, 64) + "°" + to.Name
+	})
+}
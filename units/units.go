@@ -0,0 +1,300 @@
+// Package units converts cooking quantities between units and between the
+// metric and US customary systems. Amounts are exact rationals, so that
+// conversions back and forth don't drift; only Round and Fit approximate, to
+// the fractions cooks measure with.
+package units
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Dimension is what a unit measures
+type Dimension int
+
+// Dimensions of units
+const (
+	Mass Dimension = iota + 1
+	Volume
+	Length
+	Temperature
+)
+
+// System is a system of units
+type System int
+
+// Systems of units
+const (
+	Metric System = iota + 1
+	USCustomary
+)
+
+// ParseSystem parses the name of a system, "metric" or "us"
+func ParseSystem(s string) (System, error) {
+	switch strings.ToLower(s) {
+	case "metric":
+		return Metric, nil
+	case "us":
+		return USCustomary, nil
+	}
+	return 0, fmt.Errorf("unknown unit system %q", s)
+}
+
+// Unit is a unit of measure
+type Unit struct {
+	Name      string
+	Dimension Dimension
+	System    System
+	// Size is the unit in grams, millilitres or millimetres, by dimension.
+	// It is nil for temperatures, which don't convert by a factor.
+	Size *big.Rat
+	// Min is the smallest amount Fit writes in this unit, smaller amounts
+	// move down the family
+	Min *big.Rat
+	// Dens are the denominators of the fractions amounts in this unit are
+	// rounded to
+	Dens []int64
+	// Family names the units an amount moves between to read well, like
+	// tsp, tbsp and cup. Units without a family stay as they are.
+	Family string
+}
+
+// tolerance is how far, relative to the exact amount, Fit may round an
+// amount in a unit before a smaller unit is used instead
+const tolerance = 0.1
+
+func rat(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic("units: invalid number " + s)
+	}
+	return r
+}
+
+func mul(a, b *big.Rat) *big.Rat {
+	return new(big.Rat).Mul(a, b)
+}
+
+// Units of measure. US volumes and masses are defined by their exact metric
+// size, and Japanese measuring spoons and cups are metric.
+var (
+	Millilitre = &Unit{"ml", Volume, Metric, rat("1"), nil, []int64{1}, "metric-volume"}
+	Litre      = &Unit{"l", Volume, Metric, rat("1000"), rat("1"), []int64{2, 4}, "metric-volume"}
+
+	Kosaji = &Unit{"小さじ", Volume, Metric, rat("5"), nil, []int64{2, 4}, "ja-volume"}
+	Osaji  = &Unit{"大さじ", Volume, Metric, rat("15"), rat("1"), []int64{2}, "ja-volume"}
+	Kappu  = &Unit{"カップ", Volume, Metric, rat("200"), rat("1/4"), []int64{2, 4}, "ja-volume"}
+
+	Teaspoon   = &Unit{"tsp", Volume, USCustomary, rat("4.92892159375"), nil, []int64{2, 4, 8}, "us-volume"}
+	Tablespoon = &Unit{"tbsp", Volume, USCustomary, mul(rat("3"), Teaspoon.Size), rat("1"), []int64{2}, "us-volume"}
+	FluidOunce = &Unit{"fl oz", Volume, USCustomary, mul(rat("6"), Teaspoon.Size), nil, []int64{2, 4}, ""}
+	Cup        = &Unit{"cup", Volume, USCustomary, mul(rat("48"), Teaspoon.Size), rat("1/4"), []int64{2, 3, 4}, "us-volume"}
+	Pint       = &Unit{"pint", Volume, USCustomary, mul(rat("96"), Teaspoon.Size), nil, []int64{2, 4}, ""}
+	Quart      = &Unit{"quart", Volume, USCustomary, mul(rat("192"), Teaspoon.Size), nil, []int64{2, 4}, ""}
+
+	Gram     = &Unit{"g", Mass, Metric, rat("1"), nil, []int64{1}, "metric-mass"}
+	Kilogram = &Unit{"kg", Mass, Metric, rat("1000"), rat("1"), []int64{2, 4}, "metric-mass"}
+	Ounce    = &Unit{"oz", Mass, USCustomary, rat("28.349523125"), nil, []int64{2, 4}, "us-mass"}
+	Pound    = &Unit{"lb", Mass, USCustomary, mul(rat("16"), rat("28.349523125")), rat("1"), []int64{2, 4}, "us-mass"}
+
+	Millimetre = &Unit{"mm", Length, Metric, rat("1"), nil, []int64{1}, "metric-length"}
+	Centimetre = &Unit{"cm", Length, Metric, rat("10"), rat("1"), []int64{2}, "metric-length"}
+	Inch       = &Unit{"in", Length, USCustomary, rat("25.4"), nil, []int64{2, 4, 8}, "us-length"}
+
+	Celsius    = &Unit{"C", Temperature, Metric, nil, nil, nil, ""}
+	Fahrenheit = &Unit{"F", Temperature, USCustomary, nil, nil, nil, ""}
+)
+
+// all lists every unit, from the smallest up within a family
+var all = []*Unit{
+	Millilitre, Litre, Kosaji, Osaji, Kappu,
+	Teaspoon, Tablespoon, FluidOunce, Cup, Pint, Quart,
+	Gram, Kilogram, Ounce, Pound,
+	Millimetre, Centimetre, Inch,
+	Celsius, Fahrenheit,
+}
+
+var (
+	names    = map[string]*Unit{}
+	aliases  = map[string]*Unit{}
+	families = map[string][]*Unit{}
+)
+
+// systemFamilies is the family amounts converted into a system are written in
+var systemFamilies = map[Dimension]map[System]string{
+	Mass:   {Metric: "metric-mass", USCustomary: "us-mass"},
+	Volume: {Metric: "metric-volume", USCustomary: "us-volume"},
+	Length: {Metric: "metric-length", USCustomary: "us-length"},
+}
+
+// spellings are the other ways units are written
+var spellings = map[*Unit][]string{
+	Millilitre: {"milliliter", "milliliters", "millilitre", "millilitres", "cc", "mℓ"},
+	Litre:      {"liter", "liters", "litre", "litres", "ℓ"},
+	Kosaji:     {"小匙", "tsp(jp)"},
+	Osaji:      {"大匙", "tbsp(jp)"},
+	Kappu:      {"cup(jp)"},
+	Teaspoon:   {"teaspoon", "teaspoons", "tsps"},
+	Tablespoon: {"tablespoon", "tablespoons", "tbsps", "tbs"},
+	FluidOunce: {"fluid ounce", "fluid ounces", "floz"},
+	Cup:        {"cups"},
+	Pint:       {"pints", "pt"},
+	Quart:      {"quarts", "qt"},
+	Gram:       {"gram", "grams", "gramme", "grammes"},
+	Kilogram:   {"kilogram", "kilograms", "kilo", "kilos"},
+	Ounce:      {"ounce", "ounces"},
+	Pound:      {"pound", "pounds", "lbs"},
+	Millimetre: {"millimeter", "millimeters", "millimetre", "millimetres"},
+	Centimetre: {"centimeter", "centimeters", "centimetre", "centimetres"},
+	Inch:       {"inch", "inches"},
+	Celsius:    {"°c", "℃", "celsius"},
+	Fahrenheit: {"°f", "℉", "fahrenheit"},
+}
+
+func init() {
+	for _, u := range all {
+		// the bare C and F of temperatures are only known by their exact
+		// name, as a lowercase c often means a cup
+		if u.Dimension != Temperature {
+			aliases[strings.ToLower(u.Name)] = u
+		}
+		names[u.Name] = u
+		for _, s := range spellings[u] {
+			aliases[s] = u
+		}
+		if u.Family != "" {
+			families[u.Family] = append(families[u.Family], u)
+		}
+	}
+}
+
+// Lookup finds the unit written as name, ignoring case except for the
+// names of temperatures, C and F
+func Lookup(name string) (*Unit, bool) {
+	name = strings.TrimSpace(name)
+	if u, ok := names[name]; ok {
+		return u, true
+	}
+	u, ok := aliases[strings.ToLower(name)]
+	return u, ok
+}
+
+// Convert converts amount from one unit to another of the same dimension
+func Convert(amount *big.Rat, from, to *Unit) (*big.Rat, error) {
+	if from.Dimension != to.Dimension || from.Size == nil {
+		return nil, fmt.Errorf("can't convert %s to %s", from.Name, to.Name)
+	}
+	base := mul(amount, from.Size)
+	return base.Quo(base, to.Size), nil
+}
+
+// Round returns the fraction with one of dens nearest to amount. Exact
+// amounts are kept, and positive amounts are never rounded down to zero. It
+// fails when there are no dens, or amount is too large to round.
+func Round(amount *big.Rat, dens []int64) (*big.Rat, error) {
+	if len(dens) == 0 {
+		return nil, fmt.Errorf("no fractions to round %s to", amount.RatString())
+	}
+	for _, den := range dens {
+		if new(big.Int).Rem(big.NewInt(den), amount.Denom()).Sign() == 0 {
+			return new(big.Rat).Set(amount), nil
+		}
+	}
+
+	f, _ := amount.Float64()
+	var best *big.Rat
+	bestDiff := math.Inf(1)
+	for _, den := range dens {
+		n := math.Round(f * float64(den))
+		if math.Abs(n) >= math.MaxInt64 {
+			return nil, fmt.Errorf("%s is too large to round", amount.RatString())
+		}
+		num := int64(n)
+		if num == 0 && f > 0 {
+			num = 1
+		}
+		candidate := big.NewRat(num, den)
+		c, _ := candidate.Float64()
+		if diff := math.Abs(c - f); diff < bestDiff {
+			best, bestDiff = candidate, diff
+		}
+	}
+	return best, nil
+}
+
+// Fit writes amount of unit u in the unit of its family it reads best in,
+// like 3 tsp as 1 tbsp or 1000 g as 1 kg, rounded to a fraction cooks
+// measure with. That is the largest unit the amount is at least the minimum
+// of, and rounds to within 10% in. Temperatures, and amounts too large to
+// round, are kept as they are.
+func Fit(amount *big.Rat, u *Unit) (*big.Rat, *Unit) {
+	if u.Size == nil {
+		return amount, u
+	}
+	if u.Family == "" {
+		rounded, err := Round(amount, u.Dens)
+		if err != nil {
+			return amount, u
+		}
+		return rounded, u
+	}
+	return fit(mul(amount, u.Size), families[u.Family])
+}
+
+// fit picks the unit of family for base, an amount in grams, millilitres or
+// millimetres
+func fit(base *big.Rat, family []*Unit) (*big.Rat, *Unit) {
+	for i := len(family) - 1; i > 0; i-- {
+		u := family[i]
+		in := new(big.Rat).Quo(base, u.Size)
+		if in.Cmp(u.Min) < 0 {
+			continue
+		}
+		rounded, err := Round(in, u.Dens)
+		if err != nil {
+			continue
+		}
+		r, _ := rounded.Float64()
+		f, _ := in.Float64()
+		if math.Abs(r-f) > tolerance*f {
+			continue
+		}
+		return rounded, u
+	}
+	u := family[0]
+	in := new(big.Rat).Quo(base, u.Size)
+	rounded, err := Round(in, u.Dens)
+	if err != nil {
+		return in, u
+	}
+	return rounded, u
+}
+
+// ConvertIngredient writes amount of unit u in system, in the unit of the
+// system it reads best in. Amounts already in system are kept as they are.
+// Ingredients whose density is known and which cooks weigh, like flour,
+// are weighed in metric and measured by volume in US customary, as
+// recipes of each system do.
+func ConvertIngredient(amount *big.Rat, u *Unit, system System, ingredient string) (*big.Rat, *Unit) {
+	if u.System == system || u.Size == nil {
+		return amount, u
+	}
+
+	base, dimension := mul(amount, u.Size), u.Dimension
+	if d, ok := DensityOf(ingredient); ok && d.Weighed {
+		switch {
+		case system == Metric && dimension == Volume:
+			base, dimension = mul(base, d.GramsPerMillilitre), Mass
+		case system == USCustomary && dimension == Mass:
+			base, dimension = base.Quo(base, d.GramsPerMillilitre), Volume
+		}
+	}
+
+	family, ok := families[systemFamilies[dimension][system]]
+	if !ok {
+		return amount, u
+	}
+	return fit(base, family)
+}
@@ -0,0 +1,215 @@
+package units
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestConvertIngredient(t *testing.T) {
+	type (
+		in struct {
+			amount     string
+			unit       *Unit
+			system     System
+			ingredient string
+		}
+		out struct {
+			amount string
+			unit   *Unit
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"2", Cup, Metric, "milk"}, out{"473", Millilitre}},
+		"case-02": {in{"2", Cup, Metric, "all-purpose flour"}, out{"250", Gram}},
+		"case-03": {in{"250", Gram, USCustomary, "flour"}, out{"2", Cup}},
+		"case-04": {in{"500", Gram, USCustomary, "chicken thigh"}, out{"1", Pound}},
+		"case-05": {in{"1", Tablespoon, Metric, "soy sauce"}, out{"15", Millilitre}},
+		"case-06": {in{"5", Centimetre, USCustomary, "ginger"}, out{"2", Inch}},
+		// already in the system
+		"case-07": {in{"1", Osaji, Metric, "soy sauce"}, out{"1", Osaji}},
+		"case-08": {in{"1", Osaji, USCustomary, "soy sauce"}, out{"1", Tablespoon}},
+		"case-09": {in{"3/2", Litre, USCustomary, "water"}, out{"19/3", Cup}},
+		// only weighed ingredients turn into grams
+		"case-10": {in{"2", Cup, Metric, "rice vinegar"}, out{"473", Millilitre}},
+		"case-11": {in{"2", Cup, Metric, "sugar snap peas"}, out{"473", Millilitre}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			amount, unit := ConvertIngredient(rat(in.amount), in.unit, in.system, in.ingredient)
+			if amount.Cmp(rat(out.amount)) != 0 || unit != out.unit {
+				t.Errorf("actual %s %s, expected %s %s", amount.RatString(), unit.Name, out.amount, out.unit.Name)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	type (
+		in struct {
+			amount string
+			unit   *Unit
+		}
+		out struct {
+			amount string
+			unit   *Unit
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"3", Teaspoon}, out{"1", Tablespoon}},
+		"case-02": {in{"1000", Gram}, out{"1", Kilogram}},
+		"case-03": {in{"12", Tablespoon}, out{"3/4", Cup}},
+		"case-04": {in{"1/3", Osaji}, out{"1", Kosaji}},
+		"case-05": {in{"1/100", Teaspoon}, out{"1/8", Teaspoon}},
+		"case-06": {in{"5/3", FluidOunce}, out{"7/4", FluidOunce}},
+		// temperatures are kept as they are
+		"case-07": {in{"350", Fahrenheit}, out{"350", Fahrenheit}},
+		// too large to round
+		"case-08": {in{"100000000000000000000001/10", FluidOunce}, out{"100000000000000000000001/10", FluidOunce}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			amount, unit := Fit(rat(in.amount), in.unit)
+			if amount.Cmp(rat(out.amount)) != 0 || unit != out.unit {
+				t.Errorf("actual %s %s, expected %s %s", amount.RatString(), unit.Name, out.amount, out.unit.Name)
+			}
+		})
+	}
+}
+
+func TestConvertTemperatures(t *testing.T) {
+	type (
+		in struct {
+			text   string
+			system System
+		}
+		out struct {
+			text string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"Bake at 180°C for 30 minutes", USCustomary}, out{"Bake at 350°F for 30 minutes"}},
+		"case-02": {in{"Preheat the oven to 425 degrees F.", Metric}, out{"Preheat the oven to 220°C."}},
+		"case-03": {in{"200℃のオーブンで焼く", USCustomary}, out{"400°Fのオーブンで焼く"}},
+		// no degree sign, likely cups
+		"case-04": {in{"Add 2 C of milk", USCustomary}, out{"Add 2 C of milk"}},
+		"case-05": {in{"Proof at 40 °C", USCustomary}, out{"Proof at 105°F"}},
+		"case-06": {in{"Bake at 180°C", Metric}, out{"Bake at 180°C"}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			if text := ConvertTemperatures(in.text, in.system); text != out.text {
+				t.Errorf("actual text %q, expected text %q", text, out.text)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	if r, err := Round(big.NewRat(5, 12), []int64{2, 3, 4}); err != nil || r.Cmp(big.NewRat(1, 2)) != 0 {
+		t.Errorf("actual round %v, %v, expected 1/2", r, err)
+	}
+	if r, err := Round(big.NewRat(5, 12), nil); err == nil {
+		t.Errorf("actual round %s, expected an error without fractions", r.RatString())
+	}
+}
+
+func TestLookup(t *testing.T) {
+	type (
+		in struct {
+			name string
+		}
+		out struct {
+			unit *Unit
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"Tbsp"}, out{Tablespoon}},
+		"case-02": {in{" cups "}, out{Cup}},
+		"case-03": {in{"C"}, out{Celsius}},
+		"case-04": {in{"celsius"}, out{Celsius}},
+		// a lowercase c is rather a cup, and an f isn't a unit
+		"case-05": {in{"c"}, out{nil}},
+		"case-06": {in{"f"}, out{nil}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			unit, ok := Lookup(in.name)
+			if unit != out.unit || ok != (out.unit != nil) {
+				t.Errorf("actual unit %v, expected unit %v", unit, out.unit)
+			}
+		})
+	}
+}
+
+func TestDensityOf(t *testing.T) {
+	type (
+		in struct {
+			name string
+		}
+		out struct {
+			// grams is how much a cup weighs, "" without a density
+			grams string
+		}
+	)
+
+	tests := map[string]struct {
+		in
+		out
+	}{
+		"case-01": {in{"all-purpose flour"}, out{"125"}},
+		"case-02": {in{"Brown Sugar"}, out{"220"}},
+		"case-03": {in{"unsalted butter"}, out{"227"}},
+		"case-04": {in{"強力粉"}, out{"127"}},
+		"case-05": {in{"rolled oats"}, out{"90"}},
+		// names which contain a name with a density aren't it
+		"case-06": {in{"rice vinegar"}, out{""}},
+		"case-07": {in{"米酢"}, out{""}},
+		"case-08": {in{"sugar snap peas"}, out{""}},
+		"case-09": {in{"butternut squash"}, out{""}},
+		"case-10": {in{"peanut butter"}, out{""}},
+		"case-11": {in{"saltines"}, out{""}},
+	}
+
+	for k, test := range tests {
+		t.Run(k, func(t *testing.T) {
+			in, out := test.in, test.out
+
+			d, ok := DensityOf(in.name)
+			grams := ""
+			if ok {
+				grams = new(big.Rat).Mul(d.GramsPerMillilitre, Cup.Size).RatString()
+			}
+			if grams != out.grams {
+				t.Errorf("actual grams per cup %q, expected grams per cup %q", grams, out.grams)
+			}
+		})
+	}
+}